@@ -2,12 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"pbkk-quizlit-backend/internal/handlers"
+	"pbkk-quizlit-backend/internal/services"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,9 +30,10 @@ func NewAPIServer(uploadDir string) *APIServer {
 		log.Fatalf("Failed to create upload directory: %v", err)
 	}
 
+	parser := NewConfiguredPDFParser()
 	return &APIServer{
-		parser:    NewPDFParser(),
-		validator: NewFileValidator(),
+		parser:    parser,
+		validator: NewFileValidator(parser.fileService.Limits().MaxFileSize),
 		uploadDir: uploadDir,
 	}
 }
@@ -101,8 +105,19 @@ func (s *APIServer) handlePDFUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form (max 100MB)
-	if err := r.ParseMultipartForm(100 << 20); err != nil {
+	// Refuse bodies beyond the upload limit before reading them
+	limits := s.parser.fileService.Limits()
+	if limits.MaxFileSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxFileSize+1<<20)
+	}
+
+	// Parse multipart form, keeping up to 10MB in memory
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.sendError(w, fmt.Sprintf("%v: the limit is %d MB", services.ErrFileTooLarge, limits.MaxFileSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
 		s.sendError(w, "Failed to parse form data: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -146,6 +161,15 @@ func (s *APIServer) handlePDFUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check that the file is a PDF, whatever its name says
+	head := make([]byte, 1024)
+	n, _ := dst.ReadAt(head, 0)
+	if err := services.CheckContent(header.Filename, head[:n]); err != nil {
+		os.Remove(filePath)
+		s.sendError(w, "File validation failed: "+err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
 	// Get PDF info
 	info, err := s.parser.GetPDFInfo(filePath)
 	if err != nil {
@@ -155,26 +179,32 @@ func (s *APIServer) handlePDFUpload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Extract text from PDF
-	text, err := s.parser.ExtractTextFromFile(filePath)
+	doc, err := s.parser.ExtractDocument(filePath)
 	if err != nil {
 		os.Remove(filePath) // Clean up on error
-		s.sendError(w, "Failed to extract text: "+err.Error(), http.StatusInternalServerError)
+		s.sendError(w, "Failed to extract text: "+err.Error(), handlers.UploadErrorStatus(err))
 		return
 	}
 
 	// Clean up the uploaded file after processing
 	os.Remove(filePath)
+	text := formatPages(doc.Pages)
 
 	// Prepare response
 	response := map[string]interface{}{
 		"success": true,
 		"message": "PDF processed successfully",
 		"data": map[string]interface{}{
-			"filename":   header.Filename,
-			"file_size":  info.FormatFileSize(),
-			"page_count": info.PageCount,
-			"text":       text,
-			"word_count": len([]rune(text)),
+			"filename":    header.Filename,
+			"file_size":   info.FormatFileSize(),
+			"page_count":  info.PageCount,
+			"text":        text,
+			"word_count":  len([]rune(text)),
+			"pages":       doc.Pages,
+			"sections":    doc.Sections,
+			"tables":      doc.Tables,
+			"ocr":         doc.OCR,
+			"page_status": doc.PageStatus,
 		},
 	}
 
@@ -188,8 +218,19 @@ func (s *APIServer) handlePDFInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse multipart form (max 100MB)
-	if err := r.ParseMultipartForm(100 << 20); err != nil {
+	// Refuse bodies beyond the upload limit before reading them
+	limits := s.parser.fileService.Limits()
+	if limits.MaxFileSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxFileSize+1<<20)
+	}
+
+	// Parse multipart form, keeping up to 10MB in memory
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			s.sendError(w, fmt.Sprintf("%v: the limit is %d MB", services.ErrFileTooLarge, limits.MaxFileSize>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
 		s.sendError(w, "Failed to parse form data: "+err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -233,6 +274,15 @@ func (s *APIServer) handlePDFInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check that the file is a PDF, whatever its name says
+	head := make([]byte, 1024)
+	n, _ := dst.ReadAt(head, 0)
+	if err := services.CheckContent(header.Filename, head[:n]); err != nil {
+		os.Remove(filePath)
+		s.sendError(w, "File validation failed: "+err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
 	// Get PDF info
 	info, err := s.parser.GetPDFInfo(filePath)
 	if err != nil {
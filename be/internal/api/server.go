@@ -1,15 +1,19 @@
 package api
 
 import (
+	"context"
 	"log"
 	"pbkk-quizlit-backend/internal/config"
 	"pbkk-quizlit-backend/internal/database"
 	"pbkk-quizlit-backend/internal/handlers"
 	"pbkk-quizlit-backend/internal/middleware"
 	"pbkk-quizlit-backend/internal/services"
+	"pbkk-quizlit-backend/internal/storage"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type Server struct {
@@ -50,9 +54,9 @@ func NewServer(cfg *config.Config) *Server {
 	// Configure CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{cfg.CorsOrigin},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "HEAD", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Checksum"},
+		ExposeHeaders:    []string{"Content-Length", "Location", "Tus-Resumable", "Upload-Offset", "Upload-Length", "Upload-Expires"},
 		AllowCredentials: true,
 	}))
 
@@ -67,12 +71,43 @@ func NewServer(cfg *config.Config) *Server {
 
 func (s *Server) setupRoutes() {
 	// Initialize services
-	fileService := services.NewFileService()
+	fileService := services.NewFileService().
+		WithPDFWorkers(s.config.PDFWorkers, s.config.PDFPageTimeout).
+		WithUploadLimits(UploadLimits(s.config))
+	if normalize, err := services.ParseNormalizeOptions(s.config.TextNormalize); err != nil {
+		log.Printf("⚠️  Invalid TEXT_NORMALIZE, using the default text normalisation: %v", err)
+	} else {
+		fileService = fileService.WithNormalizeOptions(normalize)
+	}
+	if ocr, err := services.OpenOCR(OCROptions(s.config)); err != nil {
+		log.Printf("⚠️  Invalid OCR settings, scanned PDFs cannot be read: %v", err)
+	} else if ocr != nil {
+		fileService = fileService.WithOCR(ocr)
+		log.Printf("✅ OCR ready (%s)", s.config.OCRBackend)
+	}
 	aiService := services.NewAIService(s.config.OpenAIKey)
 	quizService := services.NewQuizService()
+	flashcardService := services.NewFlashcardService()
+
+	// Original uploads and images are kept in blob storage; without it documents keep
+	// only their text
+	blobStore := s.openBlobStore()
+	if s.config.StorageTokenSecret == "" {
+		log.Println("⚠️  No STORAGE_TOKEN_SECRET configured, download links stop working on restart")
+	}
+	tokenSigner := storage.NewTokenSigner(s.config.StorageTokenSecret)
+	fileLinks := services.NewFileLinks(tokenSigner, s.config.StorageLinkTTL)
+	tutorService := services.NewTutorService()
+	documentService := services.NewDocumentService(blobStore, fileLinks, tutorService)
+	imageService := services.NewImageService(blobStore, fileLinks)
 
 	// Initialize handlers
-	quizHandler := handlers.NewQuizHandler(quizService, aiService, fileService)
+	quizHandler := handlers.NewQuizHandler(quizService, aiService, fileService, documentService, imageService)
+	flashcardHandler := handlers.NewFlashcardHandler(flashcardService, quizService, aiService, fileService, documentService)
+	documentHandler := handlers.NewDocumentHandler(documentService, aiService, fileService)
+	tutorHandler := handlers.NewTutorHandler(tutorService, documentService, quizService, aiService)
+	fileHandler := handlers.NewFileHandler(blobStore, tokenSigner)
+	uploadHandler := s.newUploadHandler(fileService, documentService)
 
 	// Health check
 	s.router.GET("/health", func(c *gin.Context) {
@@ -85,6 +120,9 @@ func (s *Server) setupRoutes() {
 	// API routes
 	api := s.router.Group("/api/v1")
 	{
+		// Download links carry their own signed token instead of a user session
+		api.GET("/files/:token", fileHandler.Download)
+
 		// Quiz routes (protected)
 		quizzes := api.Group("/quizzes")
 		quizzes.Use(middleware.AuthMiddleware()) // Apply auth to all quiz routes
@@ -95,16 +133,141 @@ func (s *Server) setupRoutes() {
 			quizzes.GET("/:id", quizHandler.GetQuiz)
 			quizzes.PUT("/:id", quizHandler.UpdateQuiz)
 			quizzes.DELETE("/:id", quizHandler.DeleteQuiz)
+			quizzes.POST("/:id/translate", quizHandler.TranslateQuiz)
+			quizzes.GET("/:id/translations", quizHandler.GetQuizTranslations)
+			quizzes.GET("/:id/bloom", quizHandler.GetBloomCoverage)
+			quizzes.GET("/:id/lint", quizHandler.LintQuiz)
+			quizzes.POST("/:id/chat", tutorHandler.AskQuizSource)
+			quizzes.PUT("/:id/questions/:questionId/image", quizHandler.UploadQuestionImage)
+			quizzes.DELETE("/:id/questions/:questionId/image", quizHandler.DeleteQuestionImage)
 
 			// Quiz taking endpoints
 			quizzes.GET("/take/:id", quizHandler.GetQuizForTaking)
 			quizzes.POST("/submit", quizHandler.SubmitQuizAttempt)
 			quizzes.GET("/attempt/:id", quizHandler.GetQuizAttempt)
+			quizzes.GET("/attempt/:id/explanations", tutorHandler.ExplainAttempt)
 			quizzes.GET("/attempts", quizHandler.ListUserAttempts)
 		}
+
+		// Flashcard deck routes (protected)
+		decks := api.Group("/decks")
+		decks.Use(middleware.AuthMiddleware())
+		{
+			decks.POST("/upload", flashcardHandler.UploadFileAndGenerateDeck)
+			decks.POST("/generate", flashcardHandler.GenerateDeckFromText)
+			decks.GET("/", flashcardHandler.GetAllDecks)
+			decks.GET("/:id", flashcardHandler.GetDeck)
+			decks.PUT("/:id", flashcardHandler.UpdateDeck)
+			decks.DELETE("/:id", flashcardHandler.DeleteDeck)
+			decks.POST("/:id/quiz", flashcardHandler.ConvertDeckToQuiz)
+		}
+
+		// Source document routes (protected)
+		documents := api.Group("/documents")
+		documents.Use(middleware.AuthMiddleware())
+		{
+			documents.POST("/", documentHandler.UploadDocument)
+			documents.GET("/", documentHandler.ListDocuments)
+			documents.GET("/:id", documentHandler.GetDocument)
+			documents.GET("/:id/file", documentHandler.DownloadDocument)
+			documents.GET("/:id/link", documentHandler.GetDownloadLink)
+			documents.DELETE("/:id", documentHandler.DeleteDocument)
+			documents.POST("/:id/quiz", quizHandler.GenerateQuizFromDocument)
+			documents.POST("/:id/deck", flashcardHandler.GenerateDeckFromDocument)
+			documents.POST("/glossary", documentHandler.UploadFileAndExtractGlossary)
+			documents.GET("/:id/glossary", documentHandler.GetGlossary)
+			documents.GET("/:id/notes", documentHandler.GetStudyNotes)
+			documents.POST("/:id/chat", tutorHandler.AskDocument)
+			documents.GET("/:id/chat", tutorHandler.GetHistory)
+			documents.DELETE("/:id/chat", tutorHandler.ClearHistory)
+		}
+
+		// Resumable upload routes (protected), following the tus protocol
+		if uploadHandler != nil {
+			uploads := api.Group("/uploads")
+			uploads.Use(middleware.AuthMiddleware())
+			{
+				uploads.POST("/", uploadHandler.CreateUpload)
+				uploads.HEAD("/:id", uploadHandler.GetUploadOffset)
+				uploads.PATCH("/:id", uploadHandler.AppendUpload)
+				uploads.DELETE("/:id", uploadHandler.DeleteUpload)
+			}
+		}
 	}
 }
 
 func (s *Server) Start() error {
 	return s.router.Run(":" + s.config.Port)
 }
+
+// newUploadHandler opens the store of resumable uploads and starts removing expired ones.
+// It returns nil when uploads cannot be kept.
+func (s *Server) newUploadHandler(fileService *services.FileService, documentService *services.DocumentService) *handlers.UploadHandler {
+	limits := services.UploadSessionLimits{
+		MaxPerUser:   s.config.UploadSessionMaxPerUser,
+		MaxTotalSize: int64(s.config.UploadSessionMaxMB) << 20,
+	}
+	sessions, err := services.NewUploadSessions(s.config.UploadSessionDir, s.config.UploadSessionTTL, limits)
+	if err != nil {
+		log.Printf("⚠️  Resumable uploads are off: %v", err)
+		return nil
+	}
+	go sessions.RunExpiry(context.Background(), 10*time.Minute, logrus.New())
+	return handlers.NewUploadHandler(sessions, fileService, documentService)
+}
+
+// openBlobStore opens the configured blob storage backend and starts its lifecycle
+// policy. It returns nil when the storage cannot be used.
+func (s *Server) openBlobStore() storage.BlobStore {
+	store, err := storage.Open(StorageOptions(s.config))
+	if err != nil {
+		log.Printf("⚠️  Failed to open file storage: %v", err)
+		return nil
+	}
+	log.Printf("✅ File storage ready (%s)", s.config.StorageBackend)
+
+	rules, err := storage.ParseLifecycleRules(s.config.StorageLifecycle)
+	if err != nil {
+		log.Printf("⚠️  Ignoring STORAGE_LIFECYCLE: %v", err)
+	} else if len(rules) > 0 {
+		go storage.RunLifecycle(context.Background(), store, rules, time.Hour, logrus.New())
+	}
+	return store
+}
+
+// StorageOptions maps the storage settings of the configuration to blob store options
+func StorageOptions(cfg *config.Config) storage.Options {
+	return storage.Options{
+		Backend: cfg.StorageBackend,
+		Dir:     cfg.StorageDir,
+		S3: storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		},
+	}
+}
+
+// OCROptions maps the OCR settings of the configuration to OCR engine options
+func OCROptions(cfg *config.Config) services.OCROptions {
+	return services.OCROptions{
+		Backend: cfg.OCRBackend,
+		Command: cfg.OCRCommand,
+		Timeout: cfg.OCRTimeout,
+	}
+}
+
+// UploadLimits maps the upload limits of the configuration to file service limits
+func UploadLimits(cfg *config.Config) services.UploadLimits {
+	return services.UploadLimits{
+		MaxFileSize:       int64(cfg.MaxUploadMB) << 20,
+		MaxPages:          cfg.PDFMaxPages,
+		MaxStreamSize:     int64(cfg.PDFMaxStreamMB) << 20,
+		MaxArchiveEntries: cfg.ArchiveMaxEntries,
+		MaxArchiveSize:    int64(cfg.ArchiveMaxMB) << 20,
+		MaxExtractTime:    cfg.MaxExtractTime,
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +17,54 @@ type Config struct {
 	SupabaseURL       string
 	SupabaseAnonKey   string
 	SupabaseJWTSecret string
+	StorageDir        string
+
+	// Blob storage backend: "local" keeps files under StorageDir, "s3" in an
+	// S3-compatible bucket
+	StorageBackend     string
+	S3Endpoint         string
+	S3Bucket           string
+	S3Region           string
+	S3AccessKey        string
+	S3SecretKey        string
+	S3PathStyle        bool
+	StorageTokenSecret string
+	StorageLinkTTL     time.Duration
+	StorageLifecycle   string
+
+	// Text normalisation steps for extracted documents, optionally per language, e.g.
+	// "default; id=-split"
+	TextNormalize string
+
+	// OCR of PDF pages without a text layer: "none", "command" runs OCRCommand for
+	// every page, "stub" returns placeholder text
+	OCRBackend string
+	OCRCommand string
+	OCRTimeout time.Duration
+
+	// PDF pages are extracted by up to PDFWorkers workers at a time, giving up on a page
+	// after PDFPageTimeout. Zero workers means one per CPU.
+	PDFWorkers     int
+	PDFPageTimeout time.Duration
+
+	// Limits of uploaded files: their size and, for PDFs, their pages and the
+	// decompressed content of a page, in MB, for DOCX, PPTX and EPUB files the files in
+	// the archive and what they decompress to in MB, and the time to extract a document.
+	// Zero means no limit.
+	MaxUploadMB       int
+	PDFMaxPages       int
+	PDFMaxStreamMB    int
+	ArchiveMaxEntries int
+	ArchiveMaxMB      int
+	MaxExtractTime    time.Duration
+
+	// Resumable uploads are kept under UploadSessionDir until complete, and dropped when
+	// no chunk arrived for UploadSessionTTL. A user may have UploadSessionMaxPerUser in
+	// progress, and all of them together may take UploadSessionMaxMB. Zero means no limit.
+	UploadSessionDir        string
+	UploadSessionTTL        time.Duration
+	UploadSessionMaxPerUser int
+	UploadSessionMaxMB      int
 }
 
 func Load() *Config {
@@ -31,6 +81,39 @@ func Load() *Config {
 		SupabaseURL:       getEnv("SUPABASE_URL", ""),
 		SupabaseAnonKey:   getEnv("SUPABASE_ANON_KEY", ""),
 		SupabaseJWTSecret: getEnv("SUPABASE_JWT_SECRET", ""),
+		StorageDir:        getEnv("STORAGE_DIR", "./data/blobs"),
+
+		StorageBackend:     getEnv("STORAGE_BACKEND", "local"),
+		S3Endpoint:         getEnv("S3_ENDPOINT", ""),
+		S3Bucket:           getEnv("S3_BUCKET", ""),
+		S3Region:           getEnv("S3_REGION", "us-east-1"),
+		S3AccessKey:        getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:        getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:        getEnv("S3_PATH_STYLE", "true") == "true",
+		StorageTokenSecret: getEnv("STORAGE_TOKEN_SECRET", ""),
+		StorageLinkTTL:     getDuration("STORAGE_LINK_TTL", time.Hour),
+		StorageLifecycle:   getEnv("STORAGE_LIFECYCLE", "tmp/=24h"),
+
+		TextNormalize: getEnv("TEXT_NORMALIZE", ""),
+
+		OCRBackend: getEnv("OCR_BACKEND", "none"),
+		OCRCommand: getEnv("OCR_COMMAND", ""),
+		OCRTimeout: getDuration("OCR_TIMEOUT", time.Minute),
+
+		PDFWorkers:     getInt("PDF_WORKERS", 0),
+		PDFPageTimeout: getDuration("PDF_PAGE_TIMEOUT", 30*time.Second),
+
+		MaxUploadMB:       getInt("MAX_UPLOAD_MB", 50),
+		PDFMaxPages:       getInt("PDF_MAX_PAGES", 2000),
+		PDFMaxStreamMB:    getInt("PDF_MAX_STREAM_MB", 64),
+		ArchiveMaxEntries: getInt("ARCHIVE_MAX_ENTRIES", 10000),
+		ArchiveMaxMB:      getInt("ARCHIVE_MAX_MB", 256),
+		MaxExtractTime:    getDuration("MAX_EXTRACT_TIME", 2*time.Minute),
+
+		UploadSessionDir:        getEnv("UPLOAD_SESSION_DIR", "./data/uploads"),
+		UploadSessionTTL:        getDuration("UPLOAD_SESSION_TTL", 24*time.Hour),
+		UploadSessionMaxPerUser: getInt("UPLOAD_SESSION_MAX_PER_USER", 5),
+		UploadSessionMaxMB:      getInt("UPLOAD_SESSION_MAX_MB", 2048),
 	}
 }
 
@@ -40,3 +123,29 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func getInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"pbkk-quizlit-backend/internal/middleware"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+	"pbkk-quizlit-backend/internal/storage"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type DocumentHandler struct {
+	documentService *services.DocumentService
+	aiService       *services.AIService
+	fileService     *services.FileService
+	logger          *logrus.Logger
+}
+
+func NewDocumentHandler(documentService *services.DocumentService, aiService *services.AIService, fileService *services.FileService) *DocumentHandler {
+	return &DocumentHandler{
+		documentService: documentService,
+		aiService:       aiService,
+		fileService:     fileService,
+		logger:          logrus.New(),
+	}
+}
+
+// UploadDocument adds a file to the user's document library
+func (h *DocumentHandler) UploadDocument(c *gin.Context) {
+	// Parse multipart form
+	if !parseUploadForm(c, h.fileService, h.logger) {
+		return
+	}
+
+	// Get file from form
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		h.logger.Errorf("Failed to get file from form: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No file uploaded",
+		})
+		return
+	}
+
+	doc, ok := h.ingest(c, file, header)
+	if !ok {
+		return
+	}
+
+	h.logger.Infof("Stored document %s (%s, %d pages)", doc.ID, doc.Filename, doc.PageCount)
+	doc.Pages = nil
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: uploadedMessage(doc),
+		Data:    doc,
+	})
+}
+
+// uploadedMessage tells about an uploaded document, pointing out pages that were left out
+// or are worth checking
+func uploadedMessage(doc *models.Document) string {
+	message := "Document uploaded successfully"
+	if failed := services.FailedPages(doc.PageStatus); len(failed) > 0 {
+		message += "; pages that could not be read were left out: " + services.FormatPageNumbers(failed)
+	}
+	if doc.OCR != nil && len(doc.OCR.LowConfidencePages) > 0 {
+		message += "; check the text of pages read by OCR with low confidence: " + services.FormatPageNumbers(doc.OCR.LowConfidencePages)
+	}
+	if charset := doc.Metadata["charset"]; charset != "" && charset != "UTF-8" {
+		message += "; the text was read as " + charset
+	}
+	return message
+}
+
+// ingest adds an upload to the library, writing the error response itself when it fails
+func (h *DocumentHandler) ingest(c *gin.Context, file multipart.File, header *multipart.FileHeader) (*models.Document, bool) {
+	normalize, err := normalizeOptionsField(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+
+	doc, err := ingestUpload(h.fileService, h.documentService, h.logger, file, header, middleware.GetUserID(c), normalize)
+	if err != nil {
+		h.logger.Errorf("Failed to process file: %v", err)
+		c.JSON(UploadErrorStatus(err), models.APIResponse{
+			Success: false,
+			Message: "Failed to process uploaded file: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	return doc, true
+}
+
+// ListDocuments returns the documents in the user's library, without their text
+func (h *DocumentHandler) ListDocuments(c *gin.Context) {
+	documents, err := h.documentService.ListDocuments(middleware.GetUserID(c))
+	if err != nil {
+		h.logger.Errorf("Failed to list documents: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve documents",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Documents retrieved successfully",
+		Data:    documents,
+	})
+}
+
+// GetDocument returns a document with its extracted pages, outline and tables, and how
+// well the pages read by OCR were recognised
+func (h *DocumentHandler) GetDocument(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c)
+	if !ok {
+		return
+	}
+	doc.Sections = services.DocumentOutline(doc.Pages)
+	doc.Tables = services.DocumentTables(doc.Pages)
+	doc.OCR = services.DocumentOCR(doc.Pages)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Document retrieved successfully",
+		Data:    doc,
+	})
+}
+
+// DownloadDocument streams the original upload of a document
+func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c)
+	if !ok {
+		return
+	}
+
+	file, err := h.documentService.OpenFile(doc)
+	if err != nil {
+		if err != storage.ErrNotFound {
+			h.logger.Errorf("Failed to open file of document %s: %v", doc.ID, err)
+		}
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "The original file of this document is not stored",
+		})
+		return
+	}
+	defer file.Close()
+
+	contentType := doc.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, doc.Size, contentType, file, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": doc.Filename}),
+	})
+}
+
+// GetDownloadLink returns a link to the original upload of a document that works
+// without an Authorization header until it expires
+func (h *DocumentHandler) GetDownloadLink(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.documentService.DownloadLink(doc)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "The original file of this document is not stored",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Download link created successfully",
+		Data:    link,
+	})
+}
+
+// DeleteDocument deletes a document and its stored file. Quizzes generated from it are
+// kept but no longer linked to it.
+func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c)
+	if !ok {
+		return
+	}
+
+	if err := h.documentService.DeleteDocument(doc); err != nil {
+		h.logger.Errorf("Failed to delete document %s: %v", doc.ID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to delete document",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Document deleted successfully",
+	})
+}
+
+// UploadFileAndExtractGlossary stores an uploaded document and returns its key terms
+func (h *DocumentHandler) UploadFileAndExtractGlossary(c *gin.Context) {
+	// Parse multipart form
+	if !parseUploadForm(c, h.fileService, h.logger) {
+		return
+	}
+
+	// Get file from form
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		h.logger.Errorf("Failed to get file from form: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No file uploaded",
+		})
+		return
+	}
+
+	doc, ok := h.ingest(c, file, header)
+	if !ok {
+		return
+	}
+
+	opts := services.GlossaryOptions{
+		UseLLM: c.Request.FormValue("definitions") == "llm",
+		Keywords: services.KeywordOptions{
+			Language: c.Request.FormValue("language"),
+			Stemming: c.Request.FormValue("stemming") == "true",
+		},
+	}
+	opts.Limit, _ = strconv.Atoi(c.Request.FormValue("limit"))
+
+	h.respondWithGlossary(c, doc, opts, http.StatusCreated)
+}
+
+// GetGlossary returns the stored glossary of a document, extracting it on first use
+func (h *DocumentHandler) GetGlossary(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c)
+	if !ok {
+		return
+	}
+
+	refresh := c.Query("refresh") == "true"
+	if len(doc.Glossary) > 0 && !refresh {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Glossary retrieved successfully",
+			Data: models.GlossaryResponse{
+				DocumentID: doc.ID,
+				Filename:   doc.Filename,
+				Terms:      doc.Glossary,
+			},
+		})
+		return
+	}
+
+	opts := services.GlossaryOptions{
+		UseLLM: c.Query("definitions") == "llm",
+		Keywords: services.KeywordOptions{
+			Language: c.Query("language"),
+			Stemming: c.Query("stemming") == "true",
+		},
+	}
+	opts.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	h.respondWithGlossary(c, doc, opts, http.StatusOK)
+}
+
+// respondWithGlossary extracts, stores and returns the glossary of a document. Terms are
+// ranked against the user's other documents so words common to all of them score lower.
+func (h *DocumentHandler) respondWithGlossary(c *gin.Context, doc *models.Document, opts services.GlossaryOptions, status int) {
+	corpus, err := h.documentService.GetKeywordCorpus(doc.UserID, opts.Keywords)
+	if err != nil {
+		h.logger.Warnf("Failed to build keyword corpus, ranking within the document: %v", err)
+	}
+	opts.Corpus = corpus
+
+	glossary := h.aiService.BuildGlossary(doc.Pages, opts)
+
+	if err := h.documentService.SaveGlossary(doc.ID, glossary); err != nil {
+		h.logger.Errorf("Failed to save glossary for document %s: %v", doc.ID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to save glossary",
+		})
+		return
+	}
+
+	h.logger.Infof("Extracted %d glossary terms from document %s", len(glossary), doc.ID)
+	c.JSON(status, models.APIResponse{
+		Success: true,
+		Message: "Glossary extracted successfully",
+		Data: models.GlossaryResponse{
+			DocumentID: doc.ID,
+			Filename:   doc.Filename,
+			Terms:      glossary,
+		},
+	})
+}
+
+// GetStudyNotes returns the stored study notes of a document, generating them on first
+// use. method=llm summarises with the configured provider instead of extracting sentences.
+func (h *DocumentHandler) GetStudyNotes(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c)
+	if !ok {
+		return
+	}
+
+	useLLM := c.Query("method") == "llm"
+	refresh := c.Query("refresh") == "true"
+	if doc.Notes != nil && !refresh && (!useLLM || doc.Notes.Method == "llm") {
+		c.JSON(http.StatusOK, models.APIResponse{
+			Success: true,
+			Message: "Study notes retrieved successfully",
+			Data: models.StudyNotesResponse{
+				DocumentID: doc.ID,
+				Filename:   doc.Filename,
+				Notes:      doc.Notes,
+			},
+		})
+		return
+	}
+
+	notes := h.aiService.GenerateStudyNotes(doc.Pages, useLLM)
+
+	if err := h.documentService.SaveNotes(doc.ID, notes); err != nil {
+		h.logger.Errorf("Failed to save study notes for document %s: %v", doc.ID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to save study notes",
+		})
+		return
+	}
+
+	h.logger.Infof("Generated %s study notes with %d sections for document %s", notes.Method, len(notes.Outline), doc.ID)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Study notes generated successfully",
+		Data: models.StudyNotesResponse{
+			DocumentID: doc.ID,
+			Filename:   doc.Filename,
+			Notes:      notes,
+		},
+	})
+}
+
+// getOwnedDocument loads the document in the URL and checks it belongs to the current user.
+// It writes the error response itself and returns false when the request should stop.
+func (h *DocumentHandler) getOwnedDocument(c *gin.Context) (*models.Document, bool) {
+	doc, err := h.documentService.GetDocument(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Document not found",
+		})
+		return nil, false
+	}
+
+	if doc.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this document",
+		})
+		return nil, false
+	}
+
+	return doc, true
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// zipContentTypes maps the extensions of zip-based formats to their content types
+var zipContentTypes = map[string]string{
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".epub": "application/epub+zip",
+}
+
+// normalizeOptionsField reads the optional "language" and "normalize" form fields, which
+// choose how the text of an upload is normalised, e.g. "id" and "-split". It returns nil
+// when neither is set.
+func normalizeOptionsField(c *gin.Context) (*services.NormalizeOptions, error) {
+	return parseNormalizeOptions(c.Request.FormValue("language"), c.Request.FormValue("normalize"))
+}
+
+// parseNormalizeOptions reads a language and normalisation steps given with an upload
+func parseNormalizeOptions(language, stepSpec string) (*services.NormalizeOptions, error) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	stepSpec = strings.TrimSpace(stepSpec)
+	if language == "" && stepSpec == "" {
+		return nil, nil
+	}
+
+	if language != "" && language != "en" && language != "id" {
+		return nil, fmt.Errorf("unsupported language %q, expected en or id", language)
+	}
+	opts := &services.NormalizeOptions{Language: language}
+	if stepSpec != "" {
+		steps, err := services.ParseNormalizeSteps(stepSpec)
+		if err != nil {
+			return nil, err
+		}
+		opts.Steps = steps
+	}
+	return opts, nil
+}
+
+// parseUploadForm parses a multipart upload form, refusing bodies larger than the upload
+// size limit before they are read. It writes the error response itself and returns false
+// when the request should stop.
+func parseUploadForm(c *gin.Context, fileService *services.FileService, logger *logrus.Logger) bool {
+	if limit := fileService.Limits().MaxFileSize; limit > 0 {
+		// Leave room for the other fields of the form
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit+1<<20)
+	}
+
+	if err := c.Request.ParseMultipartForm(10 << 20); err != nil { // larger files go to disk
+		logger.Errorf("Failed to parse multipart form: %v", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.APIResponse{
+				Success: false,
+				Message: fmt.Sprintf("%v: the limit is %d MB", services.ErrFileTooLarge, fileService.Limits().MaxFileSize>>20),
+			})
+			return false
+		}
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to parse form data",
+		})
+		return false
+	}
+	return true
+}
+
+// UploadErrorStatus returns the HTTP status of an error ingesting an upload: the file is
+// refused for its size or content, cannot be read, or the server failed
+func UploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrContentMismatch), errors.Is(err, services.ErrUnsupportedFile):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, services.ErrEncryptedPDF), errors.Is(err, services.ErrTooManyPages), errors.Is(err, services.ErrArchiveTooLarge),
+		errors.Is(err, services.ErrExtractionTimeout), errors.Is(err, services.ErrNoPDFText):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ingestUpload adds an uploaded file to the user's document library. The upload is
+// spooled to a temporary file and read from there, so it is never held in memory whole.
+func ingestUpload(fileService *services.FileService, documentService *services.DocumentService, logger *logrus.Logger,
+	file multipart.File, header *multipart.FileHeader, userID string, normalize *services.NormalizeOptions) (*models.Document, error) {
+	defer file.Close()
+
+	upload, err := services.SpoolUpload(file, fileService.Limits().MaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	defer upload.Close()
+
+	return ingestSpooled(fileService, documentService, logger, upload, header.Filename, header.Header.Get("Content-Type"), userID, normalize)
+}
+
+// ingestSpooled adds a file spooled to disk to the user's document library. A file the
+// user has uploaded before is not extracted again; its stored document is returned
+// instead. With normalisation options the file is extracted again and the text of the
+// stored document is replaced, which is how users fix text that came out garbled.
+func ingestSpooled(fileService *services.FileService, documentService *services.DocumentService, logger *logrus.Logger,
+	upload *services.SpooledUpload, filename, contentType, userID string, normalize *services.NormalizeOptions) (*models.Document, error) {
+	existing, err := documentService.FindUpload(userID, upload.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up earlier uploads: %w", err)
+	}
+	if existing != nil && normalize == nil {
+		logger.Infof("Reusing document %s for upload of %s", existing.ID, filename)
+		existing.OCR = services.DocumentOCR(existing.Pages)
+		return existing, nil
+	}
+	if normalize != nil {
+		fileService = fileService.WithNormalizeOptions(*normalize)
+	}
+
+	doc, err := fileService.ExtractDocumentFrom(filename, upload.File, upload.Size)
+	if err != nil {
+		return nil, err
+	}
+	if failed := services.FailedPages(doc.PageStatus); len(failed) > 0 {
+		logger.Warnf("Left out pages %s of %s that could not be read", services.FormatPageNumbers(failed), filename)
+	}
+
+	if existing != nil {
+		if err := documentService.ReplaceText(existing, doc.Pages, doc.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to update document %s: %w", existing.ID, err)
+		}
+		logger.Infof("Replaced the text of document %s with %s extracted again", existing.ID, filename)
+		existing.Sections, existing.Tables, existing.OCR, existing.PageStatus = doc.Sections, doc.Tables, doc.OCR, doc.PageStatus
+		return existing, nil
+	}
+
+	if contentType == "" || contentType == "application/octet-stream" {
+		contentType = http.DetectContentType(upload.Head(512))
+	}
+	// Office files and EPUBs are zip archives, which is all sniffing can tell
+	if zipType, ok := zipContentTypes[strings.ToLower(filepath.Ext(filename))]; ok && contentType == "application/zip" {
+		contentType = zipType
+	}
+
+	doc.ContentType = contentType
+	doc.Size, doc.SHA256 = upload.Size, upload.SHA256
+	if err := documentService.CreateDocument(doc, upload.Reader(), userID); err != nil {
+		return nil, fmt.Errorf("failed to add the file to the document library: %w", err)
+	}
+
+	return doc, nil
+}
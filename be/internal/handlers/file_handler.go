@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// FileHandler serves stored blobs to holders of a download token. It needs no
+// authentication: the token itself grants access to one blob until it expires.
+type FileHandler struct {
+	store  storage.BlobStore
+	signer *storage.TokenSigner
+	logger *logrus.Logger
+}
+
+func NewFileHandler(store storage.BlobStore, signer *storage.TokenSigner) *FileHandler {
+	return &FileHandler{
+		store:  store,
+		signer: signer,
+		logger: logrus.New(),
+	}
+}
+
+// Download streams the blob a download token grants access to
+func (h *FileHandler) Download(c *gin.Context) {
+	token, err := h.signer.Verify(c.Param("token"), time.Now())
+	if err == storage.ErrTokenExpired {
+		c.JSON(http.StatusGone, models.APIResponse{
+			Success: false,
+			Message: "This download link has expired",
+		})
+		return
+	}
+	if err != nil || h.store == nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "File not found",
+		})
+		return
+	}
+
+	file, err := h.store.Get(c.Request.Context(), token.Key)
+	if err != nil {
+		if err != storage.ErrNotFound {
+			h.logger.Errorf("Failed to open blob %s: %v", token.Key, err)
+		}
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "File not found",
+		})
+		return
+	}
+	defer file.Close()
+
+	contentType := token.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(token.Key))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Images are shown in place, everything else is downloaded
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+	params := map[string]string{}
+	if token.Filename != "" {
+		params["filename"] = token.Filename
+	}
+
+	c.DataFromReader(http.StatusOK, -1, contentType, file, map[string]string{
+		"Content-Disposition": mime.FormatMediaType(disposition, params),
+		"Cache-Control":       "private, max-age=" + strconv.Itoa(int(time.Until(token.ExpiresAt).Seconds())),
+	})
+}
@@ -0,0 +1,361 @@
+package handlers
+
+import (
+	"net/http"
+	"pbkk-quizlit-backend/internal/middleware"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type FlashcardHandler struct {
+	flashcardService *services.FlashcardService
+	quizService      *services.QuizService
+	aiService        *services.AIService
+	fileService      *services.FileService
+	documentService  *services.DocumentService
+	logger           *logrus.Logger
+}
+
+func NewFlashcardHandler(flashcardService *services.FlashcardService, quizService *services.QuizService, aiService *services.AIService, fileService *services.FileService, documentService *services.DocumentService) *FlashcardHandler {
+	return &FlashcardHandler{
+		flashcardService: flashcardService,
+		quizService:      quizService,
+		aiService:        aiService,
+		fileService:      fileService,
+		documentService:  documentService,
+		logger:           logrus.New(),
+	}
+}
+
+// UploadFileAndGenerateDeck handles file upload and flashcard generation
+func (h *FlashcardHandler) UploadFileAndGenerateDeck(c *gin.Context) {
+	// Parse multipart form
+	if !parseUploadForm(c, h.fileService, h.logger) {
+		return
+	}
+
+	// Get file from form
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		h.logger.Errorf("Failed to get file from form: %v", err)
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No file uploaded",
+		})
+		return
+	}
+
+	title := c.Request.FormValue("title")
+	if title == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Title is required",
+		})
+		return
+	}
+	cardCount, _ := strconv.Atoi(c.Request.FormValue("cardCount"))
+
+	// Optional language and normalisation steps for the extracted text
+	normalize, err := normalizeOptionsField(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Process the uploaded file keeping pages for source references, and keep it in the
+	// document library for later reuse
+	doc, err := ingestUpload(h.fileService, h.documentService, h.logger, file, header, middleware.GetUserID(c), normalize)
+	if err != nil {
+		h.logger.Errorf("Failed to process file: %v", err)
+		c.JSON(UploadErrorStatus(err), models.APIResponse{
+			Success: false,
+			Message: "Failed to process uploaded file: " + err.Error(),
+		})
+		return
+	}
+
+	deckReq := &models.DeckGenerationRequest{
+		Title:       title,
+		Description: c.Request.FormValue("description"),
+		CardCount:   cardCount,
+	}
+
+	h.generateDeckFromDocument(c, doc, deckReq)
+}
+
+// GenerateDeckFromDocument generates a deck from a document in the user's library
+func (h *FlashcardHandler) GenerateDeckFromDocument(c *gin.Context) {
+	var req models.GenerateFromDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	doc, err := h.documentService.GetDocument(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Document not found",
+		})
+		return
+	}
+	if doc.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this document",
+		})
+		return
+	}
+
+	pages, err := services.SelectParts(doc.Pages, req.Sections, req.Pages)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	selected := *doc
+	selected.Pages = pages
+
+	h.generateDeckFromDocument(c, &selected, &models.DeckGenerationRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		CardCount:   req.CardCount,
+	})
+}
+
+func (h *FlashcardHandler) generateDeckFromDocument(c *gin.Context, doc *models.Document, deckReq *models.DeckGenerationRequest) {
+	deck, err := h.aiService.GenerateFlashcardsFromPages(doc.Pages, deckReq)
+	if err != nil {
+		h.logger.Errorf("Failed to generate deck: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, models.APIResponse{
+			Success: false,
+			Message: "Failed to generate flashcards: " + err.Error(),
+		})
+		return
+	}
+	deck.SourceName = doc.Filename
+
+	h.saveDeck(c, deck)
+}
+
+// GenerateDeckFromText handles flashcard generation from text content
+func (h *FlashcardHandler) GenerateDeckFromText(c *gin.Context) {
+	var req models.GenerateDeckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	deckReq := &models.DeckGenerationRequest{
+		Title:       req.Title,
+		Description: req.Description,
+		CardCount:   req.CardCount,
+	}
+
+	deck, err := h.aiService.GenerateFlashcardsFromContent(req.Content, deckReq)
+	if err != nil {
+		h.logger.Errorf("Failed to generate deck: %v", err)
+		c.JSON(http.StatusUnprocessableEntity, models.APIResponse{
+			Success: false,
+			Message: "Failed to generate flashcards: " + err.Error(),
+		})
+		return
+	}
+
+	h.saveDeck(c, deck)
+}
+
+// saveDeck stores a generated deck for the current user and writes the response
+func (h *FlashcardHandler) saveDeck(c *gin.Context, deck *models.FlashcardDeck) {
+	userID := middleware.GetUserID(c)
+
+	if err := h.flashcardService.CreateDeck(deck, userID); err != nil {
+		h.logger.Errorf("Failed to save deck: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to save deck",
+		})
+		return
+	}
+
+	h.logger.Infof("Successfully created deck: %s", deck.ID)
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Flashcards generated successfully",
+		Data:    deck,
+	})
+}
+
+// GetAllDecks returns all decks for the authenticated user
+func (h *FlashcardHandler) GetAllDecks(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	decks, err := h.flashcardService.GetAllDecks(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve decks",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Decks retrieved successfully",
+		Data:    decks,
+	})
+}
+
+// GetDeck returns a specific deck with its cards
+func (h *FlashcardHandler) GetDeck(c *gin.Context) {
+	deck, ok := h.getOwnedDeck(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Deck retrieved successfully",
+		Data:    deck,
+	})
+}
+
+// UpdateDeck replaces the title, description and cards of a deck
+func (h *FlashcardHandler) UpdateDeck(c *gin.Context) {
+	var req models.UpdateDeckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	deck, ok := h.getOwnedDeck(c)
+	if !ok {
+		return
+	}
+
+	deck.Title = req.Title
+	deck.Description = req.Description
+	deck.Cards = req.Cards
+
+	if err := h.flashcardService.UpdateDeck(deck); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Deck updated successfully",
+		Data:    deck,
+	})
+}
+
+// DeleteDeck deletes a deck
+func (h *FlashcardHandler) DeleteDeck(c *gin.Context) {
+	deck, ok := h.getOwnedDeck(c)
+	if !ok {
+		return
+	}
+
+	if err := h.flashcardService.DeleteDeck(deck.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.logger.Infof("Deck %s deleted by user %s", deck.ID, deck.UserID)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Deck deleted successfully",
+	})
+}
+
+// ConvertDeckToQuiz builds and saves a multiple-choice quiz from a deck
+func (h *FlashcardHandler) ConvertDeckToQuiz(c *gin.Context) {
+	var req models.DeckToQuizRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "Invalid request format",
+			})
+			return
+		}
+	}
+
+	deck, ok := h.getOwnedDeck(c)
+	if !ok {
+		return
+	}
+
+	quiz, err := h.flashcardService.DeckToQuiz(deck, &req)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if err := h.quizService.CreateQuiz(quiz, deck.UserID); err != nil {
+		h.logger.Errorf("Failed to save quiz from deck: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to save quiz",
+		})
+		return
+	}
+
+	h.logger.Infof("Deck %s converted to quiz %s", deck.ID, quiz.ID)
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Quiz generated from deck successfully",
+		Data:    quiz,
+	})
+}
+
+// getOwnedDeck loads the deck in the URL and checks it belongs to the current user.
+// It writes the error response itself and returns false when the request should stop.
+func (h *FlashcardHandler) getOwnedDeck(c *gin.Context) (*models.FlashcardDeck, bool) {
+	deck, err := h.flashcardService.GetDeck(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Deck not found",
+		})
+		return nil, false
+	}
+
+	if deck.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this deck",
+		})
+		return nil, false
+	}
+
+	return deck, true
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadQuestionImage attaches an image to a question of a quiz, replacing its earlier
+// image. The image is sent as the "image" field of a multipart form.
+func (h *QuizHandler) UploadQuestionImage(c *gin.Context) {
+	quiz, ok := h.getOwnedQuiz(c)
+	if !ok {
+		return
+	}
+
+	file, _, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "No image uploaded",
+		})
+		return
+	}
+	defer file.Close()
+
+	// Read one byte past the limit so oversized images are rejected, not truncated
+	content, err := io.ReadAll(io.LimitReader(file, services.MaxImageSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Failed to read image",
+		})
+		return
+	}
+
+	link, err := h.imageService.SetQuestionImage(quiz.ID, c.Param("questionId"), content)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrUnsupportedImage), errors.Is(err, services.ErrImageTooLarge):
+			status = http.StatusBadRequest
+		case errors.Is(err, services.ErrNoImageStorage):
+			status = http.StatusServiceUnavailable
+		case err.Error() == "question not found":
+			status = http.StatusNotFound
+		default:
+			h.logger.Errorf("Failed to attach image to question %s: %v", c.Param("questionId"), err)
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Image attached successfully",
+		Data:    link,
+	})
+}
+
+// DeleteQuestionImage removes the image of a question
+func (h *QuizHandler) DeleteQuestionImage(c *gin.Context) {
+	quiz, ok := h.getOwnedQuiz(c)
+	if !ok {
+		return
+	}
+
+	if err := h.imageService.RemoveQuestionImage(quiz.ID, c.Param("questionId")); err != nil {
+		status := http.StatusInternalServerError
+		if err.Error() == "question not found" {
+			status = http.StatusNotFound
+		} else {
+			h.logger.Errorf("Failed to remove image of question %s: %v", c.Param("questionId"), err)
+		}
+		c.JSON(status, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Image removed successfully",
+	})
+}
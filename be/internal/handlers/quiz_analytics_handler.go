@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBloomCoverage reports how the questions of a quiz cover the Bloom's taxonomy levels,
+// compared with the requested mix, and how often each level was answered correctly
+func (h *QuizHandler) GetBloomCoverage(c *gin.Context) {
+	quiz, ok := h.getOwnedQuiz(c)
+	if !ok {
+		return
+	}
+
+	coverage, err := h.quizService.GetBloomCoverage(quiz)
+	if err != nil {
+		h.logger.Errorf("Failed to get bloom coverage for quiz %s: %v", quiz.ID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve coverage",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Coverage retrieved successfully",
+		Data:    coverage,
+	})
+}
+
+// LintQuiz checks the questions of a quiz for item-writing flaws. Answer keys are also
+// checked against the source document when the quiz was generated from an upload.
+func (h *QuizHandler) LintQuiz(c *gin.Context) {
+	quiz, ok := h.getOwnedQuiz(c)
+	if !ok {
+		return
+	}
+
+	source := ""
+	if quiz.DocumentID != "" {
+		doc, err := h.documentService.GetDocument(quiz.DocumentID)
+		if err != nil {
+			h.logger.Warnf("Failed to load source document %s of quiz %s: %v", quiz.DocumentID, quiz.ID, err)
+		} else {
+			source = doc.Content
+		}
+	}
+
+	report := services.LintQuiz(quiz, source)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Found %d errors and %d warnings", report.Errors, report.Warnings),
+		Data:    report,
+	})
+}
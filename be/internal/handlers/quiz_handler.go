@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"pbkk-quizlit-backend/internal/middleware"
@@ -15,31 +16,29 @@ import (
 )
 
 type QuizHandler struct {
-	quizService *services.QuizService
-	aiService   *services.AIService
-	fileService *services.FileService
-	logger      *logrus.Logger
+	quizService     *services.QuizService
+	aiService       *services.AIService
+	fileService     *services.FileService
+	documentService *services.DocumentService
+	imageService    *services.ImageService
+	logger          *logrus.Logger
 }
 
-func NewQuizHandler(quizService *services.QuizService, aiService *services.AIService, fileService *services.FileService) *QuizHandler {
+func NewQuizHandler(quizService *services.QuizService, aiService *services.AIService, fileService *services.FileService, documentService *services.DocumentService, imageService *services.ImageService) *QuizHandler {
 	return &QuizHandler{
-		quizService: quizService,
-		aiService:   aiService,
-		fileService: fileService,
-		logger:      logrus.New(),
+		quizService:     quizService,
+		aiService:       aiService,
+		fileService:     fileService,
+		documentService: documentService,
+		imageService:    imageService,
+		logger:          logrus.New(),
 	}
 }
 
 // UploadFileAndGenerateQuiz handles file upload and quiz generation
 func (h *QuizHandler) UploadFileAndGenerateQuiz(c *gin.Context) {
 	// Parse multipart form
-	err := c.Request.ParseMultipartForm(10 << 20) // 10 MB max
-	if err != nil {
-		h.logger.Errorf("Failed to parse multipart form: %v", err)
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success: false,
-			Message: "Failed to parse form data",
-		})
+	if !parseUploadForm(c, h.fileService, h.logger) {
 		return
 	}
 
@@ -67,11 +66,63 @@ func (h *QuizHandler) UploadFileAndGenerateQuiz(c *gin.Context) {
 		return
 	}
 
-	// Process the uploaded file
-	content, err := h.fileService.ProcessUploadedFile(file, header)
+	// Optional target mix of cognitive levels, e.g. {"apply": 40, "remember": 60}
+	var bloomMix map[string]float64
+	if raw := c.Request.FormValue("bloomMix"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &bloomMix); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success: false,
+				Message: "bloomMix must be a JSON object of level shares",
+			})
+			return
+		}
+	}
+	bloomMix, err = services.NormalizeBloomMix(bloomMix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Optional sections to cover, as a JSON array or separated by commas
+	sections, err := parseSectionsField(c.Request.FormValue("sections"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Optional page ranges to cover, e.g. "40-55"
+	pageSpec := c.Request.FormValue("pages")
+	if _, err := services.ParsePageRanges(pageSpec); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Optional language and normalisation steps for the extracted text
+	normalize, err := normalizeOptionsField(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	// Keep the source document in the library so the quiz can be checked against it and
+	// more quizzes can be generated from it later
+	userID := middleware.GetUserID(c)
+	doc, err := ingestUpload(h.fileService, h.documentService, h.logger, file, header, userID, normalize)
 	if err != nil {
 		h.logger.Errorf("Failed to process file: %v", err)
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		c.JSON(UploadErrorStatus(err), models.APIResponse{
 			Success: false,
 			Message: "Failed to process uploaded file: " + err.Error(),
 		})
@@ -84,10 +135,132 @@ func (h *QuizHandler) UploadFileAndGenerateQuiz(c *gin.Context) {
 		Description:   description,
 		Difficulty:    difficulty,
 		QuestionCount: 10, // Default
+		BloomMix:      bloomMix,
+		Corpus:        h.keywordCorpus(c),
 	}
 
-	// Generate quiz using AI
-	quiz, err := h.aiService.GenerateQuizFromContent(content, quizReq)
+	pages, err := services.SelectParts(doc.Pages, sections, pageSpec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.generateQuizFromDocument(c, doc, pages, sections, quizReq)
+}
+
+// GenerateQuizFromDocument generates a quiz from a document in the user's library
+func (h *QuizHandler) GenerateQuizFromDocument(c *gin.Context) {
+	var req models.GenerateFromDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid request format",
+		})
+		return
+	}
+
+	bloomMix, err := services.NormalizeBloomMix(req.BloomMix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	doc, err := h.documentService.GetDocument(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Document not found",
+		})
+		return
+	}
+	if doc.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this document",
+		})
+		return
+	}
+
+	if req.Difficulty == "" {
+		req.Difficulty = "medium"
+	}
+	if req.QuestionCount <= 0 {
+		req.QuestionCount = 10
+	}
+	if req.Description == "" {
+		req.Description = "Quiz generated from " + doc.Filename
+	}
+
+	pages, err := services.SelectParts(doc.Pages, req.Sections, req.Pages)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	quizReq := &models.QuizGenerationRequest{
+		Title:         req.Title,
+		Description:   req.Description,
+		Difficulty:    req.Difficulty,
+		QuestionCount: req.QuestionCount,
+		BloomMix:      bloomMix,
+		Corpus:        h.keywordCorpus(c),
+	}
+
+	h.generateQuizFromDocument(c, doc, pages, req.Sections, quizReq)
+}
+
+// parseSectionsField reads the sections chosen in a form, given as a JSON array or as a
+// list separated by commas
+func parseSectionsField(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var sections []string
+		if err := json.Unmarshal([]byte(raw), &sections); err != nil {
+			return nil, fmt.Errorf("sections must be a JSON array of section IDs or titles")
+		}
+		return sections, nil
+	}
+
+	var sections []string
+	for _, section := range strings.Split(raw, ",") {
+		if section = strings.TrimSpace(section); section != "" {
+			sections = append(sections, section)
+		}
+	}
+	return sections, nil
+}
+
+// generateQuizFromDocument generates, links and saves a quiz for the given pages of a
+// document, making sure the document has study notes to serve with it. The quiz records
+// which pages and sections it covers when they are not the whole document.
+func (h *QuizHandler) generateQuizFromDocument(c *gin.Context, doc *models.Document, pages []models.DocumentPage, sections []string, quizReq *models.QuizGenerationRequest) {
+	if doc.ID != "" && doc.Notes == nil {
+		doc.Notes = h.aiService.GenerateStudyNotes(doc.Pages, false)
+		if err := h.documentService.SaveNotes(doc.ID, doc.Notes); err != nil {
+			h.logger.Warnf("Failed to save study notes for document %s: %v", doc.ID, err)
+		}
+	}
+
+	// Generate quiz using AI; slide decks are covered slide group by slide group
+	var quiz *models.Quiz
+	var err error
+	if services.IsSlideDeck(doc.Filename) {
+		quiz, err = h.aiService.GenerateQuizFromSlides(pages, quizReq)
+	} else {
+		quiz, err = h.aiService.GenerateQuizFromContent(services.JoinPages(pages), quizReq)
+	}
 	if err != nil {
 		h.logger.Errorf("Failed to generate quiz: %v", err)
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -96,12 +269,20 @@ func (h *QuizHandler) UploadFileAndGenerateQuiz(c *gin.Context) {
 		})
 		return
 	}
-
-	// Get user ID from context
-	userID := middleware.GetUserID(c)
+	// Questions from a recording point at the moment of the recording they are about
+	if services.IsTranscript(doc.Filename) {
+		services.CiteTimestamps(quiz, pages)
+	}
+	quiz.DocumentID = doc.ID
+	quiz.SourceFilename = doc.Filename
+	quiz.Notes = doc.Notes
+	if len(sections) > 0 || len(pages) < len(doc.Pages) {
+		quiz.SourcePages = services.FormatPageRanges(pages)
+		quiz.SourceSections = sections
+	}
 
 	// Save quiz
-	err = h.quizService.CreateQuiz(quiz, userID)
+	err = h.quizService.CreateQuiz(quiz, middleware.GetUserID(c))
 	if err != nil {
 		h.logger.Errorf("Failed to save quiz: %v", err)
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
@@ -114,7 +295,7 @@ func (h *QuizHandler) UploadFileAndGenerateQuiz(c *gin.Context) {
 	h.logger.Infof("Successfully created quiz: %s", quiz.ID)
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
-		Message: "Quiz generated successfully",
+		Message: quizGeneratedMessage(quiz),
 		Data:    quiz,
 	})
 }
@@ -130,12 +311,23 @@ func (h *QuizHandler) GenerateQuizFromText(c *gin.Context) {
 		return
 	}
 
+	bloomMix, err := services.NormalizeBloomMix(req.BloomMix)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// Create quiz request
 	quizReq := &models.QuizGenerationRequest{
 		Title:         req.Title,
 		Description:   req.Description,
 		Difficulty:    req.Difficulty,
 		QuestionCount: req.QuestionCount,
+		BloomMix:      bloomMix,
+		Corpus:        h.keywordCorpus(c),
 	}
 
 	if quizReq.QuestionCount == 0 {
@@ -167,11 +359,32 @@ func (h *QuizHandler) GenerateQuizFromText(c *gin.Context) {
 	h.logger.Infof("Successfully created quiz from text: %s", quiz.ID)
 	c.JSON(http.StatusCreated, models.APIResponse{
 		Success: true,
-		Message: "Quiz generated successfully",
+		Message: quizGeneratedMessage(quiz),
 		Data:    quiz,
 	})
 }
 
+// quizGeneratedMessage tells about a generated quiz, pointing out levels of the requested
+// cognitive level mix that were not met
+func quizGeneratedMessage(quiz *models.Quiz) string {
+	message := "Quiz generated successfully"
+	if short := services.BloomMixShortfall(quiz.BloomMix, quiz.Questions); len(short) > 0 {
+		message += "; fewer questions than asked for at some cognitive levels: " + strings.Join(short, ", ")
+	}
+	return message
+}
+
+// keywordCorpus returns the current user's document corpus used to rank keywords, or nil
+// when it cannot be loaded
+func (h *QuizHandler) keywordCorpus(c *gin.Context) *models.KeywordCorpus {
+	corpus, err := h.documentService.GetKeywordCorpus(middleware.GetUserID(c), services.KeywordOptions{})
+	if err != nil {
+		h.logger.Warnf("Failed to build keyword corpus: %v", err)
+		return nil
+	}
+	return corpus
+}
+
 // GetQuiz returns a specific quiz
 func (h *QuizHandler) GetQuiz(c *gin.Context) {
 	id := c.Param("id")
@@ -184,6 +397,8 @@ func (h *QuizHandler) GetQuiz(c *gin.Context) {
 		})
 		return
 	}
+	h.attachNotes(quiz)
+	h.imageService.AttachLinks(quiz)
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success: true,
@@ -192,6 +407,20 @@ func (h *QuizHandler) GetQuiz(c *gin.Context) {
 	})
 }
 
+// attachNotes adds the study notes of the quiz's source document, if it has one
+func (h *QuizHandler) attachNotes(quiz *models.Quiz) {
+	if quiz.DocumentID == "" {
+		return
+	}
+
+	doc, err := h.documentService.GetDocument(quiz.DocumentID)
+	if err != nil {
+		h.logger.Warnf("Failed to load source document %s of quiz %s: %v", quiz.DocumentID, quiz.ID, err)
+		return
+	}
+	quiz.Notes = doc.Notes
+}
+
 // GetAllQuizzes returns all quizzes for the authenticated user
 func (h *QuizHandler) GetAllQuizzes(c *gin.Context) {
 	// Get user ID from auth middleware
@@ -276,6 +505,10 @@ func (h *QuizHandler) DeleteQuiz(c *gin.Context) {
 		return
 	}
 
+	if err := h.imageService.DeleteQuizImages(quiz); err != nil {
+		h.logger.Warnf("Failed to delete question images of quiz %s: %v", id, err)
+	}
+
 	h.logger.Infof("Quiz %s deleted by user %s", id, userID)
 
 	c.JSON(http.StatusOK, models.APIResponse{
@@ -284,6 +517,29 @@ func (h *QuizHandler) DeleteQuiz(c *gin.Context) {
 	})
 }
 
+// getOwnedQuiz loads the quiz in the URL and checks it belongs to the current user.
+// It writes the error response itself and returns false when the request should stop.
+func (h *QuizHandler) getOwnedQuiz(c *gin.Context) (*models.Quiz, bool) {
+	quiz, err := h.quizService.GetQuiz(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Quiz not found",
+		})
+		return nil, false
+	}
+
+	if quiz.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this quiz",
+		})
+		return nil, false
+	}
+
+	return quiz, true
+}
+
 // generateFallbackQuiz creates a quiz when AI service fails
 func (h *QuizHandler) generateFallbackQuiz(content string, req *models.QuizGenerationRequest) *models.Quiz {
 	h.logger.Info("Generating fallback quiz")
@@ -33,14 +33,31 @@ func (h *QuizHandler) GetQuizForTaking(c *gin.Context) {
 		return
 	}
 
-	// Remove correct answers from questions
-	for i := range quiz.Questions {
-		quiz.Questions[i].CorrectAnswer = -1 // Hide correct answer
-	}
+	h.respondWithQuizForTaking(c, quiz)
+}
+
+// respondWithQuizForTaking writes a quiz for a student to take, without anything that
+// gives the answers away
+func (h *QuizHandler) respondWithQuizForTaking(c *gin.Context, quiz *models.Quiz) {
+	hideAnswers(quiz)
+	h.attachNotes(quiz)
+	h.imageService.AttachLinks(quiz)
 
 	c.JSON(http.StatusOK, quiz)
 }
 
+// hideAnswers removes the answer key and explanations from the questions of a quiz. The
+// explanation of a question converted from a flashcard is the back of the card, which is
+// the answer itself.
+func hideAnswers(quiz *models.Quiz) {
+	for i := range quiz.Questions {
+		q := &quiz.Questions[i]
+		q.CorrectAnswer = -1 // Hide correct answer
+		q.Correct = ""
+		q.Explanation = ""
+	}
+}
+
 // SubmitQuizAttempt handles quiz submission and scoring
 func (h *QuizHandler) SubmitQuizAttempt(c *gin.Context) {
 	var submission struct {
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func TestQuizForTakingHidesAnswers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// A deck converted to a quiz: the explanation is the back of the card, which names
+	// the term the question asks for
+	deck := &models.FlashcardDeck{
+		ID:    "9",
+		Title: "Cells",
+		Cards: []models.Flashcard{
+			{ID: "1", Front: "Mitochondrion", Back: "The Mitochondrion releases energy from glucose"},
+			{ID: "2", Front: "Ribosome", Back: "The Ribosome builds proteins"},
+			{ID: "3", Front: "Nucleus", Back: "The Nucleus holds the genetic material"},
+			{ID: "4", Front: "Chloroplast", Back: "The Chloroplast carries out photosynthesis"},
+		},
+	}
+	quiz, err := services.NewFlashcardService().DeckToQuiz(deck, &models.DeckToQuizRequest{Title: "Cells"})
+	if err != nil {
+		t.Fatalf("DeckToQuiz: %v", err)
+	}
+	quiz.Questions = append(quiz.Questions, models.Question{
+		ID:            "5",
+		Text:          "Which organelle stores water?",
+		Options:       []string{"Vacuole", "Ribosome"},
+		Correct:       "Vacuole",
+		CorrectAnswer: 0,
+		Explanation:   "The vacuole stores water.",
+	})
+
+	h := &QuizHandler{imageService: services.NewImageService(nil, nil), logger: logrus.New()}
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	h.respondWithQuizForTaking(c, quiz)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", w.Code)
+	}
+	var payload struct {
+		Questions []map[string]interface{} `json:"questions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("payload is not JSON: %v", err)
+	}
+	if len(payload.Questions) != len(deck.Cards)+1 {
+		t.Fatalf("%d questions in the payload, want %d", len(payload.Questions), len(deck.Cards)+1)
+	}
+
+	for _, q := range payload.Questions {
+		if q["correctAnswer"] != float64(-1) {
+			t.Errorf("question %v: correctAnswer %v, want -1", q["id"], q["correctAnswer"])
+		}
+		if correct, _ := q["correct"].(string); correct != "" {
+			t.Errorf("question %v: correct %q sent", q["id"], correct)
+		}
+		if explanation, ok := q["explanation"]; ok {
+			t.Errorf("question %v: explanation %q sent", q["id"], explanation)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"pbkk-quizlit-backend/internal/middleware"
+	"pbkk-quizlit-backend/internal/models"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TranslateQuiz creates a translated copy of a quiz linked to the original
+func (h *QuizHandler) TranslateQuiz(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.TranslateQuizRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Target language is required",
+		})
+		return
+	}
+
+	targetLanguage := strings.ToLower(strings.TrimSpace(req.TargetLanguage))
+	if targetLanguage == "" || len(targetLanguage) > 35 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Invalid target language",
+		})
+		return
+	}
+
+	quiz, ok := h.getOwnedQuiz(c)
+	if !ok {
+		return
+	}
+
+	if strings.EqualFold(quiz.Language, targetLanguage) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Quiz is already in the target language",
+		})
+		return
+	}
+
+	translated, err := h.aiService.TranslateQuiz(quiz, targetLanguage)
+	if err != nil {
+		h.logger.Errorf("Failed to translate quiz %s: %v", id, err)
+		c.JSON(http.StatusBadGateway, models.APIResponse{
+			Success: false,
+			Message: "Failed to translate quiz: " + err.Error(),
+		})
+		return
+	}
+	// Get user ID from context
+	userID := middleware.GetUserID(c)
+
+	if err := h.quizService.CreateQuiz(translated, userID); err != nil {
+		h.logger.Errorf("Failed to save translated quiz: %v", err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to save translated quiz",
+		})
+		return
+	}
+
+	h.logger.Infof("Quiz %s translated to %s as quiz %s", id, targetLanguage, translated.ID)
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Quiz translated successfully",
+		Data:    translated,
+	})
+}
+
+// GetQuizTranslations returns attempt statistics for every language version of a quiz
+func (h *QuizHandler) GetQuizTranslations(c *gin.Context) {
+	quiz, ok := h.getOwnedQuiz(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.quizService.GetTranslationStats(quiz.ID)
+	if err != nil {
+		h.logger.Errorf("Failed to get translation stats: %v", err)
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Quiz not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Quiz translations retrieved successfully",
+		Data:    stats,
+	})
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"net/http"
+	"pbkk-quizlit-backend/internal/middleware"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type TutorHandler struct {
+	tutorService    *services.TutorService
+	documentService *services.DocumentService
+	quizService     *services.QuizService
+	aiService       *services.AIService
+	logger          *logrus.Logger
+}
+
+func NewTutorHandler(tutorService *services.TutorService, documentService *services.DocumentService, quizService *services.QuizService, aiService *services.AIService) *TutorHandler {
+	return &TutorHandler{
+		tutorService:    tutorService,
+		documentService: documentService,
+		quizService:     quizService,
+		aiService:       aiService,
+		logger:          logrus.New(),
+	}
+}
+
+// AskDocument answers a question about a document
+func (h *TutorHandler) AskDocument(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	h.answer(c, doc)
+}
+
+// AskQuizSource answers a question about the document a quiz was generated from
+func (h *TutorHandler) AskQuizSource(c *gin.Context) {
+	quiz, err := h.quizService.GetQuiz(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Quiz not found",
+		})
+		return
+	}
+
+	if quiz.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this quiz",
+		})
+		return
+	}
+
+	if quiz.DocumentID == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "This quiz has no source document to ask about",
+		})
+		return
+	}
+
+	doc, ok := h.getOwnedDocument(c, quiz.DocumentID)
+	if !ok {
+		return
+	}
+	h.answer(c, doc)
+}
+
+// answer retrieves the relevant passages of a document, answers the question from them
+// and stores the exchange in the user's conversation about the document
+func (h *TutorHandler) answer(c *gin.Context, doc *models.Document) {
+	var req models.TutorAskRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Question) == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Question is required",
+		})
+		return
+	}
+	if req.Retrieval != "" && req.Retrieval != services.RetrievalBM25 && req.Retrieval != services.RetrievalEmbeddings {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Retrieval must be bm25 or embeddings",
+		})
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	history, err := h.tutorService.GetHistory(userID, doc.ID, 0)
+	if err != nil {
+		// Answer without context rather than not at all
+		h.logger.Warnf("Failed to load tutor history for document %s: %v", doc.ID, err)
+	}
+
+	answer := h.aiService.AnswerQuestion(h.tutorService.GetIndex(doc), strings.TrimSpace(req.Question), history, req.Retrieval)
+	answer.DocumentID = doc.ID
+
+	if err := h.tutorService.RecordExchange(userID, answer); err != nil {
+		h.logger.Errorf("Failed to store tutor conversation for document %s: %v", doc.ID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to save conversation",
+		})
+		return
+	}
+
+	h.logger.Infof("Answered tutor question on document %s with %d citations (%s, %s)", doc.ID, len(answer.Citations), answer.Retrieval, answer.Method)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Question answered successfully",
+		Data:    answer,
+	})
+}
+
+// GetHistory returns the user's conversation about a document
+func (h *TutorHandler) GetHistory(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	messages, err := h.tutorService.GetHistory(middleware.GetUserID(c), doc.ID, limit)
+	if err != nil {
+		h.logger.Errorf("Failed to get tutor history for document %s: %v", doc.ID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to retrieve conversation",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Conversation retrieved successfully",
+		Data: models.TutorHistoryResponse{
+			DocumentID: doc.ID,
+			Filename:   doc.Filename,
+			Messages:   messages,
+		},
+	})
+}
+
+// ClearHistory deletes the user's conversation about a document
+func (h *TutorHandler) ClearHistory(c *gin.Context) {
+	doc, ok := h.getOwnedDocument(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	if err := h.tutorService.ClearHistory(middleware.GetUserID(c), doc.ID); err != nil {
+		h.logger.Errorf("Failed to clear tutor history for document %s: %v", doc.ID, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to clear conversation",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Conversation cleared successfully",
+	})
+}
+
+// ExplainAttempt explains every wrong answer of an attempt, or only the one given by
+// ?questionId=. LLM explanations are cached per question and chosen option.
+func (h *TutorHandler) ExplainAttempt(c *gin.Context) {
+	attempt, err := h.quizService.GetAttemptRecord(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Attempt not found",
+		})
+		return
+	}
+
+	if attempt.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this attempt",
+		})
+		return
+	}
+
+	quiz, err := h.quizService.GetQuiz(attempt.QuizID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Quiz not found",
+		})
+		return
+	}
+
+	// Ground the explanations in the source document when the quiz has one
+	var index *services.DocumentIndex
+	if quiz.DocumentID != "" {
+		if doc, err := h.documentService.GetDocument(quiz.DocumentID); err == nil {
+			index = h.tutorService.GetIndex(doc)
+		} else {
+			h.logger.Warnf("Failed to load source document %s of quiz %s: %v", quiz.DocumentID, quiz.ID, err)
+		}
+	}
+
+	feedback := models.AttemptFeedback{
+		AttemptID:      attempt.ID,
+		QuizID:         attempt.QuizID,
+		Score:          attempt.Score,
+		TotalQuestions: attempt.TotalQuestions,
+		Mistakes:       []models.MistakeExplanation{},
+	}
+
+	onlyQuestion := c.Query("questionId")
+	for _, question := range quiz.Questions {
+		if onlyQuestion != "" && question.ID != onlyQuestion {
+			continue
+		}
+
+		chosen := attempt.Answers[question.ID]
+		if chosen == "" || question.CorrectAnswer < 0 || question.CorrectAnswer >= len(question.Options) ||
+			chosen == question.Options[question.CorrectAnswer] {
+			continue
+		}
+
+		feedback.Mistakes = append(feedback.Mistakes, *h.explainMistake(question, chosen, index))
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Mistakes explained successfully",
+		Data:    feedback,
+	})
+}
+
+// explainMistake returns the cached explanation of choosing an option, generating and
+// caching it on first use. Fallback explanations are not cached, so they are replaced
+// once an LLM provider is available.
+func (h *TutorHandler) explainMistake(question models.Question, chosen string, index *services.DocumentIndex) *models.MistakeExplanation {
+	cached, err := h.quizService.GetAnswerExplanation(question.ID, chosen)
+	if err != nil {
+		h.logger.Warnf("Failed to read cached explanation for question %s: %v", question.ID, err)
+	}
+	if cached != nil {
+		cached.QuestionID = question.ID
+		cached.QuestionText = question.Text
+		if cached.QuestionText == "" {
+			cached.QuestionText = question.Question
+		}
+		cached.UserAnswer = chosen
+		cached.CorrectAnswer = question.Options[question.CorrectAnswer]
+		cached.Method = "llm"
+		cached.Cached = true
+		return cached
+	}
+
+	explanation := h.aiService.ExplainMistake(question, chosen, index)
+	if explanation.Method == "llm" {
+		if err := h.quizService.SaveAnswerExplanation(question.ID, chosen, explanation); err != nil {
+			h.logger.Warnf("Failed to cache explanation for question %s: %v", question.ID, err)
+		}
+	}
+	return explanation
+}
+
+// getOwnedDocument loads a document and checks it belongs to the current user.
+// It writes the error response itself and returns false when the request should stop.
+func (h *TutorHandler) getOwnedDocument(c *gin.Context, id string) (*models.Document, bool) {
+	doc, err := h.documentService.GetDocument(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success: false,
+			Message: "Document not found",
+		})
+		return nil, false
+	}
+
+	if doc.UserID != middleware.GetUserID(c) {
+		c.JSON(http.StatusForbidden, models.APIResponse{
+			Success: false,
+			Message: "You don't have permission to access this document",
+		})
+		return nil, false
+	}
+
+	return doc, true
+}
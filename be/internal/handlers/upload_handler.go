@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"pbkk-quizlit-backend/internal/middleware"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// tusVersion is the version of the tus protocol resumable uploads follow
+const tusVersion = "1.0.0"
+
+// statusChecksumMismatch is the status tus gives a chunk that does not match its checksum
+const statusChecksumMismatch = 460
+
+// UploadHandler takes uploads in chunks, following the tus protocol: POST creates an
+// upload, PATCH sends a chunk at the offset the upload stopped at, HEAD tells that offset
+// after a dropped connection and DELETE gives up. The last chunk adds the file to the
+// user's document library.
+type UploadHandler struct {
+	sessions        *services.UploadSessions
+	fileService     *services.FileService
+	documentService *services.DocumentService
+	logger          *logrus.Logger
+}
+
+func NewUploadHandler(sessions *services.UploadSessions, fileService *services.FileService, documentService *services.DocumentService) *UploadHandler {
+	return &UploadHandler{
+		sessions:        sessions,
+		fileService:     fileService,
+		documentService: documentService,
+		logger:          logrus.New(),
+	}
+}
+
+// CreateUpload starts an upload of Upload-Length bytes. Upload-Metadata must give the
+// filename and may give the filetype, the sha256 of the whole file in hex, and the
+// language and normalize options of other uploads.
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Upload-Length must be the size of the file in bytes",
+		})
+		return
+	}
+	if limit := h.fileService.Limits().MaxFileSize; limit > 0 && length > limit {
+		c.JSON(http.StatusRequestEntityTooLarge, models.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("%v: the limit is %d MB", services.ErrFileTooLarge, limit>>20),
+		})
+		return
+	}
+
+	metadata, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	filename := path.Base(strings.ReplaceAll(metadata["filename"], "\\", "/"))
+	if strings.TrimSpace(metadata["filename"]) == "" || filename == "." || filename == "/" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Upload-Metadata must give the filename",
+		})
+		return
+	}
+	if !services.SupportedFileType(filename) {
+		c.JSON(http.StatusUnsupportedMediaType, models.APIResponse{
+			Success: false,
+			Message: fmt.Sprintf("%v: %s", services.ErrUnsupportedFile, path.Ext(filename)),
+		})
+		return
+	}
+	hash := metadata["sha256"]
+	if _, err := hex.DecodeString(hash); err != nil || (hash != "" && len(hash) != 64) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "sha256 must be the SHA-256 of the file in hex",
+		})
+		return
+	}
+	if _, err := parseNormalizeOptions(metadata["language"], metadata["normalize"]); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	extra := make(map[string]string)
+	for _, key := range []string{"language", "normalize"} {
+		if value := metadata[key]; value != "" {
+			extra[key] = value
+		}
+	}
+	session, err := h.sessions.Create(middleware.GetUserID(c), filename, metadata["filetype"], length, hash, extra)
+	if errors.Is(err, services.ErrTooManyUploads) || errors.Is(err, services.ErrUploadSpace) {
+		c.JSON(uploadSessionStatus(err), models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Errorf("Failed to create upload of %s: %v", filename, err)
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success: false,
+			Message: "Failed to create upload",
+		})
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+session.ID)
+	setUploadHeaders(c, session)
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: "Upload created successfully",
+		Data:    session,
+	})
+}
+
+// GetUploadOffset tells how much of an upload arrived, so a client can resume it
+func (h *UploadHandler) GetUploadOffset(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Cache-Control", "no-store")
+
+	session, err := h.sessions.Get(middleware.GetUserID(c), c.Param("id"))
+	if err != nil {
+		c.Status(uploadSessionStatus(err))
+		return
+	}
+	setUploadHeaders(c, session)
+	c.Status(http.StatusOK)
+}
+
+// AppendUpload writes a chunk sent as application/offset+octet-stream at Upload-Offset.
+// An Upload-Checksum of "sha256 <base64 digest>" has the chunk checked. The chunk that
+// completes the upload adds the file to the document library and returns the document.
+func (h *UploadHandler) AppendUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, models.APIResponse{
+			Success: false,
+			Message: "Chunks must be sent as application/offset+octet-stream",
+		})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: "Upload-Offset must be where the upload stopped, in bytes",
+		})
+		return
+	}
+	checksum, err := parseUploadChecksum(c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	userID, id := middleware.GetUserID(c), c.Param("id")
+	session, err := h.sessions.Append(userID, id, offset, c.Request.Body, checksum)
+	if session != nil {
+		setUploadHeaders(c, session)
+	}
+	if err != nil {
+		h.logger.Warnf("Failed to write chunk of upload %s: %v", id, err)
+		c.JSON(uploadSessionStatus(err), models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if session.Offset < session.Length {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	h.finishUpload(c, userID, id)
+}
+
+// finishUpload hands a complete upload over to the document library. When that fails
+// the upload is kept, so the client can send the last PATCH again, with an empty body.
+func (h *UploadHandler) finishUpload(c *gin.Context, userID, id string) {
+	var doc *models.Document
+	var ingestErr error
+	err := h.sessions.Finish(userID, id, func(upload *services.SpooledUpload, session *models.UploadSession) error {
+		// The options were checked when the upload was created
+		normalize, _ := parseNormalizeOptions(session.Metadata["language"], session.Metadata["normalize"])
+		doc, ingestErr = ingestSpooled(h.fileService, h.documentService, h.logger, upload, session.Filename, session.ContentType, userID, normalize)
+		return ingestErr
+	})
+	if ingestErr != nil {
+		h.logger.Errorf("Failed to process upload %s: %v", id, ingestErr)
+		c.JSON(UploadErrorStatus(ingestErr), models.APIResponse{
+			Success: false,
+			Message: "Failed to process uploaded file: " + ingestErr.Error(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.Warnf("Failed to finish upload %s: %v", id, err)
+		if errors.Is(err, services.ErrChecksumMismatch) {
+			c.Header("Upload-Offset", "0")
+		}
+		c.JSON(uploadSessionStatus(err), models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	h.logger.Infof("Stored document %s (%s, %d pages) from upload %s", doc.ID, doc.Filename, doc.PageCount, id)
+	doc.Pages = nil
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success: true,
+		Message: uploadedMessage(doc),
+		Data:    doc,
+	})
+}
+
+// DeleteUpload gives up on an upload and removes what arrived of it
+func (h *UploadHandler) DeleteUpload(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+
+	if err := h.sessions.Delete(middleware.GetUserID(c), c.Param("id")); err != nil {
+		c.JSON(uploadSessionStatus(err), models.APIResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// setUploadHeaders tells a tus client where an upload stands
+func setUploadHeaders(c *gin.Context, session *models.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+// uploadSessionStatus returns the HTTP status of an error writing to an upload
+func uploadSessionStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, services.ErrUploadOffset), errors.Is(err, services.ErrUploadBusy), errors.Is(err, services.ErrUploadIncomplete):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrChecksumMismatch):
+		return statusChecksumMismatch
+	case errors.Is(err, services.ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrTooManyUploads):
+		return http.StatusTooManyRequests
+	case errors.Is(err, services.ErrUploadSpace):
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// parseUploadMetadata reads a tus Upload-Metadata header: comma-separated keys, each
+// followed by a space and its value in base64
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("Upload-Metadata %s is not base64", key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// parseUploadChecksum reads a tus Upload-Checksum header, of which only SHA-256 is
+// supported. It returns nil when there is none.
+func parseUploadChecksum(header string) ([]byte, error) {
+	if header == "" {
+		return nil, nil
+	}
+	algorithm, encoded, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if algorithm != "sha256" {
+		return nil, fmt.Errorf("unsupported checksum algorithm %q, expected sha256", algorithm)
+	}
+	checksum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(checksum) != 32 {
+		return nil, fmt.Errorf("Upload-Checksum must be a SHA-256 digest in base64")
+	}
+	return checksum, nil
+}
@@ -0,0 +1,177 @@
+package models
+
+import "time"
+
+// Document is an uploaded source file together with the text extracted from it
+type Document struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"user_id,omitempty"`
+	Filename    string            `json:"filename"`
+	ContentType string            `json:"contentType,omitempty"`
+	Size        int64             `json:"size"`
+	SHA256      string            `json:"sha256,omitempty"`
+	PageCount   int               `json:"pageCount"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	StorageKey  string            `json:"-"`
+	Content     string            `json:"-"`
+	Pages       []DocumentPage    `json:"pages,omitempty"`
+	Sections    []DocumentSection `json:"sections,omitempty"`
+	Tables      []DocumentTable   `json:"tables,omitempty"`
+	OCR         *DocumentOCR      `json:"ocr,omitempty"`
+	Glossary    []GlossaryTerm    `json:"glossary,omitempty"`
+	Notes       *StudyNotes       `json:"notes,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+
+	// PageStatus reports how each page of a PDF was extracted. It comes with the upload
+	// that extracted the document and is not stored.
+	PageStatus []PageStatus `json:"pageStatus,omitempty"`
+}
+
+// HasFile reports whether the original upload of the document is kept in storage
+func (d *Document) HasFile() bool {
+	return d.StorageKey != ""
+}
+
+// FileLink is a download URL that works without authentication until it expires
+type FileLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type DocumentPage struct {
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+	// Label names the page where a page number would be misleading, e.g. "Slide 4"
+	Label string `json:"label,omitempty"`
+	// OCR is set on pages without a text layer whose text was read by OCR
+	OCR *PageOCR `json:"ocr,omitempty"`
+}
+
+// UploadSession is a document being uploaded in chunks, which can be resumed from Offset
+// after a dropped connection until it expires
+type UploadSession struct {
+	ID          string            `json:"id"`
+	UserID      string            `json:"userId"`
+	Filename    string            `json:"filename"`
+	ContentType string            `json:"contentType,omitempty"`
+	Length      int64             `json:"length"`
+	Offset      int64             `json:"offset"`
+	SHA256      string            `json:"sha256,omitempty"`   // expected hash of the whole file
+	Metadata    map[string]string `json:"metadata,omitempty"` // other upload fields, e.g. language
+	CreatedAt   time.Time         `json:"createdAt"`
+	ExpiresAt   time.Time         `json:"expiresAt"`
+}
+
+// PageOCR tells how the text of a page was read by OCR. Confidence is between 0 and 1,
+// and left out when the engine does not report it.
+type PageOCR struct {
+	Engine     string  `json:"engine"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// PageStatus tells how a page of a PDF was extracted: "ok", "plain" when only its plain
+// text could be read, "ocr", "empty", "missing", "failed" or "timeout"
+type PageStatus struct {
+	Page       int    `json:"page"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// DocumentOCR sums up the pages of a document read by OCR. Pages with a low confidence
+// are worth checking against the original.
+type DocumentOCR struct {
+	Engine             string  `json:"engine"`
+	Pages              []int   `json:"pages"`
+	Confidence         float64 `json:"confidence,omitempty"`
+	LowConfidencePages []int   `json:"lowConfidencePages,omitempty"`
+}
+
+// DocumentBlock is a heading, paragraph, list item, table or code block of a page
+type DocumentBlock struct {
+	Kind  string `json:"kind"`            // "heading", "paragraph", "list", "table" or "code"
+	Level int    `json:"level,omitempty"` // level of a heading, depth of a list item
+	Text  string `json:"text"`
+	// Rows are the cells of a table, the first row usually being its header
+	Rows [][]string `json:"rows,omitempty"`
+	// Caption is the "Table 2: ..." line set above or below a table
+	Caption string `json:"caption,omitempty"`
+}
+
+// DocumentTable is a table of a document, as rows of cells
+type DocumentTable struct {
+	Page    int        `json:"page"`
+	Caption string     `json:"caption,omitempty"`
+	Rows    [][]string `json:"rows"`
+}
+
+// DocumentSection is a heading of a document and the pages its section spans. IDs number
+// sections by their place in the hierarchy, e.g. "2.1" for the first subsection of the
+// second chapter.
+type DocumentSection struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	Level     int    `json:"level"`
+	FirstPage int    `json:"firstPage"`
+	LastPage  int    `json:"lastPage"`
+}
+
+type GlossaryTerm struct {
+	Term             string  `json:"term"`
+	Definition       string  `json:"definition"`
+	DefinitionSource string  `json:"definitionSource"` // "text" or "llm"
+	Pages            []int   `json:"pages"`
+	Frequency        int     `json:"frequency"`
+	Score            float64 `json:"score"`
+}
+
+type GlossaryResponse struct {
+	DocumentID string         `json:"documentId"`
+	Filename   string         `json:"filename"`
+	Terms      []GlossaryTerm `json:"terms"`
+}
+
+// StudyNotes is a summary of a document: an outline of its sections and the key points
+// a student should take away
+type StudyNotes struct {
+	Outline     []OutlineSection `json:"outline"`
+	KeyPoints   []string         `json:"keyPoints"`
+	Method      string           `json:"method"` // "llm" or "extractive"
+	GeneratedAt time.Time        `json:"generatedAt"`
+}
+
+// OutlineSection is one heading of the outline with its points and subsections
+type OutlineSection struct {
+	Title    string           `json:"title"`
+	Points   []string         `json:"points,omitempty"`
+	Pages    []int            `json:"pages,omitempty"`
+	Children []OutlineSection `json:"children,omitempty"`
+}
+
+type StudyNotesResponse struct {
+	DocumentID string      `json:"documentId"`
+	Filename   string      `json:"filename"`
+	Notes      *StudyNotes `json:"notes"`
+}
+
+// KeywordCorpus holds document frequencies of keyword keys across a user's documents,
+// used as the reference corpus for TF-IDF keyword ranking
+type KeywordCorpus struct {
+	Documents int            `json:"documents"`
+	DocFreq   map[string]int `json:"docFreq"`
+	Stemmed   bool           `json:"stemmed"`
+}
+
+// GenerateFromDocumentRequest asks for a quiz or deck from a document in the library
+type GenerateFromDocumentRequest struct {
+	Title         string             `json:"title" binding:"required"`
+	Description   string             `json:"description"`
+	Difficulty    string             `json:"difficulty,omitempty"`
+	QuestionCount int                `json:"questionCount,omitempty"`
+	CardCount     int                `json:"cardCount,omitempty"`
+	BloomMix      map[string]float64 `json:"bloomMix,omitempty"`
+	// Sections limits the quiz or deck to these sections of the document, by ID or title
+	Sections []string `json:"sections,omitempty"`
+	// Pages limits the quiz or deck to page ranges such as "40-55" or "3,7-9"
+	Pages string `json:"pages,omitempty"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+type FlashcardDeck struct {
+	ID          string      `json:"id"`
+	UserID      string      `json:"user_id,omitempty"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	SourceName  string      `json:"sourceName,omitempty"`
+	Cards       []Flashcard `json:"cards"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+	TotalCards  int         `json:"totalCards"`
+}
+
+type Flashcard struct {
+	ID        string `json:"id"`
+	Front     string `json:"front"`
+	Back      string `json:"back"`
+	SourceRef string `json:"sourceRef,omitempty"`
+}
+
+type GenerateDeckRequest struct {
+	Content     string `json:"content" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	CardCount   int    `json:"cardCount,omitempty"`
+}
+
+type DeckGenerationRequest struct {
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+	CardCount   int    `json:"cardCount,omitempty"`
+}
+
+type UpdateDeckRequest struct {
+	Title       string      `json:"title" binding:"required"`
+	Description string      `json:"description"`
+	Cards       []Flashcard `json:"cards"`
+}
+
+type DeckToQuizRequest struct {
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	Difficulty    string `json:"difficulty"`
+	QuestionCount int    `json:"questionCount,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// LintIssue is one problem the question linter found
+type LintIssue struct {
+	QuestionID    string `json:"questionId"`
+	QuestionIndex int    `json:"questionIndex"`
+	Rule          string `json:"rule"`
+	Severity      string `json:"severity"` // "error", "warning" or "info"
+	Message       string `json:"message"`
+}
+
+// LintReport is the result of linting every question of a quiz
+type LintReport struct {
+	QuizID        string      `json:"quizId"`
+	Questions     int         `json:"questions"`
+	Errors        int         `json:"errors"`
+	Warnings      int         `json:"warnings"`
+	SourceChecked bool        `json:"sourceChecked"`
+	Issues        []LintIssue `json:"issues"`
+}
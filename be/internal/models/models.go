@@ -12,26 +12,53 @@ type Quiz struct {
 	CreatedAt      time.Time  `json:"createdAt"`
 	UpdatedAt      time.Time  `json:"updatedAt"`
 	TotalQuestions int        `json:"totalQuestions"`
+	Language       string     `json:"language,omitempty"`
+	SourceQuizID   string     `json:"sourceQuizId,omitempty"`
+	DocumentID     string     `json:"documentId,omitempty"`
+	SourceFilename string     `json:"sourceFilename,omitempty"`
+
+	// SourcePages and SourceSections are the part of the document the quiz was generated
+	// from, e.g. "40-55" and ["3"], when it was not generated from all of it
+	SourcePages    string   `json:"sourcePages,omitempty"`
+	SourceSections []string `json:"sourceSections,omitempty"`
+
+	// BloomMix is the requested share of questions per cognitive level, if any
+	BloomMix map[string]float64 `json:"bloomMix,omitempty"`
+
+	// Notes are the study notes of the source document, served with the quiz
+	Notes *StudyNotes `json:"notes,omitempty"`
 }
 
 type Question struct {
-	ID            string                 `json:"id"`
-	Type          string                 `json:"type"`
-	Text          string                 `json:"text"`
-	Question      string                 `json:"question"`
-	Options       []string               `json:"options"`
-	Correct       string                 `json:"correct"`
-	CorrectAnswer int                    `json:"correctAnswer"`
-	Points        int                    `json:"points"`
-	Explanation   string                 `json:"explanation,omitempty"`
-	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	ID               string                 `json:"id"`
+	Type             string                 `json:"type"`
+	Text             string                 `json:"text"`
+	Question         string                 `json:"question"`
+	Options          []string               `json:"options"`
+	Correct          string                 `json:"correct"`
+	CorrectAnswer    int                    `json:"correctAnswer"`
+	Points           int                    `json:"points"`
+	Explanation      string                 `json:"explanation,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	SourceQuestionID string                 `json:"sourceQuestionId,omitempty"`
+
+	// BloomLevel is the cognitive level the question targets (remember, understand,
+	// apply, analyze, evaluate or create)
+	BloomLevel         string   `json:"bloomLevel,omitempty"`
+	LearningObjectives []string `json:"learningObjectives,omitempty"`
+
+	// ImageKey is the blob key of the image attached to the question. Responses carry a
+	// download link to it in Image.
+	ImageKey string    `json:"-"`
+	Image    *FileLink `json:"image,omitempty"`
 }
 
 type CreateQuizRequest struct {
-	Title         string `json:"title" binding:"required"`
-	Description   string `json:"description" binding:"required"`
-	Difficulty    string `json:"difficulty" binding:"required"`
-	QuestionCount int    `json:"questionCount,omitempty"`
+	Title         string             `json:"title" binding:"required"`
+	Description   string             `json:"description" binding:"required"`
+	Difficulty    string             `json:"difficulty" binding:"required"`
+	QuestionCount int                `json:"questionCount,omitempty"`
+	BloomMix      map[string]float64 `json:"bloomMix,omitempty"`
 }
 
 type FileUploadResponse struct {
@@ -46,13 +73,51 @@ type GenerateQuizRequest struct {
 	Description   string `json:"description" binding:"required"`
 	Difficulty    string `json:"difficulty" binding:"required"`
 	QuestionCount int    `json:"questionCount,omitempty"`
+
+	// BloomMix maps cognitive levels to the share of questions wanted, e.g. {"apply": 40}
+	BloomMix map[string]float64 `json:"bloomMix,omitempty"`
 }
 
 type QuizGenerationRequest struct {
-	Title         string `json:"title" binding:"required"`
-	Description   string `json:"description" binding:"required"`
-	Difficulty    string `json:"difficulty" binding:"required"`
-	QuestionCount int    `json:"questionCount,omitempty"`
+	Title         string             `json:"title" binding:"required"`
+	Description   string             `json:"description" binding:"required"`
+	Difficulty    string             `json:"difficulty" binding:"required"`
+	QuestionCount int                `json:"questionCount,omitempty"`
+	BloomMix      map[string]float64 `json:"bloomMix,omitempty"`
+
+	// Corpus is the user's reference corpus for keyword ranking, nil when unavailable
+	Corpus *KeywordCorpus `json:"-"`
+}
+
+type TranslateQuizRequest struct {
+	TargetLanguage string `json:"targetLanguage" binding:"required"`
+}
+
+// QuizTranslationStats summarises attempts on one language version of a quiz
+type QuizTranslationStats struct {
+	QuizID       string  `json:"quizId"`
+	Title        string  `json:"title"`
+	Language     string  `json:"language,omitempty"`
+	IsOriginal   bool    `json:"isOriginal"`
+	Attempts     int     `json:"attempts"`
+	AverageScore float64 `json:"averageScore"`
+}
+
+// BloomCoverage reports how the questions of a quiz cover the cognitive levels
+type BloomCoverage struct {
+	QuizID         string               `json:"quizId"`
+	TotalQuestions int                  `json:"totalQuestions"`
+	Levels         []BloomLevelCoverage `json:"levels"`
+}
+
+// BloomLevelCoverage holds the question share and answer accuracy of one cognitive level
+type BloomLevelCoverage struct {
+	Level       string  `json:"level"`
+	Questions   int     `json:"questions"`
+	Share       float64 `json:"share"`
+	TargetShare float64 `json:"targetShare,omitempty"`
+	Answers     int     `json:"answers"`
+	CorrectRate float64 `json:"correctRate"`
 }
 
 type APIResponse struct {
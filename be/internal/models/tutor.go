@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// TutorMessage is one turn of a student's conversation about a document
+type TutorMessage struct {
+	ID         string          `json:"id"`
+	DocumentID string          `json:"documentId"`
+	Role       string          `json:"role"` // "user" or "assistant"
+	Content    string          `json:"content"`
+	Citations  []TutorCitation `json:"citations,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// TutorCitation is a passage of the document an answer is based on
+type TutorCitation struct {
+	Page    int     `json:"page"`
+	Label   string  `json:"label,omitempty"` // e.g. "Slide 4", for documents without pages
+	Excerpt string  `json:"excerpt"`
+	Score   float64 `json:"score"`
+}
+
+// Ref is how the citation is shown to a reader: its label, or else its page
+func (c TutorCitation) Ref() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return fmt.Sprintf("p. %d", c.Page)
+}
+
+type TutorAskRequest struct {
+	Question  string `json:"question" binding:"required"`
+	Retrieval string `json:"retrieval,omitempty"` // "bm25" (default) or "embeddings"
+}
+
+type TutorAnswer struct {
+	DocumentID string          `json:"documentId"`
+	Question   string          `json:"question"`
+	Answer     string          `json:"answer"`
+	Citations  []TutorCitation `json:"citations"`
+	Retrieval  string          `json:"retrieval"`
+	Method     string          `json:"method"` // "llm" or "extractive"
+}
+
+type TutorHistoryResponse struct {
+	DocumentID string         `json:"documentId"`
+	Filename   string         `json:"filename"`
+	Messages   []TutorMessage `json:"messages"`
+}
+
+// AttemptAnswers is a stored quiz attempt with the option text chosen per question ID
+type AttemptAnswers struct {
+	ID             string
+	QuizID         string
+	UserID         string
+	Score          int
+	TotalQuestions int
+	Answers        map[string]string
+}
+
+// MistakeExplanation explains why the option a student chose is wrong and why the key is
+// right
+type MistakeExplanation struct {
+	QuestionID    string         `json:"questionId"`
+	QuestionText  string         `json:"questionText"`
+	UserAnswer    string         `json:"userAnswer"`
+	CorrectAnswer string         `json:"correctAnswer"`
+	Explanation   string         `json:"explanation"`
+	Source        *TutorCitation `json:"source,omitempty"`
+	Method        string         `json:"method"` // "llm", "stored", "source" or "generic"
+	Cached        bool           `json:"cached"`
+}
+
+type AttemptFeedback struct {
+	AttemptID      string               `json:"attemptId"`
+	QuizID         string               `json:"quizId"`
+	Score          int                  `json:"score"`
+	TotalQuestions int                  `json:"totalQuestions"`
+	Mistakes       []MistakeExplanation `json:"mistakes"`
+}
@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"pbkk-quizlit-backend/internal/database"
+	"pbkk-quizlit-backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type DocumentRepository struct{}
+
+func NewDocumentRepository() *DocumentRepository {
+	return &DocumentRepository{}
+}
+
+// CreateDocument stores an uploaded document, its extracted text and file metadata
+func (r *DocumentRepository) CreateDocument(ctx context.Context, doc *models.Document, userID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	pagesJSON, err := json.Marshal(doc.Pages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pages: %w", err)
+	}
+	metadataJSON, err := nullableJSON(doc.Metadata, len(doc.Metadata) == 0)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	var documentID int64
+	err = db.QueryRow(ctx,
+		`INSERT INTO documents (user_id, filename, content, pages, storage_key, content_type, size_bytes, sha256, page_count, metadata, created_at)
+		 VALUES ($1, $2, $3, $4::jsonb, $5, $6, $7, $8, $9, $10::jsonb, $11)
+		 RETURNING id`,
+		userID, doc.Filename, doc.Content, string(pagesJSON), nullableString(doc.StorageKey), nullableString(doc.ContentType),
+		doc.Size, nullableString(doc.SHA256), len(doc.Pages), metadataJSON, doc.CreatedAt,
+	).Scan(&documentID)
+	if err != nil {
+		return fmt.Errorf("failed to insert document: %w", err)
+	}
+
+	doc.ID = fmt.Sprintf("%d", documentID)
+	doc.UserID = userID
+	doc.PageCount = len(doc.Pages)
+	return nil
+}
+
+// documentColumns are the columns read by scanDocument, without the text and pages
+const documentColumns = `id, user_id, filename, storage_key, content_type, size_bytes, sha256, page_count, metadata, created_at`
+
+// scanDocument reads the documentColumns of a row, followed by any extra destinations
+func scanDocument(row pgx.Row, doc *models.Document, extra ...interface{}) error {
+	var storageKey, contentType, sha256 *string
+	var metadataJSON []byte
+
+	dest := append([]interface{}{
+		&doc.ID, &doc.UserID, &doc.Filename, &storageKey, &contentType, &doc.Size, &sha256, &doc.PageCount, &metadataJSON, &doc.CreatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+
+	if storageKey != nil {
+		doc.StorageKey = *storageKey
+	}
+	if contentType != nil {
+		doc.ContentType = *contentType
+	}
+	if sha256 != nil {
+		doc.SHA256 = *sha256
+	}
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &doc.Metadata); err != nil {
+			return fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetDocument retrieves a document with its pages, stored glossary and study notes
+func (r *DocumentRepository) GetDocument(ctx context.Context, id string) (*models.Document, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	var doc models.Document
+	var pagesJSON, glossaryJSON, notesJSON []byte
+
+	err := scanDocument(db.QueryRow(ctx,
+		`SELECT `+documentColumns+`, content, pages, glossary, notes
+		 FROM documents
+		 WHERE id = $1`,
+		id,
+	), &doc, &doc.Content, &pagesJSON, &glossaryJSON, &notesJSON)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("document not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	if err := json.Unmarshal(pagesJSON, &doc.Pages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pages: %w", err)
+	}
+	if len(glossaryJSON) > 0 {
+		if err := json.Unmarshal(glossaryJSON, &doc.Glossary); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal glossary: %w", err)
+		}
+	}
+	if len(notesJSON) > 0 {
+		if err := json.Unmarshal(notesJSON, &doc.Notes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal notes: %w", err)
+		}
+	}
+
+	return &doc, nil
+}
+
+// FindDocumentByHash returns the id of the user's document with the given file hash, or
+// an empty string when the user has not uploaded that file
+func (r *DocumentRepository) FindDocumentByHash(ctx context.Context, userID, sha256 string) (string, error) {
+	db := database.GetDB()
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+
+	var documentID int64
+	err := db.QueryRow(ctx,
+		`SELECT id FROM documents WHERE user_id = $1 AND sha256 = $2`,
+		userID, sha256,
+	).Scan(&documentID)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find document: %w", err)
+	}
+
+	return fmt.Sprintf("%d", documentID), nil
+}
+
+// ListDocuments returns a user's documents, newest first, without their text
+func (r *DocumentRepository) ListDocuments(ctx context.Context, userID string) ([]*models.Document, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT `+documentColumns+`
+		 FROM documents
+		 WHERE user_id = $1
+		 ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := []*models.Document{}
+	for rows.Next() {
+		doc := &models.Document{}
+		if err := scanDocument(rows, doc); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		documents = append(documents, doc)
+	}
+
+	return documents, rows.Err()
+}
+
+// DeleteDocument deletes a document. Quizzes generated from it are kept and unlinked.
+func (r *DocumentRepository) DeleteDocument(ctx context.Context, id string) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	result, err := db.Exec(ctx, `DELETE FROM documents WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("document not found")
+	}
+
+	return nil
+}
+
+// UpdateDocumentText replaces the text extracted from a document, as when its file is
+// extracted again with other options. The glossary and study notes of the old text are
+// dropped, so they are generated again on first use.
+func (r *DocumentRepository) UpdateDocumentText(ctx context.Context, doc *models.Document) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	pagesJSON, err := json.Marshal(doc.Pages)
+	if err != nil {
+		return fmt.Errorf("failed to marshal pages: %w", err)
+	}
+	metadataJSON, err := nullableJSON(doc.Metadata, len(doc.Metadata) == 0)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	result, err := db.Exec(ctx,
+		`UPDATE documents
+		 SET content = $1, pages = $2::jsonb, page_count = $3, metadata = $4::jsonb, glossary = NULL, notes = NULL
+		 WHERE id = $5`,
+		doc.Content, string(pagesJSON), len(doc.Pages), metadataJSON, doc.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("document not found")
+	}
+
+	doc.PageCount = len(doc.Pages)
+	doc.Glossary, doc.Notes = nil, nil
+	return nil
+}
+
+// SaveGlossary stores the glossary extracted from a document
+func (r *DocumentRepository) SaveGlossary(ctx context.Context, id string, glossary []models.GlossaryTerm) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	glossaryJSON, err := json.Marshal(glossary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal glossary: %w", err)
+	}
+
+	result, err := db.Exec(ctx,
+		`UPDATE documents SET glossary = $1::jsonb WHERE id = $2`,
+		string(glossaryJSON), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save glossary: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("document not found")
+	}
+
+	return nil
+}
+
+// SaveNotes stores the study notes generated from a document
+func (r *DocumentRepository) SaveNotes(ctx context.Context, id string, notes *models.StudyNotes) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	notesJSON, err := json.Marshal(notes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notes: %w", err)
+	}
+
+	result, err := db.Exec(ctx,
+		`UPDATE documents SET notes = $1::jsonb WHERE id = $2`,
+		string(notesJSON), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save notes: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("document not found")
+	}
+
+	return nil
+}
+
+// ListDocumentContents returns the text of a user's most recent documents
+func (r *DocumentRepository) ListDocumentContents(ctx context.Context, userID string, limit int) ([]string, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT content
+		 FROM documents
+		 WHERE user_id = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var contents []string
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		contents = append(contents, content)
+	}
+
+	return contents, rows.Err()
+}
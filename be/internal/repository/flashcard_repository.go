@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"pbkk-quizlit-backend/internal/database"
+	"pbkk-quizlit-backend/internal/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type FlashcardRepository struct{}
+
+func NewFlashcardRepository() *FlashcardRepository {
+	return &FlashcardRepository{}
+}
+
+// CreateDeck creates a new deck with its cards in the database
+func (r *FlashcardRepository) CreateDeck(ctx context.Context, deck *models.FlashcardDeck, userID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deckID int64
+	err = tx.QueryRow(ctx,
+		`INSERT INTO flashcard_decks (user_id, title, description, source_name, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $5)
+		 RETURNING id`,
+		userID, deck.Title, deck.Description, nullableString(deck.SourceName), time.Now(),
+	).Scan(&deckID)
+	if err != nil {
+		return fmt.Errorf("failed to insert deck: %w", err)
+	}
+
+	deck.ID = fmt.Sprintf("%d", deckID)
+	deck.UserID = userID
+
+	if err := insertFlashcards(ctx, tx, deckID, deck.Cards); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// insertFlashcards writes cards in order and assigns their database IDs
+func insertFlashcards(ctx context.Context, tx pgx.Tx, deckID int64, cards []models.Flashcard) error {
+	for i := range cards {
+		card := &cards[i]
+
+		var cardID int64
+		err := tx.QueryRow(ctx,
+			`INSERT INTO flashcards (deck_id, position, front, back, source_ref)
+			 VALUES ($1, $2, $3, $4, $5)
+			 RETURNING id`,
+			deckID, i, card.Front, card.Back, nullableString(card.SourceRef),
+		).Scan(&cardID)
+		if err != nil {
+			return fmt.Errorf("failed to insert flashcard: %w", err)
+		}
+
+		card.ID = fmt.Sprintf("%d", cardID)
+	}
+
+	return nil
+}
+
+// GetDeck retrieves a deck with its cards by ID
+func (r *FlashcardRepository) GetDeck(ctx context.Context, id string) (*models.FlashcardDeck, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	var deck models.FlashcardDeck
+	var sourceName *string
+
+	err := db.QueryRow(ctx,
+		`SELECT id, user_id, title, description, source_name, created_at, updated_at
+		 FROM flashcard_decks
+		 WHERE id = $1`,
+		id,
+	).Scan(&deck.ID, &deck.UserID, &deck.Title, &deck.Description, &sourceName, &deck.CreatedAt, &deck.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("deck not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deck: %w", err)
+	}
+
+	if sourceName != nil {
+		deck.SourceName = *sourceName
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT id, front, back, source_ref
+		 FROM flashcards
+		 WHERE deck_id = $1
+		 ORDER BY position, id`,
+		id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flashcards: %w", err)
+	}
+	defer rows.Close()
+
+	deck.Cards = []models.Flashcard{}
+	for rows.Next() {
+		var card models.Flashcard
+		var sourceRef *string
+
+		if err := rows.Scan(&card.ID, &card.Front, &card.Back, &sourceRef); err != nil {
+			return nil, fmt.Errorf("failed to scan flashcard: %w", err)
+		}
+
+		if sourceRef != nil {
+			card.SourceRef = *sourceRef
+		}
+		deck.Cards = append(deck.Cards, card)
+	}
+
+	deck.TotalCards = len(deck.Cards)
+	return &deck, nil
+}
+
+// GetAllDecks retrieves all decks for a specific user without their cards
+func (r *FlashcardRepository) GetAllDecks(ctx context.Context, userID string) ([]*models.FlashcardDeck, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT d.id, d.title, d.description, d.source_name, d.created_at, d.updated_at, COUNT(f.id) AS card_count
+		 FROM flashcard_decks d
+		 LEFT JOIN flashcards f ON f.deck_id = d.id
+		 WHERE d.user_id = $1
+		 GROUP BY d.id, d.title, d.description, d.source_name, d.created_at, d.updated_at
+		 ORDER BY d.created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query decks: %w", err)
+	}
+	defer rows.Close()
+
+	decks := []*models.FlashcardDeck{}
+	for rows.Next() {
+		deck := &models.FlashcardDeck{UserID: userID}
+		var sourceName *string
+
+		if err := rows.Scan(&deck.ID, &deck.Title, &deck.Description, &sourceName, &deck.CreatedAt, &deck.UpdatedAt, &deck.TotalCards); err != nil {
+			return nil, fmt.Errorf("failed to scan deck: %w", err)
+		}
+
+		if sourceName != nil {
+			deck.SourceName = *sourceName
+		}
+		decks = append(decks, deck)
+	}
+
+	return decks, nil
+}
+
+// UpdateDeck replaces a deck's details and cards
+func (r *FlashcardRepository) UpdateDeck(ctx context.Context, deck *models.FlashcardDeck) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deckID int64
+	err = tx.QueryRow(ctx,
+		`UPDATE flashcard_decks
+		 SET title = $1, description = $2, updated_at = $3
+		 WHERE id = $4
+		 RETURNING id`,
+		deck.Title, deck.Description, time.Now(), deck.ID,
+	).Scan(&deckID)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("deck not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update deck: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM flashcards WHERE deck_id = $1`, deckID); err != nil {
+		return fmt.Errorf("failed to delete flashcards: %w", err)
+	}
+
+	if err := insertFlashcards(ctx, tx, deckID, deck.Cards); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteDeck deletes a deck and its cards
+func (r *FlashcardRepository) DeleteDeck(ctx context.Context, id string) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	result, err := db.Exec(ctx, `DELETE FROM flashcard_decks WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete deck: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("deck not found")
+	}
+
+	return nil
+}
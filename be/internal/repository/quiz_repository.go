@@ -75,13 +75,23 @@ func (r *QuizRepository) CreateQuiz(ctx context.Context, quiz *models.Quiz, user
 	}
 	defer tx.Rollback(ctx)
 
+	bloomMixJSON, err := nullableJSON(quiz.BloomMix, len(quiz.BloomMix) == 0)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bloom mix: %w", err)
+	}
+	sourceSectionsJSON, err := nullableJSON(quiz.SourceSections, len(quiz.SourceSections) == 0)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source sections: %w", err)
+	}
+
 	// Insert quiz with difficulty
 	var quizID int64
 	err = tx.QueryRow(ctx,
-		`INSERT INTO quizzes (user_id, title, description, pdf_filename, difficulty, created_at) 
-		 VALUES ($1, $2, $3, $4, $5, $6) 
+		`INSERT INTO quizzes (user_id, title, description, pdf_filename, difficulty, language, source_quiz_id, bloom_mix, document_id, source_pages, source_sections, created_at) 
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb, $9, $10, $11::jsonb, $12) 
 		 RETURNING id`,
-		userID, quiz.Title, quiz.Description, quiz.Title, quiz.Difficulty, time.Now(),
+		userID, quiz.Title, quiz.Description, quiz.SourceFilename, quiz.Difficulty, nullableString(quiz.Language), nullableID(quiz.SourceQuizID), bloomMixJSON, nullableID(quiz.DocumentID),
+		nullableString(quiz.SourcePages), sourceSectionsJSON, time.Now(),
 	).Scan(&quizID)
 	if err != nil {
 		return fmt.Errorf("failed to insert quiz: %w", err)
@@ -109,12 +119,18 @@ func (r *QuizRepository) CreateQuiz(ctx context.Context, quiz *models.Quiz, user
 			correctAnswer = question.Options[question.CorrectAnswer]
 		}
 
+		objectivesJSON, err := nullableJSON(question.LearningObjectives, len(question.LearningObjectives) == 0)
+		if err != nil {
+			return fmt.Errorf("failed to marshal learning objectives: %w", err)
+		}
+
 		var questionID int64
 		err = tx.QueryRow(ctx,
-			`INSERT INTO questions (quiz_id, question_text, options, correct_answer) 
-			 VALUES ($1, $2, $3::jsonb, $4) 
+			`INSERT INTO questions (quiz_id, question_text, options, correct_answer, explanation, source_question_id, bloom_level, learning_objectives) 
+			 VALUES ($1, $2, $3::jsonb, $4, $5, $6, $7, $8::jsonb) 
 			 RETURNING id`,
-			quizID, cleanedText, string(optionsJSON), correctAnswer,
+			quizID, cleanedText, string(optionsJSON), correctAnswer, nullableString(question.Explanation), nullableID(question.SourceQuestionID),
+			nullableString(question.BloomLevel), objectivesJSON,
 		).Scan(&questionID)
 		if err != nil {
 			return fmt.Errorf("failed to insert question: %w", err)
@@ -141,12 +157,17 @@ func (r *QuizRepository) GetQuiz(ctx context.Context, id string) (*models.Quiz,
 	// Get quiz
 	var quiz models.Quiz
 	var title, description, pdfFilename, userID string
+	var language *string
+	var sourceQuizID *int64
+	var bloomMixJSON, sourceSectionsJSON []byte
+	var documentID *int64
+	var sourcePages *string
 	var createdAt time.Time
 
 	err := db.QueryRow(ctx,
-		`SELECT id, user_id, title, description, pdf_filename, created_at FROM quizzes WHERE id = $1`,
+		`SELECT id, user_id, title, description, pdf_filename, language, source_quiz_id, bloom_mix, document_id, source_pages, source_sections, created_at FROM quizzes WHERE id = $1`,
 		id,
-	).Scan(&quiz.ID, &userID, &title, &description, &pdfFilename, &createdAt)
+	).Scan(&quiz.ID, &userID, &title, &description, &pdfFilename, &language, &sourceQuizID, &bloomMixJSON, &documentID, &sourcePages, &sourceSectionsJSON, &createdAt)
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("quiz not found")
 	}
@@ -160,10 +181,33 @@ func (r *QuizRepository) GetQuiz(ctx context.Context, id string) (*models.Quiz,
 	quiz.Description = description
 	quiz.CreatedAt = createdAt
 	quiz.UpdatedAt = createdAt
+	if language != nil {
+		quiz.Language = *language
+	}
+	if sourceQuizID != nil {
+		quiz.SourceQuizID = fmt.Sprintf("%d", *sourceQuizID)
+	}
+	if documentID != nil {
+		quiz.DocumentID = fmt.Sprintf("%d", *documentID)
+	}
+	quiz.SourceFilename = pdfFilename
+	if len(bloomMixJSON) > 0 {
+		if err := json.Unmarshal(bloomMixJSON, &quiz.BloomMix); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bloom mix: %w", err)
+		}
+	}
+	if sourcePages != nil {
+		quiz.SourcePages = *sourcePages
+	}
+	if len(sourceSectionsJSON) > 0 {
+		if err := json.Unmarshal(sourceSectionsJSON, &quiz.SourceSections); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal source sections: %w", err)
+		}
+	}
 
 	// Get questions
 	rows, err := db.Query(ctx,
-		`SELECT id, question_text, options, correct_answer 
+		`SELECT id, question_text, options, correct_answer, explanation, source_question_id, bloom_level, learning_objectives, image_key 
 		 FROM questions 
 		 WHERE quiz_id = $1 
 		 ORDER BY id`,
@@ -179,11 +223,35 @@ func (r *QuizRepository) GetQuiz(ctx context.Context, id string) (*models.Quiz,
 		var q models.Question
 		var optionsJSON []byte
 		var correctAnswer string
+		var explanation *string
+		var sourceQuestionID *int64
+		var bloomLevel *string
+		var objectivesJSON []byte
+		var imageKey *string
 
-		if err := rows.Scan(&q.ID, &q.Text, &optionsJSON, &correctAnswer); err != nil {
+		if err := rows.Scan(&q.ID, &q.Text, &optionsJSON, &correctAnswer, &explanation, &sourceQuestionID, &bloomLevel, &objectivesJSON, &imageKey); err != nil {
 			return nil, fmt.Errorf("failed to scan question: %w", err)
 		}
 
+		if bloomLevel != nil {
+			q.BloomLevel = *bloomLevel
+		}
+		if len(objectivesJSON) > 0 {
+			if err := json.Unmarshal(objectivesJSON, &q.LearningObjectives); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal learning objectives: %w", err)
+			}
+		}
+
+		if explanation != nil {
+			q.Explanation = *explanation
+		}
+		if sourceQuestionID != nil {
+			q.SourceQuestionID = fmt.Sprintf("%d", *sourceQuestionID)
+		}
+		if imageKey != nil {
+			q.ImageKey = *imageKey
+		}
+
 		// Unmarshal options
 		if err := json.Unmarshal(optionsJSON, &q.Options); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal options: %w", err)
@@ -211,6 +279,38 @@ func (r *QuizRepository) GetQuiz(ctx context.Context, id string) (*models.Quiz,
 	return &quiz, nil
 }
 
+// nullableString maps an empty string to SQL NULL
+func nullableString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// nullableJSON marshals a value for a JSONB column, mapping empty values to SQL NULL
+func nullableJSON(value interface{}, empty bool) (*string, error) {
+	if empty {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return nullableString(string(data)), nil
+}
+
+// nullableID maps an empty or non-numeric ID to SQL NULL
+func nullableID(id string) *int64 {
+	if id == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
 // GetAllQuizzes retrieves all quizzes for a specific user
 func (r *QuizRepository) GetAllQuizzes(ctx context.Context, userID string) ([]*models.Quiz, error) {
 	db := database.GetDB()
@@ -219,11 +319,11 @@ func (r *QuizRepository) GetAllQuizzes(ctx context.Context, userID string) ([]*m
 	}
 
 	rows, err := db.Query(ctx,
-		`SELECT q.id, q.title, q.description, q.pdf_filename, q.difficulty, q.created_at, COUNT(qu.id) as question_count
+		`SELECT q.id, q.title, q.description, q.pdf_filename, q.difficulty, q.language, q.source_quiz_id, q.created_at, COUNT(qu.id) as question_count
          FROM quizzes q
          LEFT JOIN questions qu ON qu.quiz_id = q.id
          WHERE q.user_id = $1
-         GROUP BY q.id, q.title, q.description, q.pdf_filename, q.difficulty, q.created_at
+         GROUP BY q.id, q.title, q.description, q.pdf_filename, q.difficulty, q.language, q.source_quiz_id, q.created_at
          ORDER BY q.created_at DESC`,
 		userID,
 	)
@@ -236,15 +336,25 @@ func (r *QuizRepository) GetAllQuizzes(ctx context.Context, userID string) ([]*m
 	for rows.Next() {
 		quiz := &models.Quiz{}
 		var title, description, pdfFilename, difficulty string
+		var language *string
+		var sourceQuizID *int64
 		var createdAt time.Time
 		var questionCount int
 
-		if err := rows.Scan(&quiz.ID, &title, &description, &pdfFilename, &difficulty, &createdAt, &questionCount); err != nil {
+		if err := rows.Scan(&quiz.ID, &title, &description, &pdfFilename, &difficulty, &language, &sourceQuizID, &createdAt, &questionCount); err != nil {
 			return nil, fmt.Errorf("failed to scan quiz: %w", err)
 		}
 
+		if language != nil {
+			quiz.Language = *language
+		}
+		if sourceQuizID != nil {
+			quiz.SourceQuizID = fmt.Sprintf("%d", *sourceQuizID)
+		}
+
 		quiz.Title = title
 		quiz.Description = description
+		quiz.SourceFilename = pdfFilename
 		quiz.Difficulty = difficulty
 		quiz.CreatedAt = createdAt
 		quiz.UpdatedAt = createdAt
@@ -294,6 +404,35 @@ func (r *QuizRepository) DeleteQuiz(ctx context.Context, id string) error {
 	return nil
 }
 
+// SetQuestionImage sets the blob key of a question's image, or removes the image when
+// key is empty. It returns the key of the image it replaced.
+func (r *QuizRepository) SetQuestionImage(ctx context.Context, quizID, questionID, key string) (string, error) {
+	db := database.GetDB()
+	if db == nil {
+		return "", fmt.Errorf("database connection not initialized")
+	}
+
+	var previous *string
+	err := db.QueryRow(ctx,
+		`UPDATE questions q SET image_key = $3
+		 FROM (SELECT id, image_key FROM questions WHERE id = $2 AND quiz_id = $1 FOR UPDATE) old
+		 WHERE q.id = old.id
+		 RETURNING old.image_key`,
+		quizID, questionID, nullableString(key),
+	).Scan(&previous)
+	if err == pgx.ErrNoRows {
+		return "", fmt.Errorf("question not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to set question image: %w", err)
+	}
+
+	if previous == nil {
+		return "", nil
+	}
+	return *previous, nil
+}
+
 // SaveQuizAttempt saves a quiz attempt to the database
 func (r *QuizRepository) SaveQuizAttempt(ctx context.Context, quizID string, userID string, score int, totalQuestions int, answers map[string]string) (string, error) {
 	db := database.GetDB()
@@ -494,3 +633,204 @@ func (r *QuizRepository) ListUserAttempts(ctx context.Context, userID string) ([
 
 	return attempts, nil
 }
+
+// GetTranslationStats returns attempt statistics for a quiz and every language version linked to it
+func (r *QuizRepository) GetTranslationStats(ctx context.Context, quizID string) ([]models.QuizTranslationStats, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	quizIDInt, err := strconv.ParseInt(quizID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quiz ID format: %w", err)
+	}
+
+	// Resolve the original quiz so that translations of translations share one group
+	var rootID int64
+	err = db.QueryRow(ctx,
+		`SELECT COALESCE(source_quiz_id, id) FROM quizzes WHERE id = $1`,
+		quizIDInt,
+	).Scan(&rootID)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("quiz not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiz: %w", err)
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT q.id, q.title, q.language, q.source_quiz_id IS NULL AS is_original,
+		        COUNT(qa.id) AS attempts,
+		        COALESCE(AVG(qa.score::float / NULLIF(qa.total_questions, 0) * 100), 0) AS average_score
+		 FROM quizzes q
+		 LEFT JOIN quiz_attempts qa ON qa.quiz_id = q.id
+		 WHERE q.id = $1 OR q.source_quiz_id = $1
+		 GROUP BY q.id, q.title, q.language, q.source_quiz_id
+		 ORDER BY q.id`,
+		rootID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query translation stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.QuizTranslationStats{}
+	for rows.Next() {
+		var id int64
+		var language *string
+		var stat models.QuizTranslationStats
+
+		if err := rows.Scan(&id, &stat.Title, &language, &stat.IsOriginal, &stat.Attempts, &stat.AverageScore); err != nil {
+			return nil, fmt.Errorf("failed to scan translation stats: %w", err)
+		}
+
+		stat.QuizID = fmt.Sprintf("%d", id)
+		if language != nil {
+			stat.Language = *language
+		}
+		stats = append(stats, stat)
+	}
+
+	return stats, nil
+}
+
+// GetAttemptAnswers returns the answers of every attempt on a quiz, keyed by question ID
+func (r *QuizRepository) GetAttemptAnswers(ctx context.Context, quizID string) ([]map[string]string, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT user_answers 
+		 FROM quiz_attempts 
+		 WHERE quiz_id = $1 AND user_answers IS NOT NULL`,
+		quizID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []map[string]string
+	for rows.Next() {
+		var answersJSON []byte
+		if err := rows.Scan(&answersJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan attempt: %w", err)
+		}
+
+		var answers map[string]string
+		if err := json.Unmarshal(answersJSON, &answers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal answers: %w", err)
+		}
+		attempts = append(attempts, answers)
+	}
+
+	return attempts, rows.Err()
+}
+
+// GetAttemptRecord returns the owner, quiz and chosen answers of an attempt
+func (r *QuizRepository) GetAttemptRecord(ctx context.Context, attemptID string) (*models.AttemptAnswers, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	attemptIDInt, err := strconv.ParseInt(attemptID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attempt ID format: %w", err)
+	}
+
+	attempt := &models.AttemptAnswers{ID: attemptID}
+	var quizID int64
+	var answersJSON []byte
+
+	err = db.QueryRow(ctx,
+		`SELECT quiz_id, user_id, score, total_questions, user_answers
+		 FROM quiz_attempts
+		 WHERE id = $1`,
+		attemptIDInt,
+	).Scan(&quizID, &attempt.UserID, &attempt.Score, &attempt.TotalQuestions, &answersJSON)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("attempt not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiz attempt: %w", err)
+	}
+
+	attempt.QuizID = fmt.Sprintf("%d", quizID)
+	attempt.Answers = make(map[string]string)
+	if len(answersJSON) > 0 {
+		if err := json.Unmarshal(answersJSON, &attempt.Answers); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal user answers: %w", err)
+		}
+	}
+
+	return attempt, nil
+}
+
+// GetAnswerExplanation returns the cached explanation of choosing an option, or nil when
+// there is none
+func (r *QuizRepository) GetAnswerExplanation(ctx context.Context, questionID, option string) (*models.MistakeExplanation, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	var explanation models.MistakeExplanation
+	var sourcePage *int
+	var sourceLabel, sourceExcerpt *string
+
+	err := db.QueryRow(ctx,
+		`SELECT explanation, source_page, source_label, source_excerpt
+		 FROM answer_explanations
+		 WHERE question_id = $1 AND option_text = $2`,
+		questionID, option,
+	).Scan(&explanation.Explanation, &sourcePage, &sourceLabel, &sourceExcerpt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get answer explanation: %w", err)
+	}
+
+	if sourcePage != nil && sourceExcerpt != nil {
+		explanation.Source = &models.TutorCitation{Page: *sourcePage, Excerpt: *sourceExcerpt}
+		if sourceLabel != nil {
+			explanation.Source.Label = *sourceLabel
+		}
+	}
+	return &explanation, nil
+}
+
+// SaveAnswerExplanation caches the explanation of choosing an option
+func (r *QuizRepository) SaveAnswerExplanation(ctx context.Context, questionID, option string, explanation *models.MistakeExplanation) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	var sourcePage *int
+	var sourceLabel, sourceExcerpt *string
+	if explanation.Source != nil {
+		sourcePage = &explanation.Source.Page
+		sourceLabel = nullableString(explanation.Source.Label)
+		sourceExcerpt = &explanation.Source.Excerpt
+	}
+
+	_, err := db.Exec(ctx,
+		`INSERT INTO answer_explanations (question_id, option_text, explanation, source_page, source_label, source_excerpt, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (question_id, option_text)
+		 DO UPDATE SET explanation = EXCLUDED.explanation, source_page = EXCLUDED.source_page,
+		               source_label = EXCLUDED.source_label, source_excerpt = EXCLUDED.source_excerpt,
+		               created_at = EXCLUDED.created_at`,
+		questionID, option, explanation.Explanation, sourcePage, sourceLabel, sourceExcerpt, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save answer explanation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"pbkk-quizlit-backend/internal/database"
+	"pbkk-quizlit-backend/internal/models"
+	"time"
+)
+
+type TutorRepository struct{}
+
+func NewTutorRepository() *TutorRepository {
+	return &TutorRepository{}
+}
+
+// AddMessage appends a message to a user's conversation about a document
+func (r *TutorRepository) AddMessage(ctx context.Context, userID string, msg *models.TutorMessage) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	citationsJSON, err := nullableJSON(msg.Citations, len(msg.Citations) == 0)
+	if err != nil {
+		return fmt.Errorf("failed to marshal citations: %w", err)
+	}
+
+	msg.CreatedAt = time.Now()
+
+	var messageID int64
+	err = db.QueryRow(ctx,
+		`INSERT INTO tutor_messages (user_id, document_id, role, content, citations, created_at)
+		 VALUES ($1, $2, $3, $4, $5::jsonb, $6)
+		 RETURNING id`,
+		userID, msg.DocumentID, msg.Role, msg.Content, citationsJSON, msg.CreatedAt,
+	).Scan(&messageID)
+	if err != nil {
+		return fmt.Errorf("failed to insert tutor message: %w", err)
+	}
+
+	msg.ID = fmt.Sprintf("%d", messageID)
+	return nil
+}
+
+// ListMessages returns the latest messages of a user's conversation about a document,
+// oldest first. A limit of 0 returns the whole conversation.
+func (r *TutorRepository) ListMessages(ctx context.Context, userID, documentID string, limit int) ([]models.TutorMessage, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, fmt.Errorf("database connection not initialized")
+	}
+
+	var maxRows *int
+	if limit > 0 {
+		maxRows = &limit
+	}
+
+	rows, err := db.Query(ctx,
+		`SELECT id, document_id, role, content, citations, created_at
+		 FROM tutor_messages
+		 WHERE user_id = $1 AND document_id = $2
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $3`,
+		userID, documentID, maxRows,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tutor messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []models.TutorMessage{}
+	for rows.Next() {
+		var msg models.TutorMessage
+		var citationsJSON []byte
+
+		if err := rows.Scan(&msg.ID, &msg.DocumentID, &msg.Role, &msg.Content, &citationsJSON, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tutor message: %w", err)
+		}
+		if len(citationsJSON) > 0 {
+			if err := json.Unmarshal(citationsJSON, &msg.Citations); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal citations: %w", err)
+			}
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tutor messages: %w", err)
+	}
+
+	// Rows come newest first so the limit keeps the latest ones
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// DeleteMessages clears a user's conversation about a document
+func (r *TutorRepository) DeleteMessages(ctx context.Context, userID, documentID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return fmt.Errorf("database connection not initialized")
+	}
+
+	if _, err := db.Exec(ctx,
+		`DELETE FROM tutor_messages WHERE user_id = $1 AND document_id = $2`,
+		userID, documentID,
+	); err != nil {
+		return fmt.Errorf("failed to delete tutor messages: %w", err)
+	}
+
+	return nil
+}
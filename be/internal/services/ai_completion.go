@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ollamaGenerateURL is the default local Ollama installation endpoint
+const ollamaGenerateURL = "http://localhost:11434/api/generate"
+
+// ollamaEmbeddingsURL is the embeddings endpoint of the local Ollama installation
+const ollamaEmbeddingsURL = "http://localhost:11434/api/embeddings"
+
+// Embedding providers. Vectors from different providers cannot be compared.
+const (
+	embeddingProviderOpenAI = "openai"
+	embeddingProviderOllama = "ollama"
+)
+
+// ollamaHTTPClient bounds how long a single Ollama call may take
+var ollamaHTTPClient = &http.Client{Timeout: 2 * time.Minute}
+
+// completeText sends a prompt to the configured LLM providers and returns the raw reply.
+// OpenAI is tried first when configured, then the local Ollama installation.
+func (ai *AIService) completeText(systemPrompt, prompt string, maxTokens int) (string, error) {
+	if ai.useOpenAI && ai.client != nil {
+		reply, err := ai.completeWithOpenAI(systemPrompt, prompt, maxTokens)
+		if err == nil {
+			return reply, nil
+		}
+		ai.logger.Warnf("OpenAI completion failed: %v, trying Ollama", err)
+	}
+
+	reply, err := ai.completeWithOllama(systemPrompt, prompt)
+	if err != nil {
+		return "", fmt.Errorf("no LLM provider available: %w", err)
+	}
+	return reply, nil
+}
+
+// completeWithOpenAI runs a single chat completion against OpenAI
+func (ai *AIService) completeWithOpenAI(systemPrompt, prompt string, maxTokens int) (string, error) {
+	resp, err := ai.client.CreateChatCompletion(
+		context.Background(),
+		openai.ChatCompletionRequest{
+			Model: openai.GPT3Dot5Turbo,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: systemPrompt,
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			MaxTokens:   maxTokens,
+			Temperature: 0.3,
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("OpenAI API error: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from OpenAI")
+	}
+
+	return resp.Choices[0].Message.Content, nil
+}
+
+// completeWithOllama runs a single non-streaming generation against Ollama
+func (ai *AIService) completeWithOllama(systemPrompt, prompt string) (string, error) {
+	requestBody := map[string]interface{}{
+		"model":  "llama2",
+		"system": systemPrompt,
+		"prompt": prompt,
+		"stream": false,
+	}
+
+	jsonData, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := ollamaHTTPClient.Post(ollamaGenerateURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("ollama API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		ai.logger.Errorf("Ollama API error response: %s", string(body))
+		return "", fmt.Errorf("ollama API returned status %d", resp.StatusCode)
+	}
+
+	var ollamaResp struct {
+		Response string `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return "", fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+
+	return ollamaResp.Response, nil
+}
+
+// embedTexts embeds texts with the first available provider, OpenAI when configured and
+// then the local Ollama installation. It returns the provider used, so later queries can
+// be embedded into the same vector space.
+func (ai *AIService) embedTexts(texts []string) ([][]float32, string, error) {
+	if ai.useOpenAI && ai.client != nil {
+		vectors, err := ai.embedWith(embeddingProviderOpenAI, texts)
+		if err == nil {
+			return vectors, embeddingProviderOpenAI, nil
+		}
+		ai.logger.Warnf("OpenAI embeddings failed: %v, trying Ollama", err)
+	}
+
+	vectors, err := ai.embedWith(embeddingProviderOllama, texts)
+	if err != nil {
+		return nil, "", fmt.Errorf("no embedding provider available: %w", err)
+	}
+	return vectors, embeddingProviderOllama, nil
+}
+
+// embedWith embeds texts with the given provider
+func (ai *AIService) embedWith(provider string, texts []string) ([][]float32, error) {
+	switch provider {
+	case embeddingProviderOpenAI:
+		if ai.client == nil {
+			return nil, fmt.Errorf("OpenAI is not configured")
+		}
+		resp, err := ai.client.CreateEmbeddings(context.Background(), openai.EmbeddingRequest{
+			Input: texts,
+			Model: openai.AdaEmbeddingV2,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("OpenAI API error: %w", err)
+		}
+		if len(resp.Data) != len(texts) {
+			return nil, fmt.Errorf("OpenAI returned %d embeddings for %d texts", len(resp.Data), len(texts))
+		}
+		vectors := make([][]float32, len(texts))
+		for _, item := range resp.Data {
+			if item.Index < 0 || item.Index >= len(texts) {
+				return nil, fmt.Errorf("OpenAI returned embedding for unknown index %d", item.Index)
+			}
+			vectors[item.Index] = item.Embedding
+		}
+		return vectors, nil
+
+	case embeddingProviderOllama:
+		vectors := make([][]float32, len(texts))
+		for i, text := range texts {
+			vector, err := ai.embedWithOllama(text)
+			if err != nil {
+				return nil, err
+			}
+			vectors[i] = vector
+		}
+		return vectors, nil
+
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q", provider)
+	}
+}
+
+// embedWithOllama embeds a single text; the Ollama endpoint takes one prompt per call
+func (ai *AIService) embedWithOllama(text string) ([]float32, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"model":  "llama2",
+		"prompt": text,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := ollamaHTTPClient.Post(ollamaEmbeddingsURL, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("ollama API error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama API returned status %d", resp.StatusCode)
+	}
+
+	var ollamaResp struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+	if len(ollamaResp.Embedding) == 0 {
+		return nil, fmt.Errorf("ollama returned an empty embedding")
+	}
+
+	return ollamaResp.Embedding, nil
+}
+
+// cleanJSONResponse strips markdown code fences that models like to wrap JSON in
+func cleanJSONResponse(response string) string {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+	return strings.TrimSpace(response)
+}
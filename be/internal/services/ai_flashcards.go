@@ -0,0 +1,241 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// definitionVerbs are the linking words that usually follow a term in a defining sentence
+var definitionVerbs = []string{
+	"is", "are", "was", "refers to", "means", "describes", "is defined as", "is called",
+	"adalah", "merupakan", "yaitu", "ialah", "disebut", "didefinisikan sebagai",
+}
+
+// GenerateFlashcardsFromContent builds a flashcard deck from plain text content
+func (ai *AIService) GenerateFlashcardsFromContent(content string, req *models.DeckGenerationRequest) (*models.FlashcardDeck, error) {
+	return ai.GenerateFlashcardsFromPages([]models.DocumentPage{{Number: 1, Text: content}}, req)
+}
+
+// GenerateFlashcardsFromPages builds a flashcard deck using the LLM providers, falling
+// back to rule-based generation from the document glossary
+func (ai *AIService) GenerateFlashcardsFromPages(pages []models.DocumentPage, req *models.DeckGenerationRequest) (*models.FlashcardDeck, error) {
+	if req.CardCount <= 0 {
+		req.CardCount = 15
+	}
+
+	ai.logger.Infof("Generating flashcard deck with %d cards", req.CardCount)
+
+	cards, err := ai.generateFlashcardsWithLLM(PlainText(JoinPages(pages), true), req.CardCount)
+	if err != nil {
+		ai.logger.Warnf("LLM flashcard generation failed: %v, using rule-based generation", err)
+		cards = ai.generateRuleBasedFlashcards(pages, req.CardCount)
+	}
+
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("could not find enough key terms in the content to build flashcards")
+	}
+
+	deck := &models.FlashcardDeck{
+		ID:          uuid.New().String(),
+		Title:       req.Title,
+		Description: req.Description,
+		Cards:       cards,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		TotalCards:  len(cards),
+	}
+
+	ai.logger.Infof("Successfully generated deck with %d cards", len(cards))
+	return deck, nil
+}
+
+// generateFlashcardsWithLLM asks the configured provider for term/definition pairs
+func (ai *AIService) generateFlashcardsWithLLM(content string, count int) ([]models.Flashcard, error) {
+	maxContentLength := 3000
+	if utf8.RuneCountInString(content) > maxContentLength {
+		content = firstRunes(content, maxContentLength) + "..."
+	}
+
+	prompt := fmt.Sprintf(`Based on the following content, create %d study flashcards.
+
+Content:
+%s
+
+Requirements:
+- "front" is a key term, name or short question from the content
+- "back" is a concise answer or definition taken from the content
+- "source" quotes the first few words of the sentence the card is based on
+- Return the response as a JSON array
+
+JSON Format:
+[
+  {
+    "front": "Term",
+    "back": "Definition of the term",
+    "source": "The first words of the source sentence"
+  }
+]
+
+Return ONLY the JSON array, no additional text.`, count, content)
+
+	reply, err := ai.completeText(
+		"You are an expert study assistant that writes accurate flashcards. Return ONLY valid JSON without any additional text or formatting.",
+		prompt,
+		2000,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawCards []struct {
+		Front  string `json:"front"`
+		Back   string `json:"back"`
+		Source string `json:"source"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(reply)), &rawCards); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	var cards []models.Flashcard
+	for _, rc := range rawCards {
+		front := strings.TrimSpace(rc.Front)
+		back := strings.TrimSpace(rc.Back)
+		if front == "" || back == "" {
+			continue
+		}
+
+		cards = append(cards, models.Flashcard{
+			ID:        uuid.New().String(),
+			Front:     front,
+			Back:      back,
+			SourceRef: strings.TrimSpace(rc.Source),
+		})
+
+		if len(cards) >= count {
+			break
+		}
+	}
+
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("no valid flashcards found in response")
+	}
+
+	return cards, nil
+}
+
+// generateRuleBasedFlashcards turns glossary terms and their defining sentences into cards
+func (ai *AIService) generateRuleBasedFlashcards(pages []models.DocumentPage, count int) []models.Flashcard {
+	ai.logger.Info("Using rule-based flashcard generation")
+
+	glossary := ai.BuildGlossary(pages, GlossaryOptions{Limit: count * 2})
+	return GlossaryToFlashcards(glossary, len(pages) > 1, count)
+}
+
+// GlossaryToFlashcards turns glossary entries into cards, skipping terms that overlap an
+// earlier card or share its definition. Page references are added for multi-page sources.
+func GlossaryToFlashcards(glossary []models.GlossaryTerm, citePages bool, count int) []models.Flashcard {
+	usedDefinitions := make(map[string]bool)
+	var usedTerms []string
+	var cards []models.Flashcard
+
+	for _, term := range glossary {
+		if len(cards) >= count {
+			break
+		}
+		if term.Definition == "" || usedDefinitions[term.Definition] || overlapsTerm(term.Term, usedTerms) {
+			continue
+		}
+
+		sourceRef := ""
+		if citePages && len(term.Pages) > 0 {
+			sourceRef = fmt.Sprintf("page %d", term.Pages[0])
+		}
+
+		usedDefinitions[term.Definition] = true
+		usedTerms = append(usedTerms, term.Term)
+		cards = append(cards, models.Flashcard{
+			ID:        uuid.New().String(),
+			Front:     capitalizeFirst(term.Term),
+			Back:      term.Definition,
+			SourceRef: sourceRef,
+		})
+	}
+
+	return cards
+}
+
+// findDefiningSentence returns the index of the unused sentence that best defines a term,
+// or -1 when no sentence mentions it
+func findDefiningSentence(sentences []string, term string, used map[int]bool) int {
+	termPattern, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`)
+	if err != nil {
+		return -1
+	}
+
+	verbs := make([]string, len(definitionVerbs))
+	for i, verb := range definitionVerbs {
+		verbs[i] = regexp.QuoteMeta(verb)
+	}
+	definitionPattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(term) + `\b[,)]?\s+(` + strings.Join(verbs, "|") + `)\b`)
+
+	best, bestScore := -1, 0
+	for i, sentence := range sentences {
+		if used[i] {
+			continue
+		}
+		loc := termPattern.FindStringIndex(sentence)
+		if loc == nil {
+			continue
+		}
+
+		score := 1
+		if definitionPattern.MatchString(sentence) {
+			score += 3
+		}
+		if loc[0] < len(sentence)/3 {
+			score++
+		}
+
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return best
+}
+
+// overlapsTerm reports whether a term repeats or contains one that was already used
+func overlapsTerm(term string, used []string) bool {
+	termLower := strings.ToLower(term)
+	for _, u := range used {
+		uLower := strings.ToLower(u)
+		if strings.Contains(termLower, uLower) || strings.Contains(uLower, termLower) {
+			return true
+		}
+	}
+	return false
+}
+
+func capitalizeFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+func lowercaseFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[size:]
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"pbkk-quizlit-backend/internal/models"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/sashabaranov/go-openai"
@@ -61,6 +63,7 @@ func (ai *AIService) GenerateQuizFromContent(content string, req *models.QuizGen
 			Description:   req.Description,
 			Difficulty:    req.Difficulty,
 			QuestionCount: req.QuestionCount,
+			BloomMix:      req.BloomMix,
 		}
 		questions, err = ai.generateWithOpenAI(content, createReq)
 		if err != nil {
@@ -89,6 +92,7 @@ func (ai *AIService) GenerateQuizFromContent(content string, req *models.QuizGen
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 		TotalQuestions: len(questions),
+		BloomMix:       req.BloomMix,
 	}
 
 	ai.logger.Infof("Successfully generated quiz with %d questions", len(questions))
@@ -142,7 +146,7 @@ func (ai *AIService) generateWithOllama(content string, req *models.QuizGenerati
 	ai.logger.Info("Using Ollama for quiz generation")
 	
 	// Ollama API endpoint (default local installation)
-	url := "http://localhost:11434/api/generate"
+	url := ollamaGenerateURL
 	
 	prompt := ai.buildPrompt(content, req)
 	
@@ -192,10 +196,13 @@ func (ai *AIService) generateWithOllama(content string, req *models.QuizGenerati
 func (ai *AIService) generateIntelligentQuestions(content string, req *models.QuizGenerationRequest) []models.Question {
 	ai.logger.Info("Using enhanced intelligent rule-based quiz generation")
 	
-	// Enhanced content analysis
-	sentences := ai.extractSentences(content)
-	keywords := ai.extractKeywords(content)
-	concepts := ai.extractConcepts(content)
+	// Enhanced content analysis, on the text without heading marks and list markers;
+	// tables are read from the structured text
+	prose := PlainText(content, true)
+	sentences := ai.extractSentences(prose)
+	keywords := ai.extractKeywordsWithCorpus(prose, req.Corpus, 30)
+	concepts := ai.extractConcepts(prose)
+	distractors := NewDistractorEngine(prose, ai.extractKeywordsWithCorpus(prose, req.Corpus, 150))
 	
 	// Filter sentences to only informative ones
 	validSentences := ai.filterInformativeSentences(sentences, keywords)
@@ -206,6 +213,14 @@ func (ai *AIService) generateIntelligentQuestions(content string, req *models.Qu
 		targetCount = 5
 	}
 	
+	// Up to a third of the questions ask for values of the document's tables
+	for _, question := range tableQuestions(content, (targetCount+2)/3) {
+		if ai.isValidQuestion(question, content) {
+			question.ID = uuid.New().String()
+			questions = append(questions, question)
+		}
+	}
+	
 	usedSentences := make(map[string]bool)
 	
 	// Generate diverse, high-quality questions
@@ -230,12 +245,13 @@ func (ai *AIService) generateIntelligentQuestions(content string, req *models.Qu
 		}
 		
 		var question models.Question
+		ok := true
 		
 		// Only generate multiple-choice and true/false questions
 		// Fill-in-blank disabled because it has no options (causes UI issues)
 		switch i % 2 {
 		case 0: // Multiple choice based on key sentences
-			question = ai.generateMultipleChoiceFromSentence(sentence, keywords, concepts)
+			question, ok = ai.generateMultipleChoiceFromSentence(sentence, keywords, concepts, distractors)
 		case 1: // True/false questions
 			question = ai.generateTrueFalseFromSentence(sentence, keywords)
 		// case 2: // Fill in the blank - DISABLED
@@ -243,7 +259,7 @@ func (ai *AIService) generateIntelligentQuestions(content string, req *models.Qu
 		}
 		
 		// Validate question quality before adding
-		if ai.isValidQuestion(question, content) {
+		if ok && ai.isValidQuestion(question, content) {
 			question.ID = uuid.New().String()
 			questions = append(questions, question)
 		}
@@ -257,6 +273,9 @@ func (ai *AIService) extractSentences(content string) []string {
 	// Better sentence extraction with length limits
 	sentences := []string{}
 	
+	// Headings and list items end sentences of their own; tables are not prose
+	content = strings.Join(strings.Fields(PlainText(content, false)), " ")
+	
 	// Split by common sentence endings
 	content = strings.ReplaceAll(content, "! ", ".|")
 	content = strings.ReplaceAll(content, "? ", ".|")
@@ -295,56 +314,15 @@ func (ai *AIService) extractSentences(content string) []string {
 }
 
 func (ai *AIService) extractKeywords(content string) []string {
-	// Better keyword extraction with frequency analysis
-	words := strings.Fields(content)
-	wordFreq := make(map[string]int)
-	
-	// Common stop words to ignore (Indonesian and English)
-	stopWords := map[string]bool{
-		"the": true, "a": true, "an": true, "and": true, "or": true, "but": true,
-		"in": true, "on": true, "at": true, "to": true, "for": true, "of": true,
-		"with": true, "by": true, "from": true, "as": true, "is": true, "was": true,
-		"are": true, "were": true, "been": true, "be": true, "have": true, "has": true,
-		"had": true, "do": true, "does": true, "did": true, "will": true, "would": true,
-		"could": true, "should": true, "may": true, "might": true, "must": true,
-		"can": true, "this": true, "that": true, "these": true, "those": true,
-		"yang": true, "dan": true, "atau": true, "adalah": true, "ini": true, "itu": true,
-		"dari": true, "ke": true, "di": true, "untuk": true, "dengan": true, "pada": true,
-	}
-	
-	for _, word := range words {
-		cleaned := strings.ToLower(strings.Trim(word, ".,!?;:()[]{}\"'"))
-		// Keep words that are substantial and not stop words
-		if len(cleaned) > 3 && !stopWords[cleaned] {
-			wordFreq[cleaned]++
-		}
-	}
-	
-	// Sort by frequency
-	type keywordScore struct {
-		word  string
-		score int
-	}
-	var scored []keywordScore
-	for word, freq := range wordFreq {
-		scored = append(scored, keywordScore{word, freq})
-	}
-	
-	// Simple sort by score
-	for i := 0; i < len(scored); i++ {
-		for j := i + 1; j < len(scored); j++ {
-			if scored[j].score > scored[i].score {
-				scored[i], scored[j] = scored[j], scored[i]
-			}
-		}
-	}
-	
-	// Return top keywords
+	return ai.extractKeywordsWithCorpus(content, nil, 30)
+}
+
+// extractKeywordsWithCorpus returns the top TF-IDF keywords and phrases of the content
+func (ai *AIService) extractKeywordsWithCorpus(content string, corpus *models.KeywordCorpus, limit int) []string {
 	var result []string
-	for i := 0; i < len(scored) && i < 30; i++ {
-		result = append(result, scored[i].word)
+	for _, kw := range ExtractKeywords(content, KeywordOptions{}, corpus, limit) {
+		result = append(result, kw.Term)
 	}
-	
 	return result
 }
 
@@ -451,125 +429,165 @@ func (ai *AIService) isValidQuestion(question models.Question, content string) b
 		}
 	}
 	
+	// Reject questions with item-writing errors such as a leaked or duplicated answer
+	for _, issue := range LintQuestion(question, "") {
+		if issue.Severity == LintError {
+			return false
+		}
+	}
+	
 	return true
 }
 
-func (ai *AIService) generateMultipleChoiceFromSentence(sentence string, keywords []string, concepts []string) models.Question {
-	// Create a question by identifying and replacing a key term
-	words := strings.Fields(sentence)
-	var targetWord string
-	var questionText string
-	
-	// First try to find a keyword in the sentence
-	for i, word := range words {
-		cleaned := strings.Trim(word, ".,!?;:")
-		for _, keyword := range keywords {
-			if strings.EqualFold(cleaned, keyword) && len(cleaned) > 4 {
-				targetWord = cleaned
-				// Replace in original sentence
-				wordsCopy := make([]string, len(words))
-				copy(wordsCopy, words)
-				wordsCopy[i] = "____"
-				questionText = strings.Join(wordsCopy, " ")
-				break
+// generateMultipleChoiceFromSentence blanks out a key term of the sentence and asks for it
+// among distractors of the same kind drawn from the document. It returns false when no
+// term in the sentence has enough plausible distractors.
+func (ai *AIService) generateMultipleChoiceFromSentence(sentence string, keywords []string, concepts []string, distractors *DistractorEngine) (models.Question, bool) {
+	for _, target := range answerCandidates(sentence, keywords, concepts) {
+		pattern, err := wholeWordPattern(target)
+		if err != nil {
+			continue
+		}
+		loc := pattern.FindStringIndex(sentence)
+		if loc == nil {
+			continue
+		}
+		
+		wrong := distractors.Distractors(target, sentence, 3)
+		if len(wrong) < 3 {
+			continue
+		}
+		
+		// Trim question text if too long, keeping the blank in view. Lengths count
+		// characters, so text in other scripts is not cut inside a character.
+		before, after := sentence[:loc[0]], sentence[loc[1]:]
+		if utf8.RuneCountInString(before)+utf8.RuneCountInString(after) > 140 {
+			if utf8.RuneCountInString(before) > 70 {
+				before = "..." + trimToWord(lastRunes(before, 70), false)
+			}
+			if room := 140 - utf8.RuneCountInString(before); utf8.RuneCountInString(after) > room {
+				after = trimToWord(firstRunes(after, room), true) + "..."
 			}
 		}
-		if targetWord != "" {
-			break
+		questionText := before + "____" + after
+		
+		// Put the answer at a random position
+		options := append([]string{target}, wrong...)
+		rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		correctAnswer := 0
+		for i, option := range options {
+			if option == target {
+				correctAnswer = i
+			}
+		}
+		
+		return models.Question{
+			Type:          "multiple-choice",
+			Text:          "Complete the sentence: " + questionText,
+			Options:       options,
+			Correct:       target,
+			CorrectAnswer: correctAnswer,
+			Points:        1,
+			Metadata:      map[string]interface{}{"source": "rule-based-enhanced"},
+		}, true
+	}
+	
+	return models.Question{}, false
+}
+
+// firstRunes returns the first n characters of s
+func firstRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
 		}
+		n--
 	}
-	
-	// If no keyword found, try concepts
-	if targetWord == "" {
-		for _, concept := range concepts {
-			if strings.Contains(sentence, concept) {
-				targetWord = concept
-				questionText = strings.Replace(sentence, concept, "____", 1)
-				break
-			}
+	return s
+}
+
+// lastRunes returns the last n characters of s
+func lastRunes(s string, n int) string {
+	for i := len(s); i > 0; {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+		if n--; n == 0 {
+			return s[i:]
 		}
 	}
-	
-	// Fallback: pick a meaningful word
-	if targetWord == "" && len(words) > 5 {
-		for i := 2; i < len(words)-2; i++ {
-			cleaned := strings.Trim(words[i], ".,!?;:")
-			if len(cleaned) > 4 && !strings.Contains(strings.ToLower(cleaned), "yang") && !strings.Contains(strings.ToLower(cleaned), "adalah") {
-				targetWord = cleaned
-				wordsCopy := make([]string, len(words))
-				copy(wordsCopy, words)
-				wordsCopy[i] = "____"
-				questionText = strings.Join(wordsCopy, " ")
-				break
-			}
+	return s
+}
+
+// trimToWord drops the partial word left at the cut end of a trimmed string
+func trimToWord(s string, fromEnd bool) string {
+	if fromEnd {
+		if i := strings.LastIndex(s, " "); i > 0 {
+			return s[:i]
 		}
+		return s
 	}
-	
-	if questionText == "" {
-		questionText = sentence
-		targetWord = "unknown"
+	if i := strings.Index(s, " "); i >= 0 {
+		return s[i:]
+	}
+	return s
+}
+
+// answerCandidates lists the terms of a sentence that could be blanked out, in order of
+// preference: keywords, concepts, numbers and names, then any other meaningful word
+func answerCandidates(sentence string, keywords []string, concepts []string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+	add := func(text string) {
+		text = strings.Trim(text, ".,!?;:()\"'")
+		if text == "" || seen[strings.ToLower(text)] {
+			return
+		}
+		seen[strings.ToLower(text)] = true
+		candidates = append(candidates, text)
 	}
 	
-	// Generate realistic distractors from content
-	options := []string{targetWord}
-	usedOptions := make(map[string]bool)
-	usedOptions[strings.ToLower(targetWord)] = true
-	
-	// Add similar keywords as distractors
 	for _, keyword := range keywords {
-		if len(options) >= 4 {
-			break
+		if len(keyword) <= 4 {
+			continue
+		}
+		pattern, err := wholeWordPattern(keyword)
+		if err != nil {
+			continue
 		}
-		keywordLower := strings.ToLower(keyword)
-		if !usedOptions[keywordLower] && len(keyword) > 3 {
-			options = append(options, keyword)
-			usedOptions[keywordLower] = true
+		if loc := pattern.FindStringIndex(sentence); loc != nil {
+			term := sentence[loc[0]:loc[1]]
+			// Lower a term that is only capitalised because it starts the sentence
+			if loc[0] == 0 && classifyAnswer(term) != kindName {
+				term = lowercaseFirst(term)
+			}
+			add(term)
 		}
 	}
 	
-	// Add concepts as distractors if needed
 	for _, concept := range concepts {
-		if len(options) >= 4 {
-			break
-		}
-		conceptLower := strings.ToLower(concept)
-		if !usedOptions[conceptLower] && !strings.Contains(conceptLower, strings.ToLower(targetWord)) {
-			options = append(options, concept)
-			usedOptions[conceptLower] = true
+		if strings.Contains(sentence, concept) {
+			add(concept)
 		}
 	}
 	
-	// If still not enough options, add generic but plausible ones
-	genericOptions := []string{"None of the above", "All of the above", "Cannot be determined", "Not specified"}
-	for _, generic := range genericOptions {
-		if len(options) >= 4 {
-			break
-		}
-		options = append(options, generic)
+	for _, number := range distractorNumberPattern.FindAllString(sentence, -1) {
+		add(number)
 	}
-	
-	// Ensure we have exactly 4 options
-	if len(options) < 4 {
-		for len(options) < 4 {
-			options = append(options, fmt.Sprintf("Option %d", len(options)+1))
+	for _, loc := range distractorNamePattern.FindAllStringIndex(sentence, -1) {
+		if loc[0] > 0 {
+			add(sentence[loc[0]:loc[1]])
 		}
-	} else if len(options) > 4 {
-		options = options[:4]
 	}
 	
-	// Trim question text if too long
-	if len(questionText) > 150 {
-		questionText = questionText[:147] + "..."
+	words := strings.Fields(sentence)
+	for i := 2; i < len(words)-2; i++ {
+		cleaned := strings.Trim(words[i], ".,!?;:")
+		if len(cleaned) > 4 && !strings.Contains(strings.ToLower(cleaned), "yang") && !strings.Contains(strings.ToLower(cleaned), "adalah") {
+			add(cleaned)
+		}
 	}
 	
-	return models.Question{
-		Type:     "multiple-choice",
-		Text:     "Complete the sentence: " + questionText,
-		Options:  options,
-		Correct:  targetWord,
-		Points:   1,
-		Metadata: map[string]interface{}{"source": "rule-based-enhanced"},
-	}
+	return candidates
 }
 
 func (ai *AIService) generateTrueFalseFromSentence(sentence string, keywords []string) models.Question {
@@ -622,17 +640,23 @@ func (ai *AIService) generateTrueFalseFromSentence(sentence string, keywords []s
 	}
 	
 	// Trim if too long
-	if len(questionText) > 150 {
-		questionText = questionText[:147] + "..."
+	if utf8.RuneCountInString(questionText) > 150 {
+		questionText = trimToWord(firstRunes(questionText, 147), true) + "..."
+	}
+	
+	correctAnswer := 0
+	if correct == "False" {
+		correctAnswer = 1
 	}
 	
 	return models.Question{
-		Type:     "true-false",
-		Text:     "True or False: " + questionText,
-		Options:  []string{"True", "False"},
-		Correct:  correct,
-		Points:   1,
-		Metadata: map[string]interface{}{"source": "rule-based-enhanced"},
+		Type:          "true-false",
+		Text:          "True or False: " + questionText,
+		Options:       []string{"True", "False"},
+		Correct:       correct,
+		CorrectAnswer: correctAnswer,
+		Points:        1,
+		Metadata:      map[string]interface{}{"source": "rule-based-enhanced"},
 	}
 }
 
@@ -680,8 +704,8 @@ func (ai *AIService) generateFillInTheBlank(sentence string, keywords []string)
 	}
 	
 	// Trim if too long
-	if len(questionText) > 150 {
-		questionText = questionText[:147] + "..."
+	if utf8.RuneCountInString(questionText) > 150 {
+		questionText = trimToWord(firstRunes(questionText, 147), true) + "..."
 	}
 	
 	return models.Question{
@@ -695,6 +719,7 @@ func (ai *AIService) generateFillInTheBlank(sentence string, keywords []string)
 }
 
 func (ai *AIService) buildPrompt(content string, req *models.QuizGenerationRequest) string {
+	content = PlainText(content, true)
 	prompt := fmt.Sprintf(`Create a quiz with %d questions based on the following content. 
 
 Content:
@@ -707,6 +732,9 @@ Requirements:
 - Generate exactly %d questions
 - Include multiple choice, true/false, and fill-in-the-blank questions
 - Provide correct answers
+- Lines of the form "| a | b |" are table rows; questions on a table should name it, e.g. "According to Table 2, ..."
+%s (remember, understand, apply, analyze, evaluate or create)
+- List the learning objectives each question assesses
 - Format as JSON with this structure:
 {
   "questions": [
@@ -715,19 +743,23 @@ Requirements:
       "text": "Question text?",
       "options": ["A", "B", "C", "D"],
       "correct": "A",
-      "points": 1
+      "points": 1,
+      "bloomLevel": "understand",
+      "objectives": ["Explain the main idea of the topic"]
     }
   ]
 }
 
 Ensure questions are clear, relevant, and test understanding of the key concepts.`,
-		req.QuestionCount, content, req.Title, req.Description, req.Difficulty, req.QuestionCount)
+		req.QuestionCount, content, req.Title, req.Description, req.Difficulty, req.QuestionCount,
+		bloomPromptInstruction(req.BloomMix, req.QuestionCount))
 		
 	return prompt
 }
 
 func (ai *AIService) createPrompt(content string, req models.CreateQuizRequest) string {
 	// Truncate content if too long
+	content = PlainText(content, true)
 	maxContentLength := 3000
 	if len(content) > maxContentLength {
 		content = content[:maxContentLength] + "..."
@@ -754,6 +786,9 @@ Requirements:
 - Each question should have 4 options (A, B, C, D)
 - Indicate the correct answer (0-3 index)
 - Provide a brief explanation for each answer
+- Lines of the form "| a | b |" are table rows; questions on a table should name it, e.g. "According to Table 2, ..."
+%s (remember, understand, apply, analyze, evaluate or create)
+- List the learning objectives each question assesses
 - Return the response as a JSON array of questions
 
 JSON Format:
@@ -762,12 +797,15 @@ JSON Format:
     "question": "Question text here?",
     "options": ["Option A", "Option B", "Option C", "Option D"],
     "correctAnswer": 0,
-    "explanation": "Brief explanation of why this is correct"
+    "explanation": "Brief explanation of why this is correct",
+    "bloomLevel": "apply",
+    "objectives": ["Apply the formula to a new example"]
   }
 ]
 
 Return ONLY the JSON array, no additional text.`, 
-		req.QuestionCount, instruction, content, req.QuestionCount)
+		req.QuestionCount, instruction, content, req.QuestionCount,
+		bloomPromptInstruction(req.BloomMix, req.QuestionCount))
 }
 
 func (ai *AIService) parseAIResponse(response string) ([]models.Question, error) {
@@ -783,6 +821,8 @@ func (ai *AIService) parseAIResponse(response string) ([]models.Question, error)
 		Options       []string `json:"options"`
 		CorrectAnswer int      `json:"correctAnswer"`
 		Explanation   string   `json:"explanation"`
+		BloomLevel    string   `json:"bloomLevel"`
+		Objectives    []string `json:"objectives"`
 	}
 
 	if err := json.Unmarshal([]byte(response), &rawQuestions); err != nil {
@@ -796,11 +836,13 @@ func (ai *AIService) parseAIResponse(response string) ([]models.Question, error)
 		}
 
 		questions = append(questions, models.Question{
-			ID:            uuid.New().String(),
-			Question:      rq.Question,
-			Options:       rq.Options,
-			CorrectAnswer: rq.CorrectAnswer,
-			Explanation:   rq.Explanation,
+			ID:                 uuid.New().String(),
+			Question:           rq.Question,
+			Options:            rq.Options,
+			CorrectAnswer:      rq.CorrectAnswer,
+			Explanation:        rq.Explanation,
+			BloomLevel:         NormalizeBloomLevel(rq.BloomLevel),
+			LearningObjectives: rq.Objectives,
 		})
 
 		// Limit to prevent excessive questions
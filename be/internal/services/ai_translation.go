@@ -0,0 +1,226 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// translationBatchSize keeps each translation prompt well inside the model's token budget
+const translationBatchSize = 10
+
+type translationOption struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+type translationQuestion struct {
+	ID          string              `json:"id"`
+	Text        string              `json:"text"`
+	Options     []translationOption `json:"options"`
+	Explanation string              `json:"explanation,omitempty"`
+	Objectives  []string            `json:"objectives,omitempty"`
+}
+
+type translationPayload struct {
+	Title       string                `json:"title,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Questions   []translationQuestion `json:"questions"`
+}
+
+// TranslateQuiz creates a translated copy of a quiz using the configured LLM providers.
+// Options are sent with stable IDs so the answer key of the copy points at the same option.
+// The copy is linked to the original quiz and questions, also when a translation is
+// translated, so every language version shares one group.
+func (ai *AIService) TranslateQuiz(quiz *models.Quiz, targetLanguage string) (*models.Quiz, error) {
+	targetLanguage = strings.TrimSpace(targetLanguage)
+	if targetLanguage == "" {
+		return nil, fmt.Errorf("target language is required")
+	}
+	if len(quiz.Questions) == 0 {
+		return nil, fmt.Errorf("quiz has no questions to translate")
+	}
+
+	ai.logger.Infof("Translating quiz %s (%d questions) to %s", quiz.ID, len(quiz.Questions), targetLanguage)
+
+	translated := &models.Quiz{
+		ID:           uuid.New().String(),
+		Title:        quiz.Title,
+		Description:  quiz.Description,
+		Difficulty:   quiz.Difficulty,
+		Language:     targetLanguage,
+		SourceQuizID: rootID(quiz.ID, quiz.SourceQuizID),
+		BloomMix:     quiz.BloomMix,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	for start := 0; start < len(quiz.Questions); start += translationBatchSize {
+		end := start + translationBatchSize
+		if end > len(quiz.Questions) {
+			end = len(quiz.Questions)
+		}
+
+		payload := translationPayload{}
+		if start == 0 {
+			payload.Title = quiz.Title
+			payload.Description = quiz.Description
+		}
+		for _, q := range quiz.Questions[start:end] {
+			payload.Questions = append(payload.Questions, toTranslationQuestion(q))
+		}
+
+		result, err := ai.translateBatch(payload, targetLanguage)
+		if err != nil {
+			return nil, err
+		}
+
+		if start == 0 {
+			if result.Title != "" {
+				translated.Title = result.Title
+			}
+			if result.Description != "" {
+				translated.Description = result.Description
+			}
+		}
+
+		byID := make(map[string]translationQuestion, len(result.Questions))
+		for _, tq := range result.Questions {
+			byID[tq.ID] = tq
+		}
+
+		for _, q := range quiz.Questions[start:end] {
+			tq, ok := byID[q.ID]
+			if !ok {
+				return nil, fmt.Errorf("translation is missing question %s", q.ID)
+			}
+			question, err := alignTranslatedQuestion(q, tq)
+			if err != nil {
+				return nil, err
+			}
+			translated.Questions = append(translated.Questions, question)
+		}
+	}
+
+	translated.TotalQuestions = len(translated.Questions)
+	return translated, nil
+}
+
+// translateBatch sends one batch of questions to the LLM and parses the reply
+func (ai *AIService) translateBatch(payload translationPayload, targetLanguage string) (*translationPayload, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal translation payload: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`Translate the following quiz into %s.
+
+Rules:
+- Translate "title", "description", every question "text", every option "text", every "explanation" and every "objectives" entry
+- Keep every "id" value exactly as it is, including option ids
+- Keep the options in the same order and do not add or remove options
+- Do not translate names, formulas, numbers or code
+- Return ONLY the JSON object with the same structure, no additional text
+
+Quiz:
+%s`, targetLanguage, string(payloadJSON))
+
+	reply, err := ai.completeText(
+		"You are a professional translator of educational material. Return ONLY valid JSON without any additional text or formatting.",
+		prompt,
+		3000,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var result translationPayload
+	if err := json.Unmarshal([]byte(cleanJSONResponse(reply)), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse translation response: %w", err)
+	}
+
+	return &result, nil
+}
+
+// toTranslationQuestion converts a question into the ID-keyed shape sent to the LLM
+func toTranslationQuestion(q models.Question) translationQuestion {
+	text := q.Text
+	if text == "" {
+		text = q.Question
+	}
+
+	options := make([]translationOption, len(q.Options))
+	for i, option := range q.Options {
+		options[i] = translationOption{ID: i, Text: option}
+	}
+
+	return translationQuestion{
+		ID:          q.ID,
+		Text:        text,
+		Options:     options,
+		Explanation: q.Explanation,
+		Objectives:  q.LearningObjectives,
+	}
+}
+
+// alignTranslatedQuestion rebuilds a question from its translation, placing options by ID
+func alignTranslatedQuestion(original models.Question, tq translationQuestion) (models.Question, error) {
+	if strings.TrimSpace(tq.Text) == "" {
+		return models.Question{}, fmt.Errorf("translation of question %s is empty", original.ID)
+	}
+	if len(tq.Options) != len(original.Options) {
+		return models.Question{}, fmt.Errorf("translation of question %s has %d options, expected %d", original.ID, len(tq.Options), len(original.Options))
+	}
+
+	options := make([]string, len(original.Options))
+	for _, option := range tq.Options {
+		if option.ID < 0 || option.ID >= len(options) || options[option.ID] != "" {
+			return models.Question{}, fmt.Errorf("translation of question %s has an invalid option id %d", original.ID, option.ID)
+		}
+		options[option.ID] = strings.TrimSpace(option.Text)
+	}
+	for i, option := range options {
+		if option == "" {
+			return models.Question{}, fmt.Errorf("translation of question %s is missing option %d", original.ID, i)
+		}
+	}
+
+	correct := ""
+	if original.CorrectAnswer >= 0 && original.CorrectAnswer < len(options) {
+		correct = options[original.CorrectAnswer]
+	}
+
+	// Keep the original objectives if the translation lost some of them
+	objectives := tq.Objectives
+	if len(objectives) != len(original.LearningObjectives) {
+		objectives = original.LearningObjectives
+	}
+
+	return models.Question{
+		ID:                 uuid.New().String(),
+		Type:               original.Type,
+		Text:               tq.Text,
+		Question:           tq.Text,
+		Options:            options,
+		Correct:            correct,
+		CorrectAnswer:      original.CorrectAnswer,
+		Points:             original.Points,
+		Explanation:        tq.Explanation,
+		SourceQuestionID:   rootID(original.ID, original.SourceQuestionID),
+		BloomLevel:         original.BloomLevel,
+		LearningObjectives: objectives,
+	}, nil
+}
+
+// rootID returns the ID of the original a quiz or question was translated from, or its
+// own ID when it is an original
+func rootID(id, sourceID string) string {
+	if sourceID != "" {
+		return sourceID
+	}
+	return id
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"testing"
+)
+
+func TestAlignTranslatedQuestionLinksOriginal(t *testing.T) {
+	tq := translationQuestion{
+		ID:   "q",
+		Text: "¿Cuál es la capital de Francia?",
+		Options: []translationOption{
+			{ID: 1, Text: "París"},
+			{ID: 0, Text: "Roma"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		original models.Question
+		want     string
+	}{
+		{
+			name:     "original question",
+			original: models.Question{ID: "7", Options: []string{"Rome", "Paris"}, CorrectAnswer: 1},
+			want:     "7",
+		},
+		{
+			name:     "translation of a translation",
+			original: models.Question{ID: "12", Options: []string{"Rom", "Paris"}, CorrectAnswer: 1, SourceQuestionID: "7"},
+			want:     "7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question, err := alignTranslatedQuestion(tt.original, tq)
+			if err != nil {
+				t.Fatalf("alignTranslatedQuestion: %v", err)
+			}
+			if question.SourceQuestionID != tt.want {
+				t.Errorf("SourceQuestionID = %q, want %q", question.SourceQuestionID, tt.want)
+			}
+			if question.Options[0] != "Roma" || question.Correct != "París" {
+				t.Errorf("options %v with key %q, want the key to stay on París", question.Options, question.Correct)
+			}
+		})
+	}
+}
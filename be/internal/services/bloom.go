@@ -0,0 +1,310 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BloomLevels are the cognitive levels of the revised Bloom's taxonomy, lowest first
+var BloomLevels = []string{"remember", "understand", "apply", "analyze", "evaluate", "create"}
+
+// bloomCues are question phrasings that signal each level, in English and Indonesian.
+// Levels are checked from the highest down, so a question asking to "explain why a design
+// is the best choice" counts as evaluate rather than understand.
+var bloomCues = map[string][]string{
+	"create": {
+		"design", "propose", "construct", "formulate", "devise", "compose", "develop a plan",
+		"rancang", "susun", "usulkan", "buatlah",
+	},
+	"evaluate": {
+		"justify", "evaluate", "assess", "critique", "which is the best", "most appropriate",
+		"most effective", "would you recommend", "defend", "nilailah", "evaluasi", "paling tepat",
+		"paling efektif",
+	},
+	"analyze": {
+		"compare", "contrast", "difference between", "differ", "analyze", "analyse",
+		"relationship between", "distinguish", "categorize", "what is the cause", "infer",
+		"bandingkan", "perbedaan", "hubungan antara", "analisis", "bedakan",
+	},
+	"apply": {
+		"calculate", "compute", "solve", "apply", "use the", "given that", "given a", "if a",
+		"what would happen", "predict", "demonstrate", "estimate", "hitung", "tentukan",
+		"jika", "terapkan", "selesaikan",
+	},
+	"understand": {
+		"explain", "describe", "summarize", "why", "interpret", "paraphrase", "main idea",
+		"best describes", "what does", "mean", "classify", "example of",
+		"jelaskan", "mengapa", "uraikan", "maksud", "contoh dari",
+	},
+}
+
+// bloomCuePatterns holds the cues of each level compiled to whole-word patterns
+var bloomCuePatterns = func() map[string][]*regexp.Regexp {
+	patterns := make(map[string][]*regexp.Regexp)
+	for level, cues := range bloomCues {
+		for _, cue := range cues {
+			patterns[level] = append(patterns[level], regexp.MustCompile(`\b`+regexp.QuoteMeta(cue)+`\b`))
+		}
+	}
+	return patterns
+}()
+
+// NormalizeBloomLevel maps a level name, including common spellings and Indonesian names,
+// to one of BloomLevels. It returns an empty string for unknown levels.
+func NormalizeBloomLevel(level string) string {
+	level = strings.ToLower(strings.TrimSpace(level))
+	switch level {
+	case "remember", "remembering", "recall", "knowledge", "mengingat":
+		return "remember"
+	case "understand", "understanding", "comprehension", "memahami":
+		return "understand"
+	case "apply", "applying", "application", "menerapkan":
+		return "apply"
+	case "analyze", "analyse", "analyzing", "analysing", "analysis", "menganalisis":
+		return "analyze"
+	case "evaluate", "evaluating", "evaluation", "mengevaluasi":
+		return "evaluate"
+	case "create", "creating", "synthesis", "mencipta":
+		return "create"
+	default:
+		return ""
+	}
+}
+
+// bloomStatementPrefixes start questions that quote a statement from the source, such as
+// the rule-based cloze and true/false items. Only the prefix is their instruction.
+var bloomStatementPrefixes = []string{"complete the sentence:", "true or false:", "fill in the blank:"}
+
+// quotedPassagePattern matches passages quoted from the source in a question
+var quotedPassagePattern = regexp.MustCompile(`"[^"]*"|“[^”]*”`)
+
+// bloomInstruction returns the part of a question that tells what to do, leaving out
+// statements and passages quoted from the source. Their wording is the source's, so a
+// "why" or "compare" in them says nothing about what the question asks.
+func bloomInstruction(questionText string) string {
+	text := strings.ToLower(strings.TrimSpace(questionText))
+	for _, prefix := range bloomStatementPrefixes {
+		if strings.HasPrefix(text, prefix) {
+			return prefix
+		}
+	}
+	return quotedPassagePattern.ReplaceAllString(text, " ")
+}
+
+// ClassifyBloomLevel guesses the cognitive level of a question from the wording of its
+// instruction
+func ClassifyBloomLevel(questionText string) string {
+	text := bloomInstruction(questionText)
+
+	for i := len(BloomLevels) - 1; i > 0; i-- {
+		level := BloomLevels[i]
+		for _, pattern := range bloomCuePatterns[level] {
+			if pattern.MatchString(text) {
+				return level
+			}
+		}
+	}
+
+	return "remember"
+}
+
+// TagBloomLevels fills in the cognitive level of questions that have none and normalises
+// the ones that do. Questions of the rule-based generator ask to recall a statement of the
+// source, so they are tagged remember.
+func TagBloomLevels(questions []models.Question) {
+	for i := range questions {
+		q := &questions[i]
+		q.BloomLevel = NormalizeBloomLevel(q.BloomLevel)
+		if q.BloomLevel != "" {
+			continue
+		}
+		if isRecallQuestion(*q) {
+			q.BloomLevel = "remember"
+			continue
+		}
+
+		text := q.Text
+		if text == "" {
+			text = q.Question
+		}
+		q.BloomLevel = ClassifyBloomLevel(text)
+	}
+}
+
+// isRecallQuestion reports whether a question was written by the rule-based generator or
+// is a true/false or fill-in-the-blank item, which only test recall
+func isRecallQuestion(q models.Question) bool {
+	if q.Type == "true-false" || q.Type == "fill-blank" {
+		return true
+	}
+	source, _ := q.Metadata["source"].(string)
+	return strings.HasPrefix(source, "rule-based")
+}
+
+// NormalizeBloomMix validates a requested level mix and scales it so the shares add up
+// to 1. Shares may be given as fractions (0.4) or percentages (40).
+func NormalizeBloomMix(mix map[string]float64) (map[string]float64, error) {
+	if len(mix) == 0 {
+		return nil, nil
+	}
+
+	normalized := make(map[string]float64)
+	total := 0.0
+	for level, share := range mix {
+		name := NormalizeBloomLevel(level)
+		if name == "" {
+			return nil, fmt.Errorf("unknown cognitive level %q", level)
+		}
+		if share < 0 {
+			return nil, fmt.Errorf("share for %q must not be negative", level)
+		}
+		normalized[name] += share
+		total += share
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("level mix must have at least one positive share")
+	}
+
+	for level := range normalized {
+		normalized[level] /= total
+		if normalized[level] == 0 {
+			delete(normalized, level)
+		}
+	}
+	return normalized, nil
+}
+
+// BloomTargetCounts splits a question count across levels according to a normalised mix,
+// giving leftover questions to the levels with the largest remainders
+func BloomTargetCounts(mix map[string]float64, total int) map[string]int {
+	counts := make(map[string]int)
+	if len(mix) == 0 || total <= 0 {
+		return counts
+	}
+
+	type remainder struct {
+		level string
+		value float64
+	}
+	var remainders []remainder
+	assigned := 0
+	for _, level := range BloomLevels {
+		share, ok := mix[level]
+		if !ok {
+			continue
+		}
+		exact := share * float64(total)
+		counts[level] = int(math.Floor(exact))
+		assigned += counts[level]
+		remainders = append(remainders, remainder{level, exact - math.Floor(exact)})
+	}
+
+	sort.SliceStable(remainders, func(i, j int) bool { return remainders[i].value > remainders[j].value })
+	for i := 0; assigned < total && len(remainders) > 0; i++ {
+		counts[remainders[i%len(remainders)].level]++
+		assigned++
+	}
+
+	return counts
+}
+
+// bloomPromptInstruction describes the requested level mix for an LLM prompt
+func bloomPromptInstruction(mix map[string]float64, total int) string {
+	counts := BloomTargetCounts(mix, total)
+	if len(counts) == 0 {
+		return "- Tag each question with the Bloom's taxonomy level it targets"
+	}
+
+	var parts []string
+	for _, level := range BloomLevels {
+		if counts[level] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[level], level))
+		}
+	}
+	return "- Tag each question with the Bloom's taxonomy level it targets and write exactly " +
+		strings.Join(parts, ", ") + " questions"
+}
+
+// BloomMixShortfall lists the levels of a requested mix that got fewer questions than
+// asked for, e.g. "apply 1 of 4". Questions must be tagged. The rule-based generator,
+// used when no LLM answers, only writes recall questions, so it cannot meet a mix that
+// asks for higher levels.
+func BloomMixShortfall(mix map[string]float64, questions []models.Question) []string {
+	targets := BloomTargetCounts(mix, len(questions))
+	got := make(map[string]int)
+	for _, q := range questions {
+		got[NormalizeBloomLevel(q.BloomLevel)]++
+	}
+
+	var short []string
+	for _, level := range BloomLevels {
+		if got[level] < targets[level] {
+			short = append(short, fmt.Sprintf("%s %d of %d", level, got[level], targets[level]))
+		}
+	}
+	return short
+}
+
+// BuildBloomCoverage reports how a quiz's questions spread over the cognitive levels and
+// how often each level was answered correctly across the given attempts
+func BuildBloomCoverage(quiz *models.Quiz, attempts []map[string]string) *models.BloomCoverage {
+	coverage := &models.BloomCoverage{
+		QuizID:         quiz.ID,
+		TotalQuestions: len(quiz.Questions),
+	}
+
+	questions := make(map[string]int)
+	answers := make(map[string]int)
+	correct := make(map[string]int)
+
+	for _, q := range quiz.Questions {
+		level := NormalizeBloomLevel(q.BloomLevel)
+		if level == "" {
+			level = "untagged"
+		}
+		questions[level]++
+
+		correctText := ""
+		if q.CorrectAnswer >= 0 && q.CorrectAnswer < len(q.Options) {
+			correctText = q.Options[q.CorrectAnswer]
+		}
+		for _, attempt := range attempts {
+			answer, ok := attempt[q.ID]
+			if !ok {
+				continue
+			}
+			answers[level]++
+			if answer == correctText {
+				correct[level]++
+			}
+		}
+	}
+
+	levels := append(append([]string{}, BloomLevels...), "untagged")
+	for _, level := range levels {
+		target := quiz.BloomMix[level]
+		if questions[level] == 0 && target == 0 {
+			continue
+		}
+
+		stats := models.BloomLevelCoverage{
+			Level:       level,
+			Questions:   questions[level],
+			TargetShare: target,
+			Answers:     answers[level],
+		}
+		if coverage.TotalQuestions > 0 {
+			stats.Share = float64(questions[level]) / float64(coverage.TotalQuestions)
+		}
+		if answers[level] > 0 {
+			stats.CorrectRate = float64(correct[level]) / float64(answers[level])
+		}
+		coverage.Levels = append(coverage.Levels, stats)
+	}
+
+	return coverage
+}
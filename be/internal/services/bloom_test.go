@@ -0,0 +1,68 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestClassifyBloomLevel(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Explain why the cell membrane is selectively permeable.", "understand"},
+		{"Compare mitosis and meiosis.", "analyze"},
+		{"Design an experiment to test the effect of light on growth.", "create"},
+		{"What is the capital of France?", "remember"},
+		// Cues in a statement or passage from the source are not the question's
+		{"Complete the sentence: Students compare results to explain why ____ grows.", "remember"},
+		{"True or False: Scientists design experiments to evaluate theories.", "remember"},
+		{`What year is given in "Historians compare sources to explain why the war began"?`, "remember"},
+		{`Explain the passage “We must compare before we judge”.`, "understand"},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyBloomLevel(tt.text); got != tt.want {
+			t.Errorf("ClassifyBloomLevel(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTagBloomLevels(t *testing.T) {
+	questions := []models.Question{
+		{Type: "multiple-choice", Text: "Complete the sentence: Scientists analyze ____ data.",
+			Metadata: map[string]interface{}{"source": "rule-based-enhanced"}},
+		{Type: "multiple-choice", Text: "According to Table 1, why is the Mean for Group B higher?",
+			Metadata: map[string]interface{}{"source": "rule-based-table"}},
+		{Type: "true-false", Text: "Evaluate: the design is sound."},
+		{Type: "multiple-choice", Text: "Which treatment is the most appropriate?"},
+		{Type: "multiple-choice", Text: "Why do leaves fall?", BloomLevel: "Analysis"},
+	}
+	TagBloomLevels(questions)
+
+	want := []string{"remember", "remember", "remember", "evaluate", "analyze"}
+	for i, q := range questions {
+		if q.BloomLevel != want[i] {
+			t.Errorf("question %d: got %q, want %q", i, q.BloomLevel, want[i])
+		}
+	}
+}
+
+func TestBloomMixShortfall(t *testing.T) {
+	questions := make([]models.Question, 10)
+	for i := range questions {
+		questions[i].BloomLevel = "remember"
+	}
+	questions[0].BloomLevel = "apply"
+
+	mix := map[string]float64{"remember": 0.5, "apply": 0.3, "analyze": 0.2}
+	want := []string{"apply 1 of 3", "analyze 0 of 2"}
+	if got := BloomMixShortfall(mix, questions); !reflect.DeepEqual(got, want) {
+		t.Errorf("BloomMixShortfall = %v, want %v", got, want)
+	}
+
+	if got := BloomMixShortfall(nil, questions); got != nil {
+		t.Errorf("without a mix: got %v, want none", got)
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+// singleByteCharset is a legacy charset a text file without a byte order mark may be in,
+// with the non-ASCII letters common in the languages written in it
+type singleByteCharset struct {
+	name    string
+	charmap *charmap.Charmap
+	common  string
+}
+
+// singleByteCharsets are tried in order, so a charset wins ties with those after it. The
+// Windows code pages come before the ISO-8859 charsets they extend: text using neither's
+// extra characters decodes the same in both. ISO-8859-1 is left out as Windows-1252 reads
+// all of its text.
+var singleByteCharsets = []singleByteCharset{
+	{"windows-1252", charmap.Windows1252, latin1Letters},
+	{"ISO-8859-15", charmap.ISO8859_15, latin1Letters + "šžŠŽ"},
+	{"windows-1250", charmap.Windows1250, latin2Letters},
+	{"ISO-8859-2", charmap.ISO8859_2, latin2Letters},
+	{"windows-1251", charmap.Windows1251, cyrillicLetters},
+	{"ISO-8859-5", charmap.ISO8859_5, cyrillicLetters},
+	{"windows-1253", charmap.Windows1253, greekLetters},
+	{"ISO-8859-7", charmap.ISO8859_7, greekLetters},
+	{"windows-1254", charmap.Windows1254, turkishLetters},
+	{"ISO-8859-9", charmap.ISO8859_9, turkishLetters},
+	{"windows-1257", charmap.Windows1257, balticLetters},
+}
+
+// Non-ASCII letters common in the languages of each group of charsets, in both cases
+const (
+	latin1Letters   = "àáâãäåæçèéêëìíîïñòóôõöøùúûüýÿßœÀÁÂÃÄÅÆÇÈÉÊËÌÍÎÏÑÒÓÔÕÖØÙÚÛÜÝŒ"
+	latin2Letters   = "áäčćďéęěíĺľłńňóôöőŕřśšťúůüűýźżžąÁÄČĆĎÉĘĚÍĹĽŁŃŇÓÔÖŐŔŘŚŠŤÚŮÜŰÝŹŻŽĄ"
+	cyrillicLetters = "абвгдеёжзийклмнопрстуфхцчшщъыьэюяіїєґАБВГДЕЁЖЗИЙКЛМНОПРСТУФХЦЧШЩЪЫЬЭЮЯІЇЄҐ"
+	greekLetters    = "αβγδεζηθικλμνξοπρσςτυφχψωάέήίόύώϊϋΐΰΑΒΓΔΕΖΗΘΙΚΛΜΝΞΟΠΡΣΤΥΦΧΨΩΆΈΉΊΌΎΏ"
+	turkishLetters  = "çğıöşüâîûÇĞİÖŞÜÂÎÛ"
+	balticLetters   = "ąčęėįšųūžāēģīķļņõöüĄČĘĖĮŠŲŪŽĀĒĢĪĶĻŅÕÖÜ"
+)
+
+// DecodeText decodes a text file to UTF-8 and returns the charset it was in. A byte order
+// mark decides the charset; otherwise text is UTF-16 when every other byte is mostly NUL,
+// UTF-8 when it is valid UTF-8, and else the legacy charset its text reads best in.
+func DecodeText(content []byte) (string, string, error) {
+	name, enc := DetectCharset(content)
+	if enc == nil {
+		return strings.TrimPrefix(string(content), "\ufeff"), name, nil
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return "", name, fmt.Errorf("failed to decode text file as %s: %w", name, err)
+	}
+	return strings.TrimPrefix(string(decoded), "\ufeff"), name, nil
+}
+
+// DetectCharset returns the name of the charset of a text file and its encoding, which is
+// nil for UTF-8
+func DetectCharset(content []byte) (string, encoding.Encoding) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		return "UTF-8", nil
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		return "UTF-16LE", xunicode.UTF16(xunicode.LittleEndian, xunicode.ExpectBOM)
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		return "UTF-16BE", xunicode.UTF16(xunicode.BigEndian, xunicode.ExpectBOM)
+	}
+
+	// UTF-16 text in Latin script is valid UTF-8 too, with a NUL in every character
+	switch utf16Order(content) {
+	case "LE":
+		return "UTF-16LE", xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM)
+	case "BE":
+		return "UTF-16BE", xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM)
+	}
+	if utf8.Valid(content) {
+		return "UTF-8", nil
+	}
+
+	best, bestScore := singleByteCharsets[0], 0
+	for i, charset := range singleByteCharsets {
+		score := charsetScore(content, charset)
+		if i == 0 || score > bestScore {
+			best, bestScore = charset, score
+		}
+	}
+	return best.name, best.charmap
+}
+
+// utf16Order guesses the byte order of UTF-16 text without a byte order mark from where
+// its NUL bytes fall: text mostly in Latin script has a NUL in every character. It
+// returns "LE", "BE" or "" when the text does not look like UTF-16.
+func utf16Order(content []byte) string {
+	sample := content[:min(len(content), 8192)&^1]
+	if len(sample) < 4 {
+		return ""
+	}
+	var evenNUL, oddNUL int
+	for i := 0; i < len(sample); i += 2 {
+		if sample[i] == 0 {
+			evenNUL++
+		}
+		if sample[i+1] == 0 {
+			oddNUL++
+		}
+	}
+	pairs := len(sample) / 2
+	switch {
+	case oddNUL*10 >= pairs*3 && evenNUL*20 < pairs:
+		return "LE"
+	case evenNUL*10 >= pairs*3 && oddNUL*20 < pairs:
+		return "BE"
+	}
+	return ""
+}
+
+// charsetScore rates how well content reads in a charset. Its non-ASCII characters score
+// for being letters common in the charset's languages, as other letters are as likely to
+// be a symbol read in the wrong charset, and lose for being control
+// characters, symbols inside words, letters of another script in a word, or capitals in
+// the middle of lower-case words, which is what text decoded in the wrong charset has.
+// Latin letters lose a little for following another non-ASCII letter: Latin script
+// writes accents between plain letters, while Cyrillic or Greek text read in a Latin
+// charset is words of accented letters only.
+func charsetScore(content []byte, charset singleByteCharset) int {
+	score := 0
+	var prev, prevLetter rune
+	for _, b := range content {
+		r := charset.charmap.DecodeByte(b)
+		if b >= 0x80 {
+			switch {
+			case r == utf8.RuneError || unicode.IsControl(r):
+				score -= 10
+			case unicode.IsLetter(r):
+				if strings.ContainsRune(charset.common, r) {
+					score += 2
+				}
+				if prevLetter != 0 && scriptOf(prevLetter) != scriptOf(r) {
+					score -= 4
+				}
+				if unicode.IsUpper(r) && unicode.IsLower(prevLetter) {
+					score -= 2
+				}
+				if prevLetter > unicode.MaxASCII && scriptOf(r) == "Latin" {
+					score--
+				}
+			case unicode.IsLetter(prev):
+				// Symbols follow words, as in "100%" or "word»", but rarely sit inside them
+				score--
+			}
+		}
+
+		prev, prevLetter = r, 0
+		if unicode.IsLetter(r) {
+			prevLetter = r
+		}
+	}
+	return score
+}
+
+// scriptOf returns the script of a letter among those the legacy charsets write
+func scriptOf(r rune) string {
+	switch {
+	case unicode.Is(unicode.Cyrillic, r):
+		return "Cyrillic"
+	case unicode.Is(unicode.Greek, r):
+		return "Greek"
+	default:
+		return "Latin"
+	}
+}
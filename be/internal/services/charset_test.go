@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	xunicode "golang.org/x/text/encoding/unicode"
+)
+
+func TestDecodeText(t *testing.T) {
+	encode := func(enc encoding.Encoding, text string) []byte {
+		data, err := enc.NewEncoder().Bytes([]byte(text))
+		if err != nil {
+			t.Fatalf("failed to encode %q: %v", text, err)
+		}
+		return data
+	}
+	utf16LE := xunicode.UTF16(xunicode.LittleEndian, xunicode.IgnoreBOM)
+	utf16BE := xunicode.UTF16(xunicode.BigEndian, xunicode.IgnoreBOM)
+
+	tests := []struct {
+		name    string
+		content []byte
+		charset string
+		text    string
+	}{
+		{"empty", nil, "UTF-8", ""},
+		{"ASCII", []byte("Cells divide."), "UTF-8", "Cells divide."},
+		{"UTF-8", []byte("Größe der Zelle"), "UTF-8", "Größe der Zelle"},
+		{"UTF-8 with BOM", []byte("\ufeffCafé"), "UTF-8", "Café"},
+		{"UTF-16LE with BOM", append([]byte{0xFF, 0xFE}, encode(utf16LE, "Zellteilung")...), "UTF-16LE", "Zellteilung"},
+		{"UTF-16BE with BOM", append([]byte{0xFE, 0xFF}, encode(utf16BE, "Zellteilung")...), "UTF-16BE", "Zellteilung"},
+		{"UTF-16LE without BOM", encode(utf16LE, "Cells divide by mitosis."), "UTF-16LE", "Cells divide by mitosis."},
+		{"UTF-16BE without BOM", encode(utf16BE, "Cells divide by mitosis."), "UTF-16BE", "Cells divide by mitosis."},
+		{"windows-1252", encode(charmap.Windows1252, "Le cœur pompe le sang à travers les vaisseaux."), "windows-1252", "Le cœur pompe le sang à travers les vaisseaux."},
+		{"windows-1250", encode(charmap.Windows1250, "Příliš žluťoučký kůň úpěl ďábelské ódy."), "windows-1250", "Příliš žluťoučký kůň úpěl ďábelské ódy."},
+		{"windows-1251", encode(charmap.Windows1251, "Клетка делится путём митоза."), "windows-1251", "Клетка делится путём митоза."},
+		{"windows-1253", encode(charmap.Windows1253, "Το κύτταρο διαιρείται με μίτωση."), "windows-1253", "Το κύτταρο διαιρείται με μίτωση."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, charset, err := DecodeText(tt.content)
+			if err != nil {
+				t.Fatalf("DecodeText: %v", err)
+			}
+			if charset != tt.charset || text != tt.text {
+				t.Errorf("DecodeText = %q in %s, want %q in %s", text, charset, tt.text, tt.charset)
+			}
+		})
+	}
+}
+
+func TestUTF16OrderShortInput(t *testing.T) {
+	for _, content := range [][]byte{nil, {0}, {'a', 0, 'b'}} {
+		if order := utf16Order(content); order != "" {
+			t.Errorf("utf16Order(%q) = %q, want none", content, order)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// answerKind is the semantic class of an answer; distractors must share it
+type answerKind int
+
+const (
+	kindTerm answerKind = iota
+	kindName
+	kindNumber
+	kindPercent
+	kindYear
+)
+
+var (
+	distractorNumberPattern = regexp.MustCompile(`\b\d{1,3}(?:,\d{3})+(?:\.\d+)?%?|\b\d{1,3}(?:\.\d{3})+(?:,\d+)?%?|\b\d+(?:[.,]\d+)?%?`)
+	distractorNamePattern   = regexp.MustCompile(`\b\p{Lu}[\p{Ll}\p{Lu}]+(?:\s+\p{Lu}[\p{Ll}\p{Lu}]+)*`)
+	distractorYearPattern   = regexp.MustCompile(`^(1[5-9]|20)\d{2}$`)
+	distractorSentenceBreak = regexp.MustCompile(`[.!?]+\s+|\n{2,}`)
+)
+
+// DistractorEngine draws wrong answers for a question from the document itself. Candidates
+// must be of the same kind as the correct answer and are ranked by how plausible they are:
+// similar length and word count, and appearing in the same sentences as the answer.
+type DistractorEngine struct {
+	candidates []distractorCandidate
+	sentences  []string
+	stemmer    Stemmer
+}
+
+type distractorCandidate struct {
+	text      string
+	lower     string
+	kind      answerKind
+	words     int
+	frequency int
+	sentences map[int]bool
+}
+
+// NewDistractorEngine collects distractor candidates from the content: the ranked keywords
+// and phrases, capitalised names and the numbers that appear in it
+func NewDistractorEngine(content string, keywords []string) *DistractorEngine {
+	engine := &DistractorEngine{
+		stemmer: stemmerFor(DetectLanguage(content)),
+	}
+	for _, sentence := range splitDistractorSentences(content) {
+		engine.sentences = append(engine.sentences, strings.ToLower(sentence))
+	}
+
+	seen := make(map[string]bool)
+	add := func(text string, kind answerKind) {
+		text = strings.TrimSpace(text)
+		lower := strings.ToLower(text)
+		if text == "" || seen[lower] {
+			return
+		}
+		seen[lower] = true
+
+		candidate := distractorCandidate{
+			text:      text,
+			lower:     lower,
+			kind:      kind,
+			words:     len(strings.Fields(text)),
+			sentences: engine.sentencesContaining(lower),
+		}
+		candidate.frequency = len(candidate.sentences)
+		if candidate.frequency > 0 {
+			engine.candidates = append(engine.candidates, candidate)
+		}
+	}
+
+	for _, name := range extractNames(content) {
+		add(name, kindName)
+	}
+	for _, keyword := range keywords {
+		add(keyword, classifyAnswer(keyword))
+	}
+	for _, number := range distractorNumberPattern.FindAllString(content, -1) {
+		add(number, classifyAnswer(number))
+	}
+
+	return engine
+}
+
+// Distractors returns up to count wrong answers for the given correct answer, best first.
+// Candidates already mentioned in the question are skipped. Fewer are returned when the
+// document has no more plausible candidates; callers should then pick another answer
+// rather than pad the options.
+func (e *DistractorEngine) Distractors(answer, question string, count int) []string {
+	answer = strings.TrimSpace(answer)
+	if answer == "" || count <= 0 {
+		return nil
+	}
+
+	kind := classifyAnswer(answer)
+	answerLower := strings.ToLower(answer)
+	answerSentences := e.sentencesContaining(answerLower)
+	answerWords := len(strings.Fields(answer))
+	questionLower := strings.ToLower(question)
+
+	type scored struct {
+		text  string
+		score float64
+	}
+	var ranked []scored
+	for _, c := range e.candidates {
+		if c.kind != kind || e.sameAnswer(answerLower, c.lower) {
+			continue
+		}
+		if pattern, err := wholeWordPattern(c.lower); err == nil && pattern.MatchString(questionLower) {
+			continue
+		}
+		ranked = append(ranked, scored{text: c.text, score: e.plausibility(c, answer, answerWords, answerSentences)})
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].text < ranked[j].text
+	})
+
+	var result []string
+	used := map[string]bool{answerLower: true}
+	for _, r := range ranked {
+		if len(result) >= count {
+			break
+		}
+		lower := strings.ToLower(r.text)
+		if used[lower] || overlapsTerm(r.text, result) {
+			continue
+		}
+		used[lower] = true
+		result = append(result, matchCase(r.text, answer, kind))
+	}
+
+	// Numbers that the document does not offer are derived from the answer itself
+	if len(result) < count && (kind == kindNumber || kind == kindPercent || kind == kindYear) {
+		for _, variant := range numericVariants(answer, kind) {
+			if len(result) >= count {
+				break
+			}
+			if !used[variant] {
+				used[variant] = true
+				result = append(result, variant)
+			}
+		}
+	}
+
+	return result
+}
+
+// plausibility scores a candidate against the correct answer
+func (e *DistractorEngine) plausibility(c distractorCandidate, answer string, answerWords int, answerSentences map[int]bool) float64 {
+	// Similar length
+	a, b := float64(len([]rune(answer))), float64(len([]rune(c.text)))
+	lengthScore := 1 - math.Abs(a-b)/math.Max(a, b)
+
+	// Same number of words
+	wordScore := 0.0
+	if c.words == answerWords {
+		wordScore = 1
+	}
+
+	// Co-occurrence: candidates discussed alongside the answer are the ones a student
+	// could mix up with it
+	shared := 0
+	for idx := range c.sentences {
+		if answerSentences[idx] {
+			shared++
+		}
+	}
+	coScore := 0.0
+	if len(answerSentences) > 0 {
+		coScore = float64(shared) / float64(len(answerSentences))
+	}
+	// Share a paragraph-sized neighbourhood even without a common sentence
+	if shared == 0 {
+		for idx := range c.sentences {
+			if answerSentences[idx-1] || answerSentences[idx+1] {
+				coScore = 0.25
+				break
+			}
+		}
+	}
+
+	return 2*lengthScore + wordScore + 2*coScore + 0.25*math.Log1p(float64(c.frequency))
+}
+
+// sameAnswer reports whether a candidate would also be correct: the same word, a
+// variant of it, or a phrase containing it
+func (e *DistractorEngine) sameAnswer(answer, candidate string) bool {
+	if answer == candidate || strings.Contains(answer, candidate) || strings.Contains(candidate, answer) {
+		return true
+	}
+	if e.stemmer == nil {
+		return false
+	}
+
+	answerWords, candidateWords := strings.Fields(answer), strings.Fields(candidate)
+	if len(answerWords) != len(candidateWords) {
+		return false
+	}
+	for i := range answerWords {
+		if e.stemmer.Stem(answerWords[i]) != e.stemmer.Stem(candidateWords[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *DistractorEngine) sentencesContaining(lower string) map[int]bool {
+	pattern, err := wholeWordPattern(lower)
+	if err != nil {
+		return nil
+	}
+
+	found := make(map[int]bool)
+	for i, sentence := range e.sentences {
+		if pattern.MatchString(sentence) {
+			found[i] = true
+		}
+	}
+	return found
+}
+
+// wholeWordPattern matches text only where it is not part of a longer word. Word
+// boundaries are only required next to letters and digits, so "25%" still matches.
+func wholeWordPattern(text string) (*regexp.Regexp, error) {
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' }
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return regexp.Compile(`$^`)
+	}
+
+	expr := regexp.QuoteMeta(text)
+	if isWord(runes[0]) {
+		expr = `\b` + expr
+	}
+	if isWord(runes[len(runes)-1]) {
+		expr += `\b`
+	}
+	return regexp.Compile(`(?i)` + expr)
+}
+
+// classifyAnswer decides which kind of distractor an answer needs
+func classifyAnswer(answer string) answerKind {
+	answer = strings.TrimSpace(answer)
+	if distractorYearPattern.MatchString(answer) {
+		return kindYear
+	}
+	if distractorNumberPattern.FindString(answer) == answer && answer != "" {
+		if strings.HasSuffix(answer, "%") {
+			return kindPercent
+		}
+		return kindNumber
+	}
+
+	words := strings.Fields(answer)
+	capitalised := 0
+	for _, word := range words {
+		for _, r := range word {
+			if unicode.IsUpper(r) {
+				capitalised++
+			}
+			break
+		}
+	}
+	if len(words) > 0 && capitalised == len(words) {
+		return kindName
+	}
+	return kindTerm
+}
+
+// extractNames returns capitalised words and word sequences. Sentence-initial words only
+// count when the same word is also capitalised in the middle of a sentence.
+func extractNames(content string) []string {
+	midSentence := make(map[string]bool)
+	var initial []string
+
+	for _, sentence := range splitDistractorSentences(content) {
+		for _, loc := range distractorNamePattern.FindAllStringIndex(sentence, -1) {
+			name := sentence[loc[0]:loc[1]]
+			if strings.TrimSpace(sentence[:loc[0]]) == "" {
+				initial = append(initial, name)
+				continue
+			}
+			midSentence[name] = true
+		}
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for name := range midSentence {
+		names = append(names, name)
+		seen[name] = true
+	}
+	for _, name := range initial {
+		// "The Turing Test ..." -> "Turing Test"
+		words := strings.Fields(name)
+		for len(words) > 0 && !midSentence[words[0]] && !midSentence[strings.Join(words, " ")] {
+			words = words[1:]
+		}
+		if trimmed := strings.Join(words, " "); trimmed != "" && !seen[trimmed] {
+			names = append(names, trimmed)
+			seen[trimmed] = true
+		}
+	}
+
+	// Map iteration order is random; keep candidates deterministic
+	sort.Strings(names)
+	return names
+}
+
+// numberFormat is how a number is written: its decimal and grouping separators and the
+// number of decimals
+type numberFormat struct {
+	decimal  string
+	group    string
+	decimals int
+}
+
+// parseNumber reads a number written with a decimal point or comma and optionally grouped
+// in thousands, e.g. "1,000", "1.234,5" or "0,75". A single comma followed by three digits
+// groups thousands; a single point followed by three digits is a decimal point.
+func parseNumber(raw string) (float64, numberFormat, bool) {
+	format := numberFormat{decimal: "."}
+	commas, points := strings.Count(raw, ","), strings.Count(raw, ".")
+	switch {
+	case commas > 0 && points > 0:
+		// The separator that comes last is the decimal one
+		if strings.LastIndex(raw, ",") > strings.LastIndex(raw, ".") {
+			format.decimal, format.group = ",", "."
+		} else {
+			format.group = ","
+		}
+	case commas > 1 || (commas == 1 && len(raw)-strings.Index(raw, ",") == 4):
+		format.group = ","
+	case points > 1:
+		format.decimal, format.group = ",", "."
+	case commas == 1:
+		format.decimal = ","
+	}
+
+	digits := raw
+	if format.group != "" {
+		digits = strings.ReplaceAll(digits, format.group, "")
+	}
+	if i := strings.Index(digits, format.decimal); i >= 0 {
+		format.decimals = len(digits) - i - 1
+		digits = digits[:i] + "." + digits[i+1:]
+	}
+	value, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, format, false
+	}
+	return value, format, true
+}
+
+// formatNumber writes a value the way a number of the given format was written
+func formatNumber(value float64, format numberFormat) string {
+	text := strconv.FormatFloat(value, 'f', format.decimals, 64)
+	whole, fraction, hasFraction := strings.Cut(text, ".")
+
+	if format.group != "" {
+		var grouped strings.Builder
+		for i, digit := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				grouped.WriteString(format.group)
+			}
+			grouped.WriteRune(digit)
+		}
+		whole = grouped.String()
+	}
+	if hasFraction {
+		return whole + format.decimal + fraction
+	}
+	return whole
+}
+
+// numericVariants derives nearby values of the same magnitude and format as a number
+func numericVariants(answer string, kind answerKind) []string {
+	percent := strings.HasSuffix(answer, "%")
+	value, format, ok := parseNumber(strings.TrimSuffix(answer, "%"))
+	if !ok {
+		return nil
+	}
+	decimals := format.decimals
+
+	var steps []float64
+	switch {
+	case kind == kindYear:
+		steps = []float64{-10, 10, -5, 5, -20, 20}
+	case value == 0:
+		steps = []float64{1, 2, 5, 10}
+	default:
+		step := math.Pow(10, math.Floor(math.Log10(math.Abs(value))))
+		if decimals == 0 && step < 1 {
+			step = 1
+		}
+		steps = []float64{-step, step, 2 * step, -2 * step, step / 2, -step / 2}
+	}
+
+	var variants []string
+	seen := map[string]bool{answer: true}
+	for _, step := range steps {
+		v := value + step
+		if v < 0 || (percent && v > 100) || (decimals == 0 && v != math.Trunc(v)) {
+			continue
+		}
+		text := formatNumber(v, format)
+		if percent {
+			text += "%"
+		}
+		if !seen[text] {
+			seen[text] = true
+			variants = append(variants, text)
+		}
+	}
+	return variants
+}
+
+// matchCase lowercases a term distractor when the answer is written in lower case, so the
+// capitalisation of the options does not give the answer away
+func matchCase(candidate, answer string, kind answerKind) string {
+	if kind != kindTerm || answer != strings.ToLower(answer) {
+		return candidate
+	}
+	return strings.ToLower(candidate)
+}
+
+func splitDistractorSentences(content string) []string {
+	return distractorSentenceBreak.Split(content, -1)
+}
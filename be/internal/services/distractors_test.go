@@ -0,0 +1,104 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		raw   string
+		value float64
+		text  string // the value written back in the same format
+	}{
+		{"42", 42, "42"},
+		{"3.14", 3.14, "3.14"},
+		{"0,75", 0.75, "0,75"},
+		{"1,000", 1000, "1,000"},
+		{"1,000,000", 1000000, "1,000,000"},
+		{"1,234.5", 1234.5, "1,234.5"},
+		{"1.234,5", 1234.5, "1.234,5"},
+		{"12.500.000", 12500000, "12.500.000"},
+		{"2.125", 2.125, "2.125"},
+	}
+
+	for _, tt := range tests {
+		value, format, ok := parseNumber(tt.raw)
+		if !ok || value != tt.value {
+			t.Errorf("parseNumber(%q) = %v, %v, want %v", tt.raw, value, ok, tt.value)
+			continue
+		}
+		if text := formatNumber(value, format); text != tt.text {
+			t.Errorf("formatNumber of %q = %q, want %q", tt.raw, text, tt.text)
+		}
+	}
+}
+
+func TestNumericVariants(t *testing.T) {
+	tests := []struct {
+		answer string
+		kind   answerKind
+		want   []string
+	}{
+		{"1,000", kindNumber, []string{"0", "2,000", "3,000", "1,500", "500"}},
+		{"12,500", kindNumber, []string{"2,500", "22,500", "32,500", "17,500", "7,500"}},
+		{"1.234,5", kindNumber, []string{"234,5", "2.234,5", "3.234,5", "1.734,5", "734,5"}},
+		{"40%", kindPercent, []string{"30%", "50%", "60%", "20%", "45%", "35%"}},
+		{"1990", kindYear, []string{"1980", "2000", "1985", "1995", "1970", "2010"}},
+	}
+
+	for _, tt := range tests {
+		if got := numericVariants(tt.answer, tt.kind); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("numericVariants(%q) = %v, want %v", tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestDistractorNumberPattern(t *testing.T) {
+	sentence := "The city grew from 1,000,000 people in 1990 to 1.250.000,5 and 3.5% more."
+	want := []string{"1,000,000", "1990", "1.250.000,5", "3.5%"}
+	if got := distractorNumberPattern.FindAllString(sentence, -1); !reflect.DeepEqual(got, want) {
+		t.Errorf("numbers = %v, want %v", got, want)
+	}
+}
+
+func TestLowercaseFirst(t *testing.T) {
+	tests := map[string]string{
+		"Photosynthesis": "photosynthesis",
+		"Ökosystem":      "ökosystem",
+		"Фотосинтез":     "фотосинтез",
+		"Élan vital":     "élan vital",
+		"":               "",
+	}
+
+	for term, want := range tests {
+		if got := lowercaseFirst(term); got != want {
+			t.Errorf("lowercaseFirst(%q) = %q, want %q", term, got, want)
+		}
+	}
+}
+
+func TestMultipleChoiceKeepsCharactersWhole(t *testing.T) {
+	ai := NewAIService("")
+	// Long words without spaces, so trimming cannot fall back to a space
+	before := "Die " + strings.Repeat("Größenänderungsüberwachung", 5) + " "
+	after := " " + strings.Repeat("Lichtabhängigkeit", 10)
+	sentence := before + "Photosynthese" + after + "."
+
+	content := sentence + " Pflanzen nutzen Atmung. Pflanzen nutzen Osmose. Pflanzen nutzen Diffusion."
+	terms := []string{"Photosynthese", "Atmung", "Osmose", "Diffusion"}
+	distractors := NewDistractorEngine(content, terms)
+
+	question, ok := ai.generateMultipleChoiceFromSentence(sentence, terms[:1], nil, distractors)
+	if !ok {
+		t.Fatal("no question generated")
+	}
+	if !utf8.ValidString(question.Text) {
+		t.Errorf("question text is not valid UTF-8: %q", question.Text)
+	}
+	if !strings.Contains(question.Text, "____") || !strings.HasSuffix(question.Text, "...") {
+		t.Errorf("question text was not trimmed around the blank: %q", question.Text)
+	}
+}
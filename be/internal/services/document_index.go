@@ -0,0 +1,233 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"pbkk-quizlit-backend/internal/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// passageSize is the number of characters of a page indexed as one passage
+	passageSize = 800
+	// embeddingBatchSize is the number of passages embedded per provider call
+	embeddingBatchSize = 100
+
+	// BM25 parameters
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Retrieval methods for DocumentIndex.Search
+const (
+	RetrievalBM25       = "bm25"
+	RetrievalEmbeddings = "embeddings"
+)
+
+// DocumentIndex finds the passages of a document that are relevant to a question. Passages
+// are ranked with BM25 over stemmed, stop-word-free terms, or by cosine similarity of
+// embeddings from the configured provider. Embeddings are computed on first use.
+type DocumentIndex struct {
+	passages  []indexedPassage
+	docFreq   map[string]int
+	avgLength float64
+	stemmer   Stemmer
+
+	embedMu           sync.Mutex
+	embeddingProvider string
+}
+
+type indexedPassage struct {
+	page      int
+	label     string
+	text      string
+	terms     map[string]int
+	length    int
+	embedding []float32
+}
+
+// NewDocumentIndex splits the pages of a document into passages and indexes their terms
+func NewDocumentIndex(pages []models.DocumentPage) *DocumentIndex {
+	idx := &DocumentIndex{
+		docFreq: make(map[string]int),
+		stemmer: stemmerFor(DetectLanguage(JoinPages(pages))),
+	}
+
+	totalLength := 0
+	for _, page := range pages {
+		for _, chunk := range chunkPages([]models.DocumentPage{page}, passageSize) {
+			text := PlainText(chunk.Text, true)
+			passage := indexedPassage{
+				page:  page.Number,
+				label: page.Label,
+				text:  strings.Join(strings.Fields(text), " "),
+				terms: make(map[string]int),
+			}
+			for _, term := range idx.terms(text) {
+				passage.terms[term]++
+				passage.length++
+			}
+			for term := range passage.terms {
+				idx.docFreq[term]++
+			}
+			totalLength += passage.length
+			idx.passages = append(idx.passages, passage)
+		}
+	}
+
+	if len(idx.passages) > 0 {
+		idx.avgLength = float64(totalLength) / float64(len(idx.passages))
+	}
+	return idx
+}
+
+// Search returns up to k passages relevant to the query, best first. Embedding search
+// falls back to BM25 when no embedding provider is available; the method actually used is
+// returned alongside the passages.
+func (idx *DocumentIndex) Search(ai *AIService, query, method string, k int) ([]models.TutorCitation, string) {
+	if method == RetrievalEmbeddings {
+		results, err := idx.searchEmbeddings(ai, query, k)
+		if err == nil {
+			return results, RetrievalEmbeddings
+		}
+		ai.logger.Warnf("Embedding search failed: %v, using BM25", err)
+	}
+	return idx.searchBM25(query, k), RetrievalBM25
+}
+
+// searchBM25 ranks passages by the Okapi BM25 score of the query terms
+func (idx *DocumentIndex) searchBM25(query string, k int) []models.TutorCitation {
+	queryTerms := make(map[string]bool)
+	for _, term := range idx.terms(query) {
+		queryTerms[term] = true
+	}
+
+	n := float64(len(idx.passages))
+	scores := make([]float64, len(idx.passages))
+	for term := range queryTerms {
+		df := float64(idx.docFreq[term])
+		if df == 0 {
+			continue
+		}
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for i, passage := range idx.passages {
+			tf := float64(passage.terms[term])
+			if tf == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(passage.length)/idx.avgLength
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+
+	return idx.topPassages(scores, k)
+}
+
+// searchEmbeddings ranks passages by cosine similarity to the query embedding
+func (idx *DocumentIndex) searchEmbeddings(ai *AIService, query string, k int) ([]models.TutorCitation, error) {
+	provider, err := idx.ensureEmbeddings(ai)
+	if err != nil {
+		return nil, err
+	}
+
+	vectors, err := ai.embedWith(provider, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed question: %w", err)
+	}
+
+	scores := make([]float64, len(idx.passages))
+	for i, passage := range idx.passages {
+		scores[i] = cosineSimilarity(vectors[0], passage.embedding)
+	}
+	return idx.topPassages(scores, k), nil
+}
+
+// ensureEmbeddings embeds every passage once and remembers which provider did it
+func (idx *DocumentIndex) ensureEmbeddings(ai *AIService) (string, error) {
+	idx.embedMu.Lock()
+	defer idx.embedMu.Unlock()
+
+	if idx.embeddingProvider != "" || len(idx.passages) == 0 {
+		return idx.embeddingProvider, nil
+	}
+
+	texts := make([]string, len(idx.passages))
+	for i, passage := range idx.passages {
+		texts[i] = passage.text
+	}
+
+	vectors, provider, err := ai.embedTexts(texts[:min(embeddingBatchSize, len(texts))])
+	if err != nil {
+		return "", err
+	}
+	for start := embeddingBatchSize; start < len(texts); start += embeddingBatchSize {
+		batch, err := ai.embedWith(provider, texts[start:min(start+embeddingBatchSize, len(texts))])
+		if err != nil {
+			return "", err
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	for i := range idx.passages {
+		idx.passages[i].embedding = vectors[i]
+	}
+	idx.embeddingProvider = provider
+	return provider, nil
+}
+
+// topPassages returns the k best-scoring passages that have a positive score
+func (idx *DocumentIndex) topPassages(scores []float64, k int) []models.TutorCitation {
+	order := make([]int, 0, len(scores))
+	for i, score := range scores {
+		if score > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	if len(order) > k {
+		order = order[:k]
+	}
+
+	results := make([]models.TutorCitation, 0, len(order))
+	for _, i := range order {
+		results = append(results, models.TutorCitation{
+			Page:    idx.passages[i].page,
+			Label:   idx.passages[i].label,
+			Excerpt: idx.passages[i].text,
+			Score:   math.Round(scores[i]*1000) / 1000,
+		})
+	}
+	return results
+}
+
+// terms returns the stemmed content words of a text
+func (idx *DocumentIndex) terms(text string) []string {
+	var terms []string
+	for _, segment := range tokenizeKeywordSegments(text, idx.stemmer) {
+		for _, token := range segment {
+			if !token.stop && len([]rune(token.key)) > 1 {
+				terms = append(terms, token.key)
+			}
+		}
+	}
+	return terms
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/repository"
+	"pbkk-quizlit-backend/internal/storage"
+	"strings"
+	"sync"
+	"time"
+)
+
+// corpusDocumentLimit is the number of recent documents a keyword corpus is built from
+const corpusDocumentLimit = 200
+
+type DocumentService struct {
+	repo  *repository.DocumentRepository
+	store storage.BlobStore
+	links *FileLinks
+	tutor *TutorService
+
+	// Keyword corpora per user and options, dropped when the user uploads a document
+	corpusMu sync.Mutex
+	corpora  map[string]*models.KeywordCorpus
+}
+
+// NewDocumentService creates a document service. The search indexes of tutor are dropped
+// when a document changes; tutor may be nil.
+func NewDocumentService(store storage.BlobStore, links *FileLinks, tutor *TutorService) *DocumentService {
+	return &DocumentService{
+		repo:    repository.NewDocumentRepository(),
+		store:   store,
+		links:   links,
+		tutor:   tutor,
+		corpora: make(map[string]*models.KeywordCorpus),
+	}
+}
+
+// FileHash returns the hex SHA-256 a document file is identified by
+func FileHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// FindUpload returns the user's document for a file they uploaded before, or nil
+func (ds *DocumentService) FindUpload(userID, hash string) (*models.Document, error) {
+	ctx := context.Background()
+	id, err := ds.repo.FindDocumentByHash(ctx, userID, hash)
+	if err != nil || id == "" {
+		return nil, err
+	}
+	return ds.repo.GetDocument(ctx, id)
+}
+
+// CreateDocument stores an uploaded file in blob storage together with the text and
+// metadata extracted from it. doc must have its Filename, Pages and file fields set,
+// including the Size and SHA256 of the file read from file.
+func (ds *DocumentService) CreateDocument(doc *models.Document, file io.Reader, userID string) error {
+	ctx := context.Background()
+
+	doc.Content = JoinPages(doc.Pages)
+	doc.CreatedAt = time.Now()
+
+	if ds.store != nil {
+		doc.StorageKey = fmt.Sprintf("documents/%s/%s%s", userID, doc.SHA256, strings.ToLower(filepath.Ext(doc.Filename)))
+		if _, err := ds.store.Put(ctx, doc.StorageKey, file); err != nil {
+			return fmt.Errorf("failed to store file: %w", err)
+		}
+	}
+
+	if err := ds.repo.CreateDocument(ctx, doc, userID); err != nil {
+		// Keys are per user and hash, so the file may belong to a concurrent upload of it
+		if existing, _ := ds.repo.FindDocumentByHash(ctx, userID, doc.SHA256); doc.StorageKey != "" && existing == "" {
+			ds.store.Delete(ctx, doc.StorageKey)
+		}
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+
+	ds.invalidateCorpus(userID)
+
+	return nil
+}
+
+// ReplaceText stores text extracted again from the file of a stored document, keeping
+// the document, its file and the quizzes generated from it
+func (ds *DocumentService) ReplaceText(doc *models.Document, pages []models.DocumentPage, metadata map[string]string) error {
+	ctx := context.Background()
+
+	updated := *doc
+	updated.Pages = pages
+	updated.Content = JoinPages(pages)
+	updated.Metadata = metadata
+	if err := ds.repo.UpdateDocumentText(ctx, &updated); err != nil {
+		return err
+	}
+	*doc = updated
+
+	ds.invalidateCorpus(doc.UserID)
+	ds.invalidateIndex(doc.ID)
+
+	return nil
+}
+
+func (ds *DocumentService) ListDocuments(userID string) ([]*models.Document, error) {
+	ctx := context.Background()
+	return ds.repo.ListDocuments(ctx, userID)
+}
+
+// OpenFile opens the original upload of a document. The caller must close it.
+func (ds *DocumentService) OpenFile(doc *models.Document) (io.ReadCloser, error) {
+	if !doc.HasFile() || ds.store == nil {
+		return nil, storage.ErrNotFound
+	}
+
+	ctx := context.Background()
+	return ds.store.Get(ctx, doc.StorageKey)
+}
+
+// DownloadLink returns a link to the original upload of a document that works without
+// authentication until it expires
+func (ds *DocumentService) DownloadLink(doc *models.Document) (*models.FileLink, error) {
+	if !doc.HasFile() || ds.store == nil || ds.links == nil {
+		return nil, storage.ErrNotFound
+	}
+	return ds.links.Link(doc.StorageKey, doc.Filename, doc.ContentType), nil
+}
+
+// DeleteDocument deletes a document and its stored file
+func (ds *DocumentService) DeleteDocument(doc *models.Document) error {
+	ctx := context.Background()
+	if err := ds.repo.DeleteDocument(ctx, doc.ID); err != nil {
+		return err
+	}
+
+	ds.invalidateCorpus(doc.UserID)
+	ds.invalidateIndex(doc.ID)
+
+	if doc.HasFile() && ds.store != nil {
+		if err := ds.store.Delete(ctx, doc.StorageKey); err != nil {
+			return fmt.Errorf("document deleted but its file was not: %w", err)
+		}
+	}
+	return nil
+}
+
+func (ds *DocumentService) GetDocument(id string) (*models.Document, error) {
+	ctx := context.Background()
+	return ds.repo.GetDocument(ctx, id)
+}
+
+func (ds *DocumentService) SaveGlossary(id string, glossary []models.GlossaryTerm) error {
+	ctx := context.Background()
+	return ds.repo.SaveGlossary(ctx, id, glossary)
+}
+
+func (ds *DocumentService) SaveNotes(id string, notes *models.StudyNotes) error {
+	ctx := context.Background()
+	return ds.repo.SaveNotes(ctx, id, notes)
+}
+
+// GetKeywordCorpus returns document frequencies over the user's own documents, used as
+// the IDF reference when ranking keywords. Corpora are cached until the next upload.
+func (ds *DocumentService) GetKeywordCorpus(userID string, opts KeywordOptions) (*models.KeywordCorpus, error) {
+	key := fmt.Sprintf("%s|%s|%t|%d", userID, opts.Language, opts.Stemming, opts.MaxNGram)
+
+	ds.corpusMu.Lock()
+	corpus, ok := ds.corpora[key]
+	ds.corpusMu.Unlock()
+	if ok {
+		return corpus, nil
+	}
+
+	ctx := context.Background()
+	contents, err := ds.repo.ListDocumentContents(ctx, userID, corpusDocumentLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load documents: %w", err)
+	}
+
+	corpus = BuildKeywordCorpus(contents, opts)
+
+	ds.corpusMu.Lock()
+	ds.corpora[key] = corpus
+	ds.corpusMu.Unlock()
+
+	return corpus, nil
+}
+
+func (ds *DocumentService) invalidateCorpus(userID string) {
+	prefix := userID + "|"
+
+	ds.corpusMu.Lock()
+	defer ds.corpusMu.Unlock()
+	for key := range ds.corpora {
+		if strings.HasPrefix(key, prefix) {
+			delete(ds.corpora, key)
+		}
+	}
+}
+
+func (ds *DocumentService) invalidateIndex(docID string) {
+	if ds.tutor != nil {
+		ds.tutor.Invalidate(docID)
+	}
+}
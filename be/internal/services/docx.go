@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// headingStylePattern matches the names Word gives its built-in heading styles
+var headingStylePattern = regexp.MustCompile(`(?i)^heading\s*([1-9])$`)
+
+// docxParagraph collects the text and properties of a <w:p> while it is read
+type docxParagraph struct {
+	text       strings.Builder
+	style      string
+	outline    int // outline level + 1 from the paragraph itself, 0 for none
+	numID      string
+	level      int
+	breakAfter bool
+}
+
+// docxTable collects the rows of a <w:tbl> while it is read
+type docxTable struct {
+	rows [][]string
+	row  []string
+	cell []string
+}
+
+// docxReader turns the body of a Word document into pages of structured text: headings
+// become "#" lines, list items "-" or "1." lines indented by level, and tables rows of
+// cells separated by "|". Pages end at page breaks, both explicit ones and the ones Word
+// recorded when the file was last saved.
+type docxReader struct {
+	headings  map[string]int            // style ID -> heading level
+	numFormat map[string]map[int]string // numbering ID -> level -> number format
+	counters  map[string][]int          // numbering ID -> item count per level
+
+	paragraphs []*docxParagraph
+	tables     []*docxTable
+
+	pages   []models.DocumentPage
+	blocks  []textBlock
+	pageNum int
+}
+
+// processDOCXFile extracts the text of a Word document page by page
+func (fs *FileService) processDOCXFile(content []byte) ([]models.DocumentPage, error) {
+	archive, err := openZipArchive(content, fs.limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX file: %w", err)
+	}
+
+	body, err := readZipPart(archive, "word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DOCX document: %w", err)
+	}
+
+	r := &docxReader{
+		headings:  make(map[string]int),
+		numFormat: make(map[string]map[int]string),
+		counters:  make(map[string][]int),
+		pageNum:   1,
+	}
+	// Styles and numbering only refine the output, so documents without them still load
+	if styles, err := readZipPart(archive, "word/styles.xml"); err == nil {
+		r.readStyles(styles)
+	}
+	if numbering, err := readZipPart(archive, "word/numbering.xml"); err == nil {
+		r.readNumbering(numbering)
+	}
+
+	if err := r.readBody(body); err != nil {
+		return nil, fmt.Errorf("failed to parse DOCX document: %w", err)
+	}
+
+	if len(r.pages) == 0 {
+		return nil, fmt.Errorf("no text content found in DOCX")
+	}
+	return r.pages, nil
+}
+
+// readStyles finds the paragraph styles that are headings, by their name ("heading 2")
+// or their outline level, so localised style IDs are recognised too
+func (r *docxReader) readStyles(data []byte) {
+	var styles struct {
+		Styles []struct {
+			ID   string `xml:"styleId,attr"`
+			Name struct {
+				Val string `xml:"val,attr"`
+			} `xml:"name"`
+			Outline *struct {
+				Val string `xml:"val,attr"`
+			} `xml:"pPr>outlineLvl"`
+		} `xml:"style"`
+	}
+	if xml.Unmarshal(data, &styles) != nil {
+		return
+	}
+
+	for _, style := range styles.Styles {
+		name := strings.TrimSpace(style.Name.Val)
+		switch {
+		case strings.EqualFold(name, "title"):
+			r.headings[style.ID] = 1
+		case headingStylePattern.MatchString(name):
+			level, _ := strconv.Atoi(headingStylePattern.FindStringSubmatch(name)[1])
+			r.headings[style.ID] = level
+		case style.Outline != nil:
+			if level, err := strconv.Atoi(style.Outline.Val); err == nil && level < 9 {
+				r.headings[style.ID] = level + 1
+			}
+		}
+	}
+}
+
+// readNumbering finds the number format (e.g. "bullet" or "decimal") of every level of
+// every list
+func (r *docxReader) readNumbering(data []byte) {
+	type level struct {
+		Index  int `xml:"ilvl,attr"`
+		Format struct {
+			Val string `xml:"val,attr"`
+		} `xml:"numFmt"`
+	}
+	var numbering struct {
+		Abstract []struct {
+			ID     string  `xml:"abstractNumId,attr"`
+			Levels []level `xml:"lvl"`
+		} `xml:"abstractNum"`
+		Nums []struct {
+			ID       string `xml:"numId,attr"`
+			Abstract struct {
+				Val string `xml:"val,attr"`
+			} `xml:"abstractNumId"`
+		} `xml:"num"`
+	}
+	if xml.Unmarshal(data, &numbering) != nil {
+		return
+	}
+
+	formats := make(map[string]map[int]string)
+	for _, abstract := range numbering.Abstract {
+		formats[abstract.ID] = make(map[int]string)
+		for _, lvl := range abstract.Levels {
+			formats[abstract.ID][lvl.Index] = lvl.Format.Val
+		}
+	}
+	for _, num := range numbering.Nums {
+		if f, ok := formats[num.Abstract.Val]; ok {
+			r.numFormat[num.ID] = f
+		}
+	}
+}
+
+// readBody walks document.xml in order. Paragraphs can nest (text boxes) and so can
+// tables, so both are kept on stacks.
+func (r *docxReader) readBody(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			para := r.paragraph()
+			switch t.Name.Local {
+			case "Fallback":
+				// Alternate content repeats what the preferred choice already holds
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+			case "p":
+				r.paragraphs = append(r.paragraphs, &docxParagraph{})
+			case "pStyle":
+				if para != nil {
+					para.style = attr(t, "val")
+				}
+			case "outlineLvl":
+				if para != nil {
+					if level, err := strconv.Atoi(attr(t, "val")); err == nil && level < 9 {
+						para.outline = level + 1
+					}
+				}
+			case "numId":
+				if para != nil {
+					para.numID = attr(t, "val")
+				}
+			case "ilvl":
+				if para != nil {
+					para.level, _ = strconv.Atoi(attr(t, "val"))
+				}
+			case "t":
+				inText = para != nil
+			case "tab":
+				if para != nil {
+					para.text.WriteString("\t")
+				}
+			case "br", "cr":
+				if para == nil {
+					break
+				}
+				if attr(t, "type") == "page" {
+					r.pageBreak(para)
+				} else {
+					para.text.WriteString("\n")
+				}
+			case "lastRenderedPageBreak":
+				if para != nil {
+					r.pageBreak(para)
+				}
+			case "tbl":
+				r.tables = append(r.tables, &docxTable{})
+			case "tr":
+				if table := r.table(); table != nil {
+					table.row = nil
+				}
+			case "tc":
+				if table := r.table(); table != nil {
+					table.cell = []string{}
+				}
+			}
+
+		case xml.CharData:
+			if inText {
+				if para := r.paragraph(); para != nil {
+					para.text.Write(t)
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				r.endParagraph()
+			case "tc":
+				if table := r.table(); table != nil && table.cell != nil {
+					table.row = append(table.row, strings.Join(table.cell, " "))
+					table.cell = nil
+				}
+			case "tr":
+				if table := r.table(); table != nil && len(table.row) > 0 {
+					table.rows = append(table.rows, table.row)
+					table.row = nil
+				}
+			case "tbl":
+				r.endTable()
+			}
+		}
+	}
+
+	r.endPage()
+	return nil
+}
+
+func (r *docxReader) paragraph() *docxParagraph {
+	if len(r.paragraphs) == 0 {
+		return nil
+	}
+	return r.paragraphs[len(r.paragraphs)-1]
+}
+
+func (r *docxReader) table() *docxTable {
+	if len(r.tables) == 0 {
+		return nil
+	}
+	return r.tables[len(r.tables)-1]
+}
+
+// pageBreak ends the page before a paragraph when the break comes before its text, and
+// after it otherwise. Word often records a break twice, explicitly and as rendered, so a
+// page only ends when something was written on it.
+func (r *docxReader) pageBreak(para *docxParagraph) {
+	if strings.TrimSpace(para.text.String()) == "" {
+		r.endPage()
+	} else {
+		para.breakAfter = true
+	}
+}
+
+func (r *docxReader) endParagraph() {
+	para := r.paragraph()
+	if para == nil {
+		return
+	}
+	r.paragraphs = r.paragraphs[:len(r.paragraphs)-1]
+
+	text := strings.Join(strings.Fields(strings.ReplaceAll(para.text.String(), "\t", " ")), " ")
+	if text != "" {
+		// Paragraphs in table cells become part of the cell
+		if table := r.table(); table != nil && table.cell != nil {
+			table.cell = append(table.cell, text)
+		} else {
+			r.blocks = append(r.blocks, r.formatParagraph(para, text))
+		}
+	}
+
+	if para.breakAfter {
+		r.endPage()
+	}
+}
+
+// formatParagraph marks headings and list items
+func (r *docxReader) formatParagraph(para *docxParagraph, text string) textBlock {
+	level := para.outline
+	if level == 0 {
+		level = r.headings[para.style]
+	}
+	if level > 0 {
+		return textBlock{text: strings.Repeat("#", min(level, 6)) + " " + text}
+	}
+
+	if para.numID == "" || para.numID == "0" {
+		return textBlock{text: text}
+	}
+
+	// Count items per list and level; a shallower item restarts the deeper levels
+	counts := r.counters[para.numID]
+	for len(counts) <= para.level {
+		counts = append(counts, 0)
+	}
+	counts[para.level]++
+	for i := para.level + 1; i < len(counts); i++ {
+		counts[i] = 0
+	}
+	r.counters[para.numID] = counts
+
+	marker := "-"
+	switch format := r.numFormat[para.numID][para.level]; format {
+	case "", "bullet", "none":
+	default:
+		marker = strconv.Itoa(counts[para.level]) + "."
+	}
+	return textBlock{text: strings.Repeat("  ", para.level) + marker + " " + text, listItem: true}
+}
+
+// endTable writes a finished table as rows of cells. A table inside a table cell is
+// flattened into that cell.
+func (r *docxReader) endTable() {
+	table := r.table()
+	if table == nil {
+		return
+	}
+	r.tables = r.tables[:len(r.tables)-1]
+	if len(table.rows) == 0 {
+		return
+	}
+
+	lines := make([]string, 0, len(table.rows))
+	for _, row := range table.rows {
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+	}
+
+	if outer := r.table(); outer != nil && outer.cell != nil {
+		outer.cell = append(outer.cell, strings.Join(lines, " "))
+		return
+	}
+	r.blocks = append(r.blocks, textBlock{text: strings.Join(lines, "\n")})
+}
+
+// endPage writes the blocks read so far as a page
+func (r *docxReader) endPage() {
+	if len(r.blocks) == 0 {
+		return
+	}
+
+	r.pages = append(r.pages, models.DocumentPage{Number: r.pageNum, Text: joinBlocks(r.blocks)})
+	r.pageNum++
+	r.blocks = nil
+}
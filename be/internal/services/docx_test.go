@@ -0,0 +1,107 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// buildZip returns an archive holding the given files, in name order
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// docxBody wraps paragraphs and tables in the body of a document.xml
+func docxBody(content string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		content + `</w:body></w:document>`
+}
+
+func TestProcessDOCXFile(t *testing.T) {
+	styles := `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:style w:styleId="Kop1"><w:name w:val="heading 1"/></w:style></w:styles>`
+	numbering := `<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:abstractNum w:abstractNumId="0"><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num></w:numbering>`
+	item := func(text string) string {
+		return `<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string // nil for an empty file
+		want  []models.DocumentPage
+	}{
+		{name: "empty file"},
+		{name: "no document part", files: map[string]string{"word/other.xml": "<x/>"}},
+		{name: "malformed XML", files: map[string]string{"word/document.xml": docxBody(`<w:p><w:r><w:t>Cells</w:r></w:p>`)}},
+		{name: "no text", files: map[string]string{"word/document.xml": docxBody(`<w:p><w:r><w:t>  </w:t></w:r></w:p>`)}},
+		{
+			name: "headings, lists, tables and page breaks",
+			files: map[string]string{
+				"word/styles.xml":    styles,
+				"word/numbering.xml": numbering,
+				"word/document.xml": docxBody(
+					`<w:p><w:pPr><w:pStyle w:val="Kop1"/></w:pPr><w:r><w:t>Cells</w:t></w:r></w:p>` +
+						item("Nucleus") + item("Ribosome") +
+						`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Part</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Role</w:t></w:r></w:p></w:tc></w:tr>` +
+						`<w:tr><w:tc><w:p><w:r><w:t>Nucleus</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Holds DNA</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+						`<w:p><w:r><w:br w:type="page"/><w:t>Mitosis</w:t><w:tab/><w:t>splits   a cell.</w:t></w:r></w:p>`),
+			},
+			want: []models.DocumentPage{
+				{Number: 1, Text: "# Cells\n\n1. Nucleus\n2. Ribosome\n\n| Part | Role |\n| Nucleus | Holds DNA |"},
+				{Number: 2, Text: "Mitosis splits a cell."},
+			},
+		},
+	}
+
+	fs := NewFileService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content []byte
+			if tt.files != nil {
+				content = buildZip(t, tt.files)
+			}
+
+			pages, err := fs.processDOCXFile(content)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("no error, pages %+v", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processDOCXFile: %v", err)
+			}
+			if !reflect.DeepEqual(pages, tt.want) {
+				t.Errorf("pages = %+v\nwant %+v", pages, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path"
+	"pbkk-quizlit-backend/internal/models"
+	"strings"
+)
+
+// epubPackage is the part of an EPUB package document (the OPF file) that says what the
+// book is and in which order its chapters are read
+type epubPackage struct {
+	Metadata struct {
+		Titles   []string `xml:"title"`
+		Creators []string `xml:"creator"`
+		Subjects []string `xml:"subject"`
+		Language string   `xml:"language"`
+	} `xml:"metadata"`
+	Items []struct {
+		ID         string `xml:"id,attr"`
+		Href       string `xml:"href,attr"`
+		MediaType  string `xml:"media-type,attr"`
+		Properties string `xml:"properties,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef  string `xml:"idref,attr"`
+		Linear string `xml:"linear,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// processEPUBFile extracts the chapters of an EPUB book in reading order, one page per
+// section. Chapters are XHTML and are read like web pages; front and back matter the
+// book marks as outside the reading order is left out.
+func (fs *FileService) processEPUBFile(content []byte) ([]models.DocumentPage, error) {
+	archive, err := openZipArchive(content, fs.limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EPUB file: %w", err)
+	}
+
+	opfPath, pkg, err := readEPUBPackage(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string]int, len(pkg.Items))
+	for i, item := range pkg.Items {
+		items[item.ID] = i
+	}
+
+	var pages []models.DocumentPage
+	for _, ref := range pkg.Spine {
+		i, ok := items[ref.IDRef]
+		if !ok || ref.Linear == "no" {
+			continue
+		}
+		item := pkg.Items[i]
+		if item.MediaType != "application/xhtml+xml" && item.MediaType != "text/html" {
+			continue
+		}
+		if strings.Contains(" "+item.Properties+" ", " nav ") {
+			continue
+		}
+
+		href, err := url.PathUnescape(item.Href)
+		if err != nil {
+			href = item.Href
+		}
+		chapterPath := path.Join(path.Dir(opfPath), href)
+		data, err := readZipPart(archive, chapterPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read EPUB chapter %s: %w", chapterPath, err)
+		}
+		blocks, err := htmlBlocks(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse EPUB chapter %s: %w", chapterPath, err)
+		}
+
+		// Every chapter starts a page of its own
+		for _, page := range sectionPages(blocks) {
+			page.Number = len(pages) + 1
+			pages = append(pages, page)
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no text content found in EPUB")
+	}
+	return pages, nil
+}
+
+// readEPUBPackage finds the package document through META-INF/container.xml and reads it
+func readEPUBPackage(archive *zipArchive) (string, *epubPackage, error) {
+	data, err := readZipPart(archive, "META-INF/container.xml")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read EPUB container: %w", err)
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath  string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(data, &container); err != nil {
+		return "", nil, fmt.Errorf("failed to parse EPUB container: %w", err)
+	}
+
+	opfPath := ""
+	for _, rootfile := range container.Rootfiles {
+		if rootfile.MediaType == "" || rootfile.MediaType == "application/oebps-package+xml" {
+			opfPath = rootfile.FullPath
+			break
+		}
+	}
+	if opfPath == "" {
+		return "", nil, fmt.Errorf("EPUB container names no package document")
+	}
+
+	data, err = readZipPart(archive, opfPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read EPUB package: %w", err)
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return "", nil, fmt.Errorf("failed to parse EPUB package: %w", err)
+	}
+	return opfPath, &pkg, nil
+}
+
+// epubMetadata reads the title, authors, subjects and language of an EPUB book
+func epubMetadata(content []byte, limits UploadLimits) map[string]string {
+	metadata := make(map[string]string)
+
+	archive, err := openZipArchive(content, limits)
+	if err != nil {
+		return metadata
+	}
+	_, pkg, err := readEPUBPackage(archive)
+	if err != nil {
+		return metadata
+	}
+
+	if len(pkg.Metadata.Titles) > 0 {
+		setMetadata(metadata, "title", pkg.Metadata.Titles[0])
+	}
+	setMetadata(metadata, "author", strings.Join(pkg.Metadata.Creators, ", "))
+	setMetadata(metadata, "subject", strings.Join(pkg.Metadata.Subjects, ", "))
+	setMetadata(metadata, "language", pkg.Metadata.Language)
+	return metadata
+}
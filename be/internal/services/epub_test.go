@@ -0,0 +1,101 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"testing"
+)
+
+const epubContainer = `<?xml version="1.0"?><container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">` +
+	`<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`
+
+// epubOPF writes a package document with the given manifest items and spine
+func epubOPF(manifest, spine string) string {
+	return `<?xml version="1.0"?><package xmlns="http://www.idpf.org/2007/opf" version="3.0">` +
+		`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Biology</dc:title></metadata>` +
+		`<manifest>` + manifest + `</manifest><spine>` + spine + `</spine></package>`
+}
+
+func TestProcessEPUBFile(t *testing.T) {
+	book := map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": epubContainer,
+		"OEBPS/content.opf": epubOPF(
+			`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>`+
+				`<item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>`+
+				`<item id="ch1" href="text/chapter%201.xhtml" media-type="application/xhtml+xml"/>`+
+				`<item id="ch2" href="text/ch2.xhtml" media-type="application/xhtml+xml"/>`+
+				`<item id="css" href="style.css" media-type="text/css"/>`,
+			`<itemref idref="nav"/><itemref idref="cover" linear="no"/><itemref idref="ch1"/><itemref idref="css"/><itemref idref="ch2"/>`),
+		"OEBPS/nav.xhtml":            `<html><body><ol><li>Cells</li></ol></body></html>`,
+		"OEBPS/cover.xhtml":          `<html><body><p>Cover</p></body></html>`,
+		"OEBPS/text/chapter 1.xhtml": `<html><body><h1>Cells</h1><p>Cells divide.</p><h2>Mitosis</h2><p>One cell becomes two.</p></body></html>`,
+		"OEBPS/text/ch2.xhtml":       `<html><body><p>Plants make sugar.</p></body></html>`,
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string // nil for an empty file
+		want  []models.DocumentPage
+	}{
+		{name: "empty file"},
+		{name: "no container", files: map[string]string{"OEBPS/content.opf": epubOPF("", "")}},
+		{name: "malformed container", files: map[string]string{"META-INF/container.xml": `<container><rootfiles>`}},
+		{name: "no package document", files: map[string]string{"META-INF/container.xml": epubContainer}},
+		{
+			name: "malformed package document",
+			files: map[string]string{
+				"META-INF/container.xml": epubContainer,
+				"OEBPS/content.opf":      `<package><manifest><item id="ch1"></package>`,
+			},
+		},
+		{
+			name: "missing chapter",
+			files: map[string]string{
+				"META-INF/container.xml": epubContainer,
+				"OEBPS/content.opf":      epubOPF(`<item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/>`, `<itemref idref="ch1"/>`),
+			},
+		},
+		{
+			name: "no text",
+			files: map[string]string{
+				"META-INF/container.xml": epubContainer,
+				"OEBPS/content.opf":      epubOPF(`<item id="ch1" href="ch1.xhtml" media-type="application/xhtml+xml"/>`, `<itemref idref="ch1"/>`),
+				"OEBPS/ch1.xhtml":        `<html><body><script>x()</script></body></html>`,
+			},
+		},
+		{
+			name:  "chapters in reading order",
+			files: book,
+			want: []models.DocumentPage{
+				{Number: 1, Label: "Cells", Text: "# Cells\n\nCells divide."},
+				{Number: 2, Label: "Mitosis", Text: "## Mitosis\n\nOne cell becomes two."},
+				{Number: 3, Text: "Plants make sugar."},
+			},
+		},
+	}
+
+	fs := NewFileService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content []byte
+			if tt.files != nil {
+				content = buildZip(t, tt.files)
+			}
+
+			pages, err := fs.processEPUBFile(content)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("no error, pages %+v", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processEPUBFile: %v", err)
+			}
+			if !reflect.DeepEqual(pages, tt.want) {
+				t.Errorf("pages = %+v\nwant %+v", pages, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/storage"
+	"time"
+)
+
+// FileLinkPath is the route download tokens are served from
+const FileLinkPath = "/api/v1/files/"
+
+// FileLinks issues download links for stored blobs. A link works without an
+// Authorization header until it expires, so it can be used as an <img> source or handed
+// to the browser for a download.
+type FileLinks struct {
+	signer *storage.TokenSigner
+	ttl    time.Duration
+}
+
+func NewFileLinks(signer *storage.TokenSigner, ttl time.Duration) *FileLinks {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &FileLinks{signer: signer, ttl: ttl}
+}
+
+// Link returns a download link for the blob stored under key. filename and contentType
+// are sent with the download.
+func (fl *FileLinks) Link(key, filename, contentType string) *models.FileLink {
+	expiresAt := time.Now().Add(fl.ttl).Truncate(time.Second)
+	token := fl.signer.Sign(storage.DownloadToken{
+		Key:         key,
+		Filename:    filename,
+		ContentType: contentType,
+		ExpiresAt:   expiresAt,
+	})
+	return &models.FileLink{URL: FileLinkPath + token, ExpiresAt: expiresAt}
+}
@@ -1,233 +1,370 @@
 package services
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ledongthuc/pdf"
-	"golang.org/x/text/encoding/charmap"
 )
 
-type FileService struct{}
+type FileService struct {
+	normalize NormalizeOptions
+	ocr       OCREngine
+
+	// PDF pages are read by up to pdfWorkers workers, each page within pdfPageTimeout
+	pdfWorkers     int
+	pdfPageTimeout time.Duration
+
+	limits UploadLimits
+}
 
 func NewFileService() *FileService {
-	return &FileService{}
+	return &FileService{pdfWorkers: runtime.NumCPU(), pdfPageTimeout: 30 * time.Second, limits: DefaultUploadLimits()}
+}
+
+// WithNormalizeOptions returns a file service normalising extracted text with the given
+// options, which take precedence over those the service already has
+func (fs *FileService) WithNormalizeOptions(opts NormalizeOptions) *FileService {
+	service := *fs
+	service.normalize = fs.normalize.Merge(opts)
+	return &service
+}
+
+// WithOCR returns a file service reading PDF pages without a text layer with the given
+// OCR engine
+func (fs *FileService) WithOCR(engine OCREngine) *FileService {
+	service := *fs
+	service.ocr = engine
+	return &service
+}
+
+// WithPDFWorkers returns a file service reading up to workers pages of a PDF at a time,
+// giving up on a page after timeout. Zero keeps the current setting.
+func (fs *FileService) WithPDFWorkers(workers int, timeout time.Duration) *FileService {
+	service := *fs
+	if workers > 0 {
+		service.pdfWorkers = workers
+	}
+	if timeout > 0 {
+		service.pdfPageTimeout = timeout
+	}
+	return &service
+}
+
+// WithUploadLimits returns a file service refusing files beyond the given limits
+func (fs *FileService) WithUploadLimits(limits UploadLimits) *FileService {
+	service := *fs
+	service.limits = limits
+	return &service
+}
+
+// Limits returns the limits files are extracted within
+func (fs *FileService) Limits() UploadLimits {
+	return fs.limits
 }
 
 // ProcessUploadedFile extracts text content from uploaded files
 func (fs *FileService) ProcessUploadedFile(file multipart.File, header *multipart.FileHeader) (string, error) {
+	pages, err := fs.ExtractPages(file, header)
+	if err != nil {
+		return "", err
+	}
+
+	return JoinPages(pages), nil
+}
+
+// ExtractPages extracts text content from uploaded files keeping page boundaries
+func (fs *FileService) ExtractPages(file multipart.File, header *multipart.FileHeader) ([]models.DocumentPage, error) {
 	defer file.Close()
 
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+
+	return fs.ExtractPagesFromBytes(header.Filename, content)
+}
+
+// ExtractDocument extracts a file into a document: its pages as structured text, the
+// sections their headings outline, its tables, the pages read by OCR and the metadata of
+// the file
+func (fs *FileService) ExtractDocument(filename string, content []byte) (*models.Document, error) {
+	return fs.ExtractDocumentFrom(filename, bytes.NewReader(content), int64(len(content)))
+}
+
+// ExtractDocumentFrom extracts a document from a file read in place, such as an upload
+// spooled to disk. PDFs are read from it as needed; other formats are read into memory.
+func (fs *FileService) ExtractDocumentFrom(filename string, file io.ReaderAt, size int64) (*models.Document, error) {
+	pages, statuses, err := fs.extractPages(filename, file, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata map[string]string
+	if strings.ToLower(filepath.Ext(filename)) == ".pdf" {
+		metadata = pdfMetadata(file, size)
+	} else {
+		metadata = fs.ExtractMetadata(filename, readHead(file, int(size)))
+	}
+
+	return &models.Document{
+		Filename:   filename,
+		PageCount:  len(pages),
+		Metadata:   metadata,
+		Content:    JoinPages(pages),
+		Pages:      pages,
+		Sections:   DocumentOutline(pages),
+		Tables:     DocumentTables(pages),
+		OCR:        DocumentOCR(pages),
+		PageStatus: statuses,
+	}, nil
+}
+
+// ExtractPagesFromBytes extracts text content from a file already read into memory
+func (fs *FileService) ExtractPagesFromBytes(filename string, content []byte) ([]models.DocumentPage, error) {
+	pages, _, err := fs.extractPages(filename, bytes.NewReader(content), int64(len(content)))
+	return pages, err
+}
+
+// extractPages extracts the pages of a file within the upload limits, with the status of
+// every page for PDFs. The content must match the extension of the file.
+func (fs *FileService) extractPages(filename string, file io.ReaderAt, size int64) ([]models.DocumentPage, []models.PageStatus, error) {
+	if fs.limits.MaxFileSize > 0 && size > fs.limits.MaxFileSize {
+		return nil, nil, fmt.Errorf("%w: the limit is %s", ErrFileTooLarge, formatBytes(fs.limits.MaxFileSize))
+	}
+	if err := CheckContent(filename, readHead(file, 8192)); err != nil {
+		return nil, nil, err
+	}
+
+	ctx := context.Background()
+	if fs.limits.MaxExtractTime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fs.limits.MaxExtractTime)
+		defer cancel()
+	}
+
+	if strings.ToLower(filepath.Ext(filename)) == ".pdf" {
+		return fs.processPDFFile(ctx, file, size)
+	}
+
+	// Other formats are parsed in one go, which is given up on at the deadline
+	type result struct {
+		pages []models.DocumentPage
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("failed to read %s: %v", filepath.Base(filename), r)}
+			}
+		}()
+		pages, err := fs.extractOtherPages(filename, readHead(file, int(size)))
+		done <- result{pages, err}
+	}()
+	select {
+	case r := <-done:
+		return r.pages, nil, r.err
+	case <-ctx.Done():
+		return nil, nil, fmt.Errorf("%w: the limit is %s", ErrExtractionTimeout, fs.limits.MaxExtractTime)
+	}
+}
+
+// extractOtherPages extracts the pages of a file in any format but PDF
+func (fs *FileService) extractOtherPages(filename string, content []byte) ([]models.DocumentPage, error) {
 	// Get file extension
-	ext := strings.ToLower(filepath.Ext(header.Filename))
+	ext := strings.ToLower(filepath.Ext(filename))
 
 	switch ext {
 	case ".txt":
-		return fs.processTXTFile(file)
+		text, err := fs.processTXTFile(content)
+		if err != nil {
+			return nil, err
+		}
+		return splitTextPages(text), nil
+	case ".docx":
+		return fs.processDOCXFile(content)
+	case ".pptx":
+		return fs.processPPTXFile(content)
+	case ".md", ".markdown":
+		return fs.processMarkdownFile(content)
+	case ".html", ".htm", ".xhtml":
+		return fs.processHTMLFile(content)
+	case ".epub":
+		return fs.processEPUBFile(content)
+	case ".srt", ".vtt":
+		return fs.processSubtitleFile(content)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFile, ext)
+	}
+}
+
+// ExtractMetadata reads the document information of a file, such as the title and author
+// stored in a PDF, and the charset of text files. Files without any return an empty map.
+func (fs *FileService) ExtractMetadata(filename string, content []byte) map[string]string {
+	metadata := make(map[string]string)
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
 	case ".pdf":
-		return fs.processPDFFile(file)
+	case ".docx", ".pptx":
+		return officeMetadata(content, fs.limits)
+	case ".txt", ".md", ".markdown", ".html", ".htm", ".xhtml", ".srt", ".vtt":
+		return textMetadata(ext, content)
+	case ".epub":
+		return epubMetadata(content, fs.limits)
 	default:
-		return "", fmt.Errorf("unsupported file type: %s", ext)
+		return metadata
 	}
+	return pdfMetadata(bytes.NewReader(content), int64(len(content)))
 }
 
-func (fs *FileService) processTXTFile(file multipart.File) (string, error) {
-	content, err := io.ReadAll(file)
+// textMetadata reads the metadata of a text file in its own charset, adding the charset
+func textMetadata(ext string, content []byte) map[string]string {
+	text, charset, err := DecodeText(content)
 	if err != nil {
-		return "", fmt.Errorf("failed to read TXT file: %w", err)
+		return make(map[string]string)
 	}
 
-	// Try to decode as UTF-8 first, fallback to Windows-1252 if needed
-	text := string(content)
-	if !isValidUTF8(text) {
-		decoder := charmap.Windows1252.NewDecoder()
-		decodedContent, err := decoder.Bytes(content)
-		if err != nil {
-			return "", fmt.Errorf("failed to decode text file: %w", err)
+	var metadata map[string]string
+	switch ext {
+	case ".md", ".markdown":
+		metadata = markdownMetadata([]byte(text))
+	case ".html", ".htm", ".xhtml":
+		metadata = htmlMetadata([]byte(text))
+	default:
+		metadata = make(map[string]string)
+	}
+	metadata["charset"] = charset
+	return metadata
+}
+
+// pdfMetadata reads the document information dictionary of a PDF
+func pdfMetadata(file io.ReaderAt, size int64) map[string]string {
+	metadata := make(map[string]string)
+	reader, err := pdf.NewReader(file, size)
+	if err != nil {
+		return metadata
+	}
+
+	info := reader.Trailer().Key("Info")
+	for _, key := range []string{"Title", "Author", "Subject", "Keywords", "Creator", "Producer"} {
+		if value := strings.TrimSpace(info.Key(key).Text()); value != "" {
+			metadata[strings.ToLower(key)] = value
 		}
-		text = string(decodedContent)
 	}
+	return metadata
+}
 
-	return text, nil
+// JoinPages joins extracted pages into a single text, keeping the blocks of each page apart
+func JoinPages(pages []models.DocumentPage) string {
+	texts := make([]string, 0, len(pages))
+	for _, page := range pages {
+		texts = append(texts, page.Text)
+	}
+	return strings.Join(texts, "\n\n")
 }
 
-func (fs *FileService) processPDFFile(file multipart.File) (string, error) {
-	// Read all content into memory
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return "", fmt.Errorf("failed to read PDF file: %w", err)
+// splitTextPages treats form feeds in plain text as page breaks
+func splitTextPages(text string) []models.DocumentPage {
+	var pages []models.DocumentPage
+	for i, part := range strings.Split(text, "\f") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		pages = append(pages, models.DocumentPage{Number: i + 1, Text: part})
+	}
+	if len(pages) == 0 {
+		pages = append(pages, models.DocumentPage{Number: 1, Text: text})
 	}
+	return pages
+}
 
-	// Create a reader from the content
-	reader, err := pdf.NewReader(strings.NewReader(string(content)), int64(len(content)))
+// processTXTFile decodes a text file in whatever charset it is in
+func (fs *FileService) processTXTFile(content []byte) (string, error) {
+	text, _, err := DecodeText(content)
+	return text, err
+}
+
+// processPDFFile extracts the pages of a PDF with the status of every page. Pages that
+// cannot be read are left out and reported, so the rest of the document still comes
+// through.
+func (fs *FileService) processPDFFile(ctx context.Context, file io.ReaderAt, size int64) ([]models.DocumentPage, []models.PageStatus, error) {
+	// Create a reader reading the file in place
+	reader, err := pdf.NewReader(file, size)
 	if err != nil {
-		return "", fmt.Errorf("failed to create PDF reader: %w", err)
+		if err == pdf.ErrInvalidPassword || strings.Contains(err.Error(), "encryption") {
+			return nil, nil, fmt.Errorf("%w: remove the password and upload it again", ErrEncryptedPDF)
+		}
+		return nil, nil, fmt.Errorf("failed to create PDF reader: %w", err)
+	}
+	if limit := fs.limits.MaxPages; limit > 0 && reader.NumPage() > limit {
+		return nil, nil, fmt.Errorf("%w: it has %d pages, the limit is %d", ErrTooManyPages, reader.NumPage(), limit)
 	}
 
-	var text strings.Builder
-	numPages := reader.NumPage()
+	// Read every page as paragraphs in reading order, several pages at a time
+	layouts, statuses := extractPDFPages(ctx, reader, fs.pdfWorkers, fs.pdfPageTimeout, fs.limits.MaxStreamSize)
 
-	for i := 1; i <= numPages; i++ {
-		page := reader.Page(i)
-		if page.V.IsNull() {
-			continue
+	dropRunningHeads(layouts)
+
+	// Pages without a text layer, as scanned pages are, are read by OCR, and so are
+	// pages that could not be read otherwise
+	var ocrErr error
+	if fs.ocr != nil {
+		var numbers []int
+		for _, status := range statuses {
+			if status.Status != PageMissing {
+				numbers = append(numbers, status.Page)
+			}
 		}
+		layouts, ocrErr = fs.recognizePDFPages(ctx, file, size, layouts, numbers)
+	}
 
-		// Try GetPlainText first
-		pageText, err := page.GetPlainText(nil)
-		if err != nil {
+	// Headings come from the bookmarks when the PDF has them, else from font sizes
+	if entries := pdfOutline(reader); len(entries) > 0 {
+		applyPDFOutline(layouts, entries)
+	} else {
+		detectPDFHeadings(layouts)
+	}
+
+	// Normalisation depends on the language of the document
+	normalizer := fs.normalize.Normalizer(pdfTextSample(layouts))
+
+	var pages []models.DocumentPage
+	for _, layout := range layouts {
+		status := &statuses[layout.number-1]
+		text := fs.pdfPageText(layout, normalizer)
+		switch {
+		case text == "":
+			status.Status, status.Error = PageEmpty, ""
 			continue
+		case layout.ocr != nil:
+			status.Status, status.Error = PageOCR, ""
 		}
+		pages = append(pages, models.DocumentPage{Number: layout.number, Text: text, OCR: layout.ocr})
+	}
 
-		// Clean and normalize the text
-		cleanedText := fs.cleanPDFText(pageText)
-		
-		text.WriteString(cleanedText)
-		text.WriteString("\n\n") // Add paragraph breaks between pages
-	}
-
-	if text.Len() == 0 {
-		return "", fmt.Errorf("no text content found in PDF")
-	}
-
-	finalText := text.String()
-	
-	// Final cleaning pass
-	finalText = fs.normalizePDFText(finalText)
-	
-	return finalText, nil
-}
-
-// cleanPDFText cleans up PDF text extraction artifacts
-func (fs *FileService) cleanPDFText(text string) string {
-	// First, remove common PDF artifacts
-	text = strings.ReplaceAll(text, "□", " ")
-	text = strings.ReplaceAll(text, "�", "")
-	text = strings.ReplaceAll(text, "\u00a0", " ") // non-breaking space
-	
-	// Replace multiple spaces/newlines with single space
-	text = strings.Join(strings.Fields(text), " ")
-	
-	// AGGRESSIVE word boundary detection
-	var result strings.Builder
-	runes := []rune(text)
-	
-	for i := 0; i < len(runes); i++ {
-		current := runes[i]
-		result.WriteRune(current)
-		
-		if i < len(runes)-1 {
-			next := runes[i+1]
-			
-			// Skip if already has space
-			if current == ' ' || next == ' ' {
-				continue
-			}
-			
-			// Add space between lowercase and uppercase (camelCase)
-			// Example: "matriksselisih" → "matriks selisih"
-			if (current >= 'a' && current <= 'z') && (next >= 'A' && next <= 'Z') {
-				result.WriteRune(' ')
-				continue
-			}
-			
-			// Add space between uppercase and lowercase (if previous was lowercase)
-			// Example: "SSdan" → "SS dan"
-			if i > 0 && (current >= 'A' && current <= 'Z') && (next >= 'a' && next <= 'z') {
-				prev := runes[i-1]
-				if prev >= 'a' && prev <= 'z' {
-					result.WriteRune(' ')
-					continue
-				}
-			}
-			
-			// Add space between letter and number
-			if ((current >= 'a' && current <= 'z') || (current >= 'A' && current <= 'Z')) && 
-			   (next >= '0' && next <= '9') {
-				result.WriteRune(' ')
-				continue
-			}
-			
-			// Add space between number and letter
-			if (current >= '0' && current <= '9') && 
-			   ((next >= 'a' && next <= 'z') || (next >= 'A' && next <= 'Z')) {
-				result.WriteRune(' ')
-				continue
-			}
-			
-			// Add space after closing bracket if followed by letter
-			if (current == ')' || current == ']' || current == '}') && 
-			   ((next >= 'A' && next <= 'Z') || (next >= 'a' && next <= 'z')) {
-				result.WriteRune(' ')
-				continue
-			}
-			
-			// Add space before opening bracket if preceded by letter
-			if ((current >= 'a' && current <= 'z') || (current >= 'A' && current <= 'Z')) &&
-			   (next == '(' || next == '[' || next == '{') {
-				result.WriteRune(' ')
-				continue
-			}
-			
-			// Add space after period if followed by uppercase (sentence boundary)
-			if current == '.' && next >= 'A' && next <= 'Z' {
-				result.WriteRune(' ')
-				continue
-			}
-			
-			// Add space after comma if not already present
-			if current == ',' && ((next >= 'A' && next <= 'Z') || (next >= 'a' && next <= 'z')) {
-				result.WriteRune(' ')
-				continue
-			}
-			
-			// Add space after colon/semicolon if followed by letter
-			if (current == ':' || current == ';') && 
-			   ((next >= 'A' && next <= 'Z') || (next >= 'a' && next <= 'z')) {
-				result.WriteRune(' ')
-				continue
-			}
+	if len(pages) == 0 {
+		if ctx.Err() != nil {
+			return nil, statuses, fmt.Errorf("%w: the limit is %s", ErrExtractionTimeout, fs.limits.MaxExtractTime)
+		}
+		if failed := FailedPages(statuses); len(failed) > 0 {
+			return nil, statuses, fmt.Errorf("%w: pages %s could not be read: %s", ErrNoPDFText, FormatPageNumbers(failed), statuses[failed[0]-1].Error)
+		}
+		if fs.ocr == nil {
+			return nil, statuses, fmt.Errorf("%w: it may be scanned, and no OCR engine is configured", ErrNoPDFText)
 		}
+		if ocrErr != nil {
+			return nil, statuses, fmt.Errorf("%w: OCR failed: %v", ErrNoPDFText, ocrErr)
+		}
+		return nil, statuses, ErrNoPDFText
 	}
-	
-	return result.String()
-}
-
-// normalizePDFText performs final normalization
-func (fs *FileService) normalizePDFText(text string) string {
-	// Remove excessive whitespace
-	text = strings.Join(strings.Fields(text), " ")
-	
-	// Fix common ligatures and special characters
-	replacements := map[string]string{
-		"ﬁ": "fi",
-		"ﬂ": "fl",
-		"ﬀ": "ff",
-		"ﬃ": "ffi",
-		"ﬄ": "ffl",
-		"□": " ", // Replace box character with space
-		"�": "",  // Remove replacement character
-	}
-	
-	for old, new := range replacements {
-		text = strings.ReplaceAll(text, old, new)
-	}
-	
-	// Ensure proper sentence spacing
-	text = strings.ReplaceAll(text, ". ", ". ")
-	text = strings.ReplaceAll(text, "? ", "? ")
-	text = strings.ReplaceAll(text, "! ", "! ")
-	
-	// Remove multiple consecutive spaces again
-	text = strings.Join(strings.Fields(text), " ")
-	
-	return strings.TrimSpace(text)
-}
-
-func isValidUTF8(s string) bool {
-	// Simple check for valid UTF-8 by looking for replacement characters
-	return !strings.Contains(s, "\ufffd")
-}
\ No newline at end of file
+
+	return pages, statuses, nil
+}
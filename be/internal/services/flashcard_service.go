@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/repository"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FlashcardService struct {
+	repo *repository.FlashcardRepository
+}
+
+func NewFlashcardService() *FlashcardService {
+	return &FlashcardService{
+		repo: repository.NewFlashcardRepository(),
+	}
+}
+
+func (fs *FlashcardService) CreateDeck(deck *models.FlashcardDeck, userID string) error {
+	if len(deck.Cards) == 0 {
+		return fmt.Errorf("deck has no cards")
+	}
+
+	deck.CreatedAt = time.Now()
+	deck.UpdatedAt = time.Now()
+	deck.TotalCards = len(deck.Cards)
+
+	ctx := context.Background()
+	if err := fs.repo.CreateDeck(ctx, deck, userID); err != nil {
+		return fmt.Errorf("failed to create deck: %w", err)
+	}
+
+	return nil
+}
+
+func (fs *FlashcardService) GetDeck(id string) (*models.FlashcardDeck, error) {
+	ctx := context.Background()
+	return fs.repo.GetDeck(ctx, id)
+}
+
+func (fs *FlashcardService) GetAllDecks(userID string) ([]*models.FlashcardDeck, error) {
+	ctx := context.Background()
+	return fs.repo.GetAllDecks(ctx, userID)
+}
+
+func (fs *FlashcardService) UpdateDeck(deck *models.FlashcardDeck) error {
+	for i, card := range deck.Cards {
+		if strings.TrimSpace(card.Front) == "" || strings.TrimSpace(card.Back) == "" {
+			return fmt.Errorf("card %d must have both a front and a back", i+1)
+		}
+	}
+
+	ctx := context.Background()
+	if err := fs.repo.UpdateDeck(ctx, deck); err != nil {
+		return err
+	}
+
+	deck.UpdatedAt = time.Now()
+	deck.TotalCards = len(deck.Cards)
+	return nil
+}
+
+func (fs *FlashcardService) DeleteDeck(id string) error {
+	ctx := context.Background()
+	return fs.repo.DeleteDeck(ctx, id)
+}
+
+// DeckToQuiz turns a deck into a multiple-choice quiz where each card's back is the
+// prompt and the fronts of other cards in the deck act as distractors
+func (fs *FlashcardService) DeckToQuiz(deck *models.FlashcardDeck, req *models.DeckToQuizRequest) (*models.Quiz, error) {
+	if len(deck.Cards) < 2 {
+		return nil, fmt.Errorf("a deck needs at least 2 cards to build a quiz")
+	}
+
+	cards := make([]models.Flashcard, len(deck.Cards))
+	copy(cards, deck.Cards)
+	rand.Shuffle(len(cards), func(i, j int) { cards[i], cards[j] = cards[j], cards[i] })
+
+	count := req.QuestionCount
+	if count <= 0 || count > len(cards) {
+		count = len(cards)
+	}
+
+	var questions []models.Question
+	for _, card := range cards[:count] {
+		options := []string{card.Front}
+		seen := map[string]bool{strings.ToLower(card.Front): true}
+
+		for _, i := range rand.Perm(len(deck.Cards)) {
+			if len(options) >= 4 {
+				break
+			}
+			front := deck.Cards[i].Front
+			if seen[strings.ToLower(front)] {
+				continue
+			}
+			seen[strings.ToLower(front)] = true
+			options = append(options, front)
+		}
+
+		if len(options) < 2 {
+			continue
+		}
+
+		rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+		correctAnswer := 0
+		for i, option := range options {
+			if option == card.Front {
+				correctAnswer = i
+				break
+			}
+		}
+
+		text := "Which term matches this description: " + maskTerm(card.Back, card.Front)
+		explanation := card.Back
+		if card.SourceRef != "" {
+			explanation += " (" + card.SourceRef + ")"
+		}
+
+		questions = append(questions, models.Question{
+			ID:            uuid.New().String(),
+			Type:          "multiple-choice",
+			Text:          text,
+			Question:      text,
+			Options:       options,
+			Correct:       card.Front,
+			CorrectAnswer: correctAnswer,
+			Points:        1,
+			Explanation:   explanation,
+			Metadata:      map[string]interface{}{"source": "flashcard-deck", "deckId": deck.ID},
+		})
+	}
+
+	if len(questions) == 0 {
+		return nil, fmt.Errorf("deck cards are too similar to build a quiz")
+	}
+
+	title := req.Title
+	if title == "" {
+		title = deck.Title + " (Quiz)"
+	}
+	description := req.Description
+	if description == "" {
+		description = "Quiz generated from the flashcard deck " + deck.Title
+	}
+	difficulty := req.Difficulty
+	if difficulty == "" {
+		difficulty = "medium"
+	}
+
+	return &models.Quiz{
+		ID:             uuid.New().String(),
+		Title:          title,
+		Description:    description,
+		Questions:      questions,
+		Difficulty:     difficulty,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		TotalQuestions: len(questions),
+	}, nil
+}
+
+// maskTerm blanks out the term in a definition so the answer is not given away
+func maskTerm(text, term string) string {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return text
+	}
+	re, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`)
+	if err != nil {
+		return text
+	}
+	return re.ReplaceAllString(text, "____")
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strings"
+)
+
+// GlossaryOptions controls glossary extraction
+type GlossaryOptions struct {
+	Limit    int
+	UseLLM   bool
+	Keywords KeywordOptions
+	Corpus   *models.KeywordCorpus
+}
+
+// BuildGlossary ranks the key terms of a document and pairs each with a definition,
+// the pages it appears on and its frequency. When UseLLM is set the configured provider
+// writes the definitions; sentences from the text are used otherwise or when it fails.
+func (ai *AIService) BuildGlossary(pages []models.DocumentPage, opts GlossaryOptions) []models.GlossaryTerm {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	content := JoinPages(pages)
+	sentences := ai.extractSentences(content)
+
+	var terms []models.GlossaryTerm
+	for _, kw := range ExtractKeywords(content, opts.Keywords, opts.Corpus, limit*3) {
+		termPages, _ := locateTerm(pages, kw.Term)
+		if len(termPages) == 0 {
+			continue
+		}
+
+		terms = append(terms, models.GlossaryTerm{
+			Term:      kw.Term,
+			Pages:     termPages,
+			Frequency: kw.Frequency,
+			Score:     kw.Score,
+		})
+	}
+
+	// Attach definitions from the text
+	var glossary []models.GlossaryTerm
+	for _, term := range terms {
+		if len(glossary) >= limit {
+			break
+		}
+		if idx := findDefiningSentence(sentences, term.Term, nil); idx >= 0 {
+			term.Definition = strings.Join(strings.Fields(sentences[idx]), " ")
+			term.DefinitionSource = "text"
+		}
+		if term.Definition == "" && !opts.UseLLM {
+			continue
+		}
+		glossary = append(glossary, term)
+	}
+
+	if opts.UseLLM && len(glossary) > 0 {
+		if err := ai.defineTermsWithLLM(glossary, sentences); err != nil {
+			ai.logger.Warnf("LLM glossary definitions failed: %v, keeping definitions from text", err)
+		}
+	}
+
+	// Drop terms nobody could define
+	defined := glossary[:0]
+	for _, term := range glossary {
+		if term.Definition != "" {
+			defined = append(defined, term)
+		}
+	}
+
+	return defined
+}
+
+// defineTermsWithLLM asks the configured provider to write a definition for every term,
+// giving it the sentences that mention the term as context
+func (ai *AIService) defineTermsWithLLM(glossary []models.GlossaryTerm, sentences []string) error {
+	var context strings.Builder
+	for _, term := range glossary {
+		context.WriteString("- " + term.Term)
+		if term.Definition != "" {
+			context.WriteString(": " + term.Definition)
+		} else if idx := findDefiningSentence(sentences, term.Term, nil); idx >= 0 {
+			context.WriteString(": " + sentences[idx])
+		}
+		context.WriteString("\n")
+	}
+
+	prompt := fmt.Sprintf(`Write a one-sentence glossary definition for each of the following terms.
+Each term is followed by a sentence from the source document for context.
+
+Terms:
+%s
+Requirements:
+- Base each definition on the source document
+- Write the definition in the same language as the context sentence
+- Return the response as a JSON object mapping each term to its definition
+
+Return ONLY the JSON object, no additional text.`, context.String())
+
+	reply, err := ai.completeText(
+		"You are an expert at writing concise glossary definitions for study material. Return ONLY valid JSON without any additional text or formatting.",
+		prompt,
+		2000,
+	)
+	if err != nil {
+		return err
+	}
+
+	var definitions map[string]string
+	if err := json.Unmarshal([]byte(cleanJSONResponse(reply)), &definitions); err != nil {
+		return fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+
+	lowered := make(map[string]string, len(definitions))
+	for term, definition := range definitions {
+		lowered[strings.ToLower(strings.TrimSpace(term))] = strings.TrimSpace(definition)
+	}
+
+	for i := range glossary {
+		if definition := lowered[glossary[i].Term]; definition != "" {
+			glossary[i].Definition = definition
+			glossary[i].DefinitionSource = "llm"
+		}
+	}
+
+	return nil
+}
+
+// locateTerm returns the pages a term appears on and its total number of occurrences
+func locateTerm(pages []models.DocumentPage, term string) ([]int, int) {
+	pattern, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`)
+	if err != nil {
+		return nil, 0
+	}
+
+	termPages := []int{}
+	frequency := 0
+	for _, page := range pages {
+		count := len(pattern.FindAllStringIndex(page.Text, -1))
+		if count > 0 {
+			termPages = append(termPages, page.Number)
+			frequency += count
+		}
+	}
+
+	return termPages, frequency
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlList is a list being read, with the number of its last item
+type htmlList struct {
+	ordered bool
+	count   int
+}
+
+// htmlReader turns an HTML document into structured text. The document is parsed like a
+// browser would, so broken markup is read the way it is displayed, and only text nodes
+// are kept: scripts, styles, forms and navigation never reach the output.
+type htmlReader struct {
+	blocks []textBlock
+	text   strings.Builder
+	prefix string // marker of the list item whose text is being read
+	lists  []htmlList
+	tables []*docxTable
+	cells  int // depth of table cells being read
+	pre    int // depth of preformatted elements being read
+}
+
+// htmlSkipped are the elements whose content is not text of the document
+var htmlSkipped = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Nav: true, atom.Footer: true, atom.Form: true, atom.Button: true, atom.Select: true,
+	atom.Textarea: true, atom.Svg: true, atom.Math: true, atom.Iframe: true, atom.Object: true,
+	atom.Embed: true, atom.Canvas: true, atom.Audio: true, atom.Video: true,
+}
+
+// htmlBlockElements start and end a block of text
+var htmlBlockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Header: true, atom.Aside: true, atom.Blockquote: true, atom.Figure: true,
+	atom.Figcaption: true, atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Address: true,
+	atom.Details: true, atom.Summary: true, atom.Hr: true, atom.Caption: true, atom.Center: true,
+}
+
+// processHTMLFile extracts the text of a web page as one page per section
+func (fs *FileService) processHTMLFile(content []byte) ([]models.DocumentPage, error) {
+	text, err := fs.processTXTFile(content)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks, err := htmlBlocks(text)
+	if err != nil {
+		return nil, err
+	}
+	pages := sectionPages(blocks)
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no text content found in HTML file")
+	}
+	return pages, nil
+}
+
+// htmlBlocks converts an HTML document into blocks of structured text. Saved web pages
+// carry menus and sidebars around the article, so when the page marks its main content
+// only that is read.
+func htmlBlocks(text string) ([]textBlock, error) {
+	doc, err := html.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	root := doc
+	if main := findHTMLElement(doc, atom.Main); main != nil {
+		root = main
+	} else if articles := findHTMLElements(doc, atom.Article); len(articles) == 1 {
+		root = articles[0]
+	}
+
+	r := &htmlReader{}
+	r.walk(root)
+	r.endBlock()
+	return r.blocks, nil
+}
+
+func (r *htmlReader) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.text.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		r.walkChildren(n)
+		return
+	default:
+		return
+	}
+
+	if htmlSkipped[n.DataAtom] || hasHTMLAttr(n, "hidden") || htmlAttr(n, "aria-hidden") == "true" {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		r.endBlock()
+		r.walkChildren(n)
+		level, _ := strconv.Atoi(n.Data[1:])
+		r.prefix = strings.Repeat("#", level)
+		r.endBlock()
+		r.prefix = ""
+	case atom.Br:
+		r.text.WriteString("\n")
+	case atom.Img:
+		// Images are left out; their alt text is usually a caption of what is already said
+	case atom.Ul, atom.Ol, atom.Menu:
+		r.endBlock()
+		r.lists = append(r.lists, htmlList{ordered: n.DataAtom == atom.Ol})
+		if start, err := strconv.Atoi(htmlAttr(n, "start")); err == nil && n.DataAtom == atom.Ol {
+			r.lists[len(r.lists)-1].count = start - 1
+		}
+		r.walkChildren(n)
+		r.endBlock()
+		r.lists = r.lists[:len(r.lists)-1]
+	case atom.Li:
+		r.endBlock()
+		r.prefix = r.listMarker()
+		r.walkChildren(n)
+		r.endBlock()
+		r.prefix = ""
+	case atom.Pre:
+		r.endBlock()
+		r.pre++
+		r.walkChildren(n)
+		r.pre--
+		r.endCode()
+	case atom.Table:
+		if r.cells > 0 {
+			// A table inside a table cell is flattened into that cell
+			r.walkChildren(n)
+			return
+		}
+		r.endBlock()
+		r.tables = append(r.tables, &docxTable{})
+		r.walkChildren(n)
+		r.endTable()
+	case atom.Tr:
+		if table := r.table(); table != nil && r.cells == 0 {
+			table.row = nil
+			r.walkChildren(n)
+			if len(table.row) > 0 {
+				table.rows = append(table.rows, table.row)
+			}
+			return
+		}
+		r.walkChildren(n)
+	case atom.Td, atom.Th:
+		table := r.table()
+		if table == nil || r.cells > 0 {
+			r.text.WriteString(" ")
+			r.walkChildren(n)
+			return
+		}
+		r.cells++
+		r.walkChildren(n)
+		r.cells--
+		table.row = append(table.row, collapseSpace(r.text.String()))
+		r.text.Reset()
+	default:
+		if htmlBlockElements[n.DataAtom] {
+			r.endBlock()
+			r.walkChildren(n)
+			r.endBlock()
+			return
+		}
+		r.walkChildren(n)
+	}
+}
+
+func (r *htmlReader) walkChildren(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		r.walk(child)
+	}
+}
+
+// listMarker numbers the next item of the innermost list, indented by the list's depth
+func (r *htmlReader) listMarker() string {
+	if len(r.lists) == 0 {
+		return "-"
+	}
+	list := &r.lists[len(r.lists)-1]
+	list.count++
+	marker := "-"
+	if list.ordered {
+		marker = strconv.Itoa(list.count) + "."
+	}
+	return strings.Repeat("  ", len(r.lists)-1) + marker
+}
+
+// endBlock writes the text read since the last block. Inside a table cell blocks only
+// separate the words of the cell. A list marker waits for the first block with text, as
+// list items often wrap their text in paragraphs.
+func (r *htmlReader) endBlock() {
+	if r.cells > 0 || r.pre > 0 {
+		r.text.WriteString(" ")
+		return
+	}
+
+	text := collapseSpace(r.text.String())
+	r.text.Reset()
+	if text == "" {
+		return
+	}
+	block := textBlock{text: text}
+	if r.prefix != "" {
+		block = textBlock{text: r.prefix + " " + text, listItem: !strings.HasPrefix(r.prefix, "#")}
+		r.prefix = ""
+	}
+	r.blocks = append(r.blocks, block)
+}
+
+// endCode writes preformatted text line by line, indented as code
+func (r *htmlReader) endCode() {
+	if r.cells > 0 || r.pre > 0 {
+		return
+	}
+	lines := strings.Split(strings.Trim(r.text.String(), "\n"), "\n")
+	r.text.Reset()
+
+	for i, line := range lines {
+		lines[i] = "    " + strings.TrimRight(line, " \t\r")
+	}
+	if text := strings.Join(lines, "\n"); strings.TrimSpace(text) != "" {
+		r.blocks = append(r.blocks, textBlock{text: text})
+	}
+}
+
+func (r *htmlReader) table() *docxTable {
+	if len(r.tables) == 0 {
+		return nil
+	}
+	return r.tables[len(r.tables)-1]
+}
+
+// endTable writes a finished table as rows of cells
+func (r *htmlReader) endTable() {
+	table := r.table()
+	r.tables = r.tables[:len(r.tables)-1]
+	r.endBlock() // a caption or text outside the rows
+
+	var lines []string
+	for _, row := range table.rows {
+		if strings.TrimSpace(strings.Join(row, "")) != "" {
+			lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		}
+	}
+	if len(lines) > 0 {
+		r.blocks = append(r.blocks, textBlock{text: strings.Join(lines, "\n")})
+	}
+}
+
+// htmlMetadata reads the title, author, description and keywords of an HTML document
+func htmlMetadata(content []byte) map[string]string {
+	metadata := make(map[string]string)
+	doc, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return metadata
+	}
+
+	if title := findHTMLElement(doc, atom.Title); title != nil {
+		setMetadata(metadata, "title", collapseSpace(htmlText(title)))
+	}
+	for _, meta := range findHTMLElements(doc, atom.Meta) {
+		switch strings.ToLower(htmlAttr(meta, "name")) {
+		case "author":
+			setMetadata(metadata, "author", htmlAttr(meta, "content"))
+		case "description":
+			setMetadata(metadata, "subject", htmlAttr(meta, "content"))
+		case "keywords":
+			setMetadata(metadata, "keywords", htmlAttr(meta, "content"))
+		case "generator":
+			setMetadata(metadata, "creator", htmlAttr(meta, "content"))
+		}
+	}
+	return metadata
+}
+
+func findHTMLElement(n *html.Node, a atom.Atom) *html.Node {
+	if elements := findHTMLElements(n, a); len(elements) > 0 {
+		return elements[0]
+	}
+	return nil
+}
+
+// findHTMLElements returns the elements of a kind in document order, not looking inside
+// the ones found
+func findHTMLElements(n *html.Node, a atom.Atom) []*html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return []*html.Node{n}
+	}
+	var found []*html.Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		found = append(found, findHTMLElements(child, a)...)
+	}
+	return found
+}
+
+// htmlText returns all text below a node
+func htmlText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var text strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(htmlText(child))
+	}
+	return text.String()
+}
+
+func htmlAttr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasHTMLAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestProcessHTMLFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []models.DocumentPage // nil when the file has no text
+	}{
+		{name: "empty file"},
+		{name: "only whitespace", content: "  \n\t"},
+		{name: "only scripts and navigation", content: "<html><body><nav>Menu</nav><script>x()</script></body></html>"},
+		{
+			name: "main content with lists and tables",
+			content: "<html><head><title>Cells</title><style>p{}</style></head><body><nav>Menu</nav><main>" +
+				"<h1>Cells</h1><p>The <b>nucleus</b> holds DNA.<ul><li>Mitosis<li>Meiosis</ul>" +
+				"<table><tr><th>Part<th>Role<tr><td>Nucleus<td>Holds DNA</table>" +
+				"<h2>Osmosis</h2><p>Water &amp; salt</main><footer>Contact</footer></body></html>",
+			want: []models.DocumentPage{
+				{Number: 1, Label: "Cells", Text: "# Cells\n\nThe nucleus holds DNA.\n\n- Mitosis\n- Meiosis\n\n| Part | Role |\n| Nucleus | Holds DNA |"},
+				{Number: 2, Label: "Osmosis", Text: "## Osmosis\n\nWater & salt"},
+			},
+		},
+		{
+			name:    "unclosed tags",
+			content: "<p>Unclosed <b>bold<div>and a block",
+			want:    []models.DocumentPage{{Number: 1, Text: "Unclosed bold\n\nand a block"}},
+		},
+	}
+
+	fs := NewFileService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := fs.processHTMLFile([]byte(tt.content))
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("no error, pages %+v", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processHTMLFile: %v", err)
+			}
+			if !reflect.DeepEqual(pages, tt.want) {
+				t.Errorf("pages = %+v\nwant %+v", pages, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/repository"
+	"pbkk-quizlit-backend/internal/storage"
+)
+
+// MaxImageSize is the largest image that can be attached to a question
+const MaxImageSize = 5 << 20
+
+var (
+	ErrUnsupportedImage = errors.New("image must be a PNG, JPEG, GIF or WebP file")
+	ErrImageTooLarge    = fmt.Errorf("image must be at most %d MB", MaxImageSize>>20)
+	ErrNoImageStorage   = errors.New("file storage is not available")
+)
+
+// imageExtensions maps the image types accepted as attachments to their file extension
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageService keeps the images attached to quiz questions in blob storage
+type ImageService struct {
+	quizRepo *repository.QuizRepository
+	store    storage.BlobStore
+	links    *FileLinks
+}
+
+func NewImageService(store storage.BlobStore, links *FileLinks) *ImageService {
+	return &ImageService{
+		quizRepo: repository.NewQuizRepository(),
+		store:    store,
+		links:    links,
+	}
+}
+
+// SetQuestionImage stores an image and attaches it to a question, replacing the image
+// the question had before. The type is taken from the content, not the file name.
+func (is *ImageService) SetQuestionImage(quizID, questionID string, content []byte) (*models.FileLink, error) {
+	if is.store == nil {
+		return nil, ErrNoImageStorage
+	}
+	if len(content) > MaxImageSize {
+		return nil, ErrImageTooLarge
+	}
+	contentType := http.DetectContentType(content)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImage
+	}
+
+	ctx := context.Background()
+	key := fmt.Sprintf("images/questions/%s/%s%s", questionID, FileHash(content)[:16], ext)
+	if _, err := is.store.Put(ctx, key, bytes.NewReader(content)); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+
+	previous, err := is.quizRepo.SetQuestionImage(ctx, quizID, questionID, key)
+	if err != nil {
+		is.store.Delete(ctx, key)
+		return nil, err
+	}
+	if previous != "" && previous != key {
+		is.store.Delete(ctx, previous)
+	}
+
+	return is.links.Link(key, "", contentType), nil
+}
+
+// RemoveQuestionImage detaches the image of a question and deletes it
+func (is *ImageService) RemoveQuestionImage(quizID, questionID string) error {
+	ctx := context.Background()
+	previous, err := is.quizRepo.SetQuestionImage(ctx, quizID, questionID, "")
+	if err != nil {
+		return err
+	}
+	if previous != "" && is.store != nil {
+		if err := is.store.Delete(ctx, previous); err != nil {
+			return fmt.Errorf("image detached but not deleted: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteQuizImages deletes the images of a quiz's questions once the quiz is deleted
+func (is *ImageService) DeleteQuizImages(quiz *models.Quiz) error {
+	if is.store == nil {
+		return nil
+	}
+
+	ctx := context.Background()
+	var firstErr error
+	for _, q := range quiz.Questions {
+		if q.ImageKey == "" {
+			continue
+		}
+		if err := is.store.Delete(ctx, q.ImageKey); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// AttachLinks adds a download link to every question of a quiz that has an image
+func (is *ImageService) AttachLinks(quiz *models.Quiz) {
+	if is.store == nil {
+		return
+	}
+	for i := range quiz.Questions {
+		if key := quiz.Questions[i].ImageKey; key != "" {
+			quiz.Questions[i].Image = is.links.Link(key, "", "")
+		}
+	}
+}
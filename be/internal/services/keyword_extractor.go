@@ -0,0 +1,309 @@
+package services
+
+import (
+	"math"
+	"pbkk-quizlit-backend/internal/models"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// keywordWindowSize is the number of tokens per pseudo-document used for IDF when no
+// reference corpus is available
+const keywordWindowSize = 60
+
+// englishStopWords and indonesianStopWords are ignored as keywords and never start or
+// end a keyword phrase
+var englishStopWords = wordSet(`a about above after again against all also an and any are as at be because been
+before being below between both but by can could did do does doing down during each either few for from
+further had has have having he her here hers him his how however i if in into is it its itself just
+may me might more most must my no nor not now of off on once only or other our ours out over own same
+she should so some such than that the their theirs them then there these they this those through thus
+to too under until up upon us very was we were what when where which while who whom why will with
+within without would you your yours include includes including use used using`)
+
+var indonesianStopWords = wordSet(`ada adalah agar akan aku anda antara apa apabila atas atau bagai bagaimana
+bagi bahwa baik banyak beberapa belum bisa dalam dan dapat dari demikian dengan di dia harus hal hanya
+ia ini itu jadi jika juga kami kamu karena ke kembali kemudian kepada ketika kita lagi lain lalu maka
+masih mereka merupakan namun oleh pada para saat saja sama sangat saya sebagai sebelum sedang sehingga
+sejak seperti serta setelah sudah telah tentang terhadap tersebut tetapi tidak untuk yaitu yang`)
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// KeywordOptions controls how keywords are extracted and keyed
+type KeywordOptions struct {
+	Language string // "en", "id" or "" to detect automatically
+	Stemming bool
+	MaxNGram int
+}
+
+// RankedKeyword is a keyword or phrase with its TF-IDF score
+type RankedKeyword struct {
+	Term      string  `json:"term"`
+	Key       string  `json:"key"`
+	Score     float64 `json:"score"`
+	Frequency int     `json:"frequency"`
+}
+
+type keywordToken struct {
+	lower string
+	key   string
+	stop  bool
+	digit bool
+}
+
+type keywordStats struct {
+	key        string
+	tokens     int
+	frequency  int
+	windows    int
+	lastWindow int
+	surfaces   map[string]int
+}
+
+// ExtractKeywords ranks the keywords and phrases of a text by TF-IDF. Document
+// frequencies come from the reference corpus when it was built with the same options,
+// otherwise from fixed-size windows of the text itself.
+func ExtractKeywords(content string, opts KeywordOptions, corpus *models.KeywordCorpus, limit int) []RankedKeyword {
+	opts = normalizeKeywordOptions(opts, content)
+	stats, windows := collectKeywordStats(content, opts)
+
+	useCorpus := corpus != nil && corpus.Documents > 0 && corpus.Stemmed == opts.Stemming
+
+	ranked := make([]RankedKeyword, 0, len(stats))
+	for _, st := range stats {
+		// Phrases must recur to count as a keyword
+		if st.tokens > 1 && st.frequency < 2 {
+			continue
+		}
+
+		var idf float64
+		if useCorpus {
+			idf = math.Log(float64(corpus.Documents+1)/float64(corpus.DocFreq[st.key]+1)) + 1
+		} else {
+			idf = math.Log(float64(windows+1)/float64(st.windows+1)) + 1
+		}
+		tf := 1 + math.Log(float64(st.frequency))
+
+		ranked = append(ranked, RankedKeyword{
+			Term:      mostCommonSurface(st.surfaces),
+			Key:       st.key,
+			Score:     tf * idf * (1 + 0.5*float64(st.tokens-1)),
+			Frequency: st.frequency,
+		})
+	}
+
+	ranked = dropSubsumedKeywords(ranked, stats)
+
+	// O(n log n) ranking; ties are broken alphabetically so results are stable
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Key < ranked[j].Key
+	})
+
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// BuildKeywordCorpus computes document frequencies of keyword keys over a set of texts
+func BuildKeywordCorpus(texts []string, opts KeywordOptions) *models.KeywordCorpus {
+	corpus := &models.KeywordCorpus{
+		DocFreq: make(map[string]int),
+		Stemmed: opts.Stemming,
+	}
+
+	for _, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		stats, _ := collectKeywordStats(text, normalizeKeywordOptions(opts, text))
+		for key := range stats {
+			corpus.DocFreq[key]++
+		}
+		corpus.Documents++
+	}
+
+	return corpus
+}
+
+// DetectLanguage guesses whether a text is English or Indonesian from stop-word hits
+func DetectLanguage(content string) string {
+	english, indonesian := 0, 0
+	for i, word := range strings.Fields(strings.ToLower(content)) {
+		if i >= 2000 {
+			break
+		}
+		word = strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) })
+		if englishStopWords[word] {
+			english++
+		}
+		if indonesianStopWords[word] {
+			indonesian++
+		}
+	}
+
+	if indonesian > english {
+		return "id"
+	}
+	return "en"
+}
+
+func normalizeKeywordOptions(opts KeywordOptions, content string) KeywordOptions {
+	if opts.Language == "" || opts.Language == "auto" {
+		opts.Language = DetectLanguage(content)
+	}
+	if opts.MaxNGram <= 0 {
+		opts.MaxNGram = 3
+	}
+	return opts
+}
+
+// collectKeywordStats counts every candidate n-gram and how many windows it appears in
+func collectKeywordStats(content string, opts KeywordOptions) (map[string]*keywordStats, int) {
+	var stemmer Stemmer
+	if opts.Stemming {
+		stemmer = stemmerFor(opts.Language)
+	}
+
+	stats := make(map[string]*keywordStats)
+	tokenCount := 0
+	window := 0
+
+	for _, segment := range tokenizeKeywordSegments(PlainText(content, true), stemmer) {
+		for i := range segment {
+			tokenCount++
+			window = tokenCount / keywordWindowSize
+
+			for n := 1; n <= opts.MaxNGram && i+n <= len(segment); n++ {
+				gram := segment[i : i+n]
+				first, last := gram[0], gram[n-1]
+				if first.stop || last.stop || first.digit || last.digit {
+					continue
+				}
+				if n == 1 && len([]rune(first.lower)) < 3 {
+					continue
+				}
+
+				keys := make([]string, n)
+				surfaces := make([]string, n)
+				for k, token := range gram {
+					keys[k] = token.key
+					surfaces[k] = token.lower
+				}
+				key := strings.Join(keys, " ")
+
+				st, ok := stats[key]
+				if !ok {
+					st = &keywordStats{key: key, tokens: n, lastWindow: -1, surfaces: make(map[string]int)}
+					stats[key] = st
+				}
+				st.frequency++
+				st.surfaces[strings.Join(surfaces, " ")]++
+				if st.lastWindow != window {
+					st.windows++
+					st.lastWindow = window
+				}
+			}
+		}
+	}
+
+	return stats, window + 1
+}
+
+// tokenizeKeywordSegments splits text into runs of words that are not separated by
+// punctuation, so phrases never cross sentence or clause boundaries
+func tokenizeKeywordSegments(content string, stemmer Stemmer) [][]keywordToken {
+	var segments [][]keywordToken
+	var segment []keywordToken
+	var word strings.Builder
+
+	flushWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		lower := strings.Trim(strings.ToLower(word.String()), "-'")
+		word.Reset()
+		if lower == "" {
+			return
+		}
+
+		token := keywordToken{
+			lower: lower,
+			key:   lower,
+			stop:  englishStopWords[lower] || indonesianStopWords[lower],
+			digit: strings.IndexFunc(lower, unicode.IsDigit) >= 0,
+		}
+		if stemmer != nil && !token.stop && !token.digit {
+			token.key = stemmer.Stem(lower)
+		}
+		segment = append(segment, token)
+	}
+	flushSegment := func() {
+		flushWord()
+		if len(segment) > 0 {
+			segments = append(segments, segment)
+			segment = nil
+		}
+	}
+
+	for _, r := range content {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '\'':
+			word.WriteRune(r)
+		case unicode.IsSpace(r):
+			flushWord()
+		default:
+			flushSegment()
+		}
+	}
+	flushSegment()
+
+	return segments
+}
+
+// dropSubsumedKeywords removes single words that never occur outside one of the
+// phrases that contain them, e.g. "machine" when it only appears in "machine learning"
+func dropSubsumedKeywords(ranked []RankedKeyword, stats map[string]*keywordStats) []RankedKeyword {
+	covered := make(map[string]int)
+	for _, kw := range ranked {
+		st := stats[kw.Key]
+		if st.tokens < 2 {
+			continue
+		}
+		for _, part := range strings.Fields(kw.Key) {
+			if st.frequency > covered[part] {
+				covered[part] = st.frequency
+			}
+		}
+	}
+
+	kept := ranked[:0]
+	for _, kw := range ranked {
+		if stats[kw.Key].tokens == 1 && covered[kw.Key] >= kw.Frequency {
+			continue
+		}
+		kept = append(kept, kw)
+	}
+	return kept
+}
+
+// mostCommonSurface picks the spelling of a keyword that occurs most often in the text
+func mostCommonSurface(surfaces map[string]int) string {
+	best, bestCount := "", -1
+	for surface, count := range surfaces {
+		if count > bestCount || (count == bestCount && surface < best) {
+			best, bestCount = surface, count
+		}
+	}
+	return best
+}
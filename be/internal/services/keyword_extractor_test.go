@@ -0,0 +1,314 @@
+package services
+
+import (
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"strings"
+	"testing"
+)
+
+func keywordKeys(ranked []RankedKeyword) []string {
+	keys := make([]string, len(ranked))
+	for i, kw := range ranked {
+		keys[i] = kw.Key
+	}
+	return keys
+}
+
+func findKeyword(ranked []RankedKeyword, key string) (RankedKeyword, bool) {
+	for _, kw := range ranked {
+		if kw.Key == key {
+			return kw, true
+		}
+	}
+	return RankedKeyword{}, false
+}
+
+func TestExtractKeywordsNGrams(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    KeywordOptions
+		want    []string // keys that must be ranked
+		notWant []string // keys that must not be ranked
+		first   string   // key ranked first, if set
+	}{
+		{
+			name:    "recurring bigram",
+			content: "Machine learning needs data. Machine learning finds patterns. Patterns repeat.",
+			opts:    KeywordOptions{Language: "en"},
+			want:    []string{"machine learning", "patterns"},
+			// "machine" never occurs outside the phrase
+			notWant: []string{"machine", "learning"},
+			first:   "machine learning",
+		},
+		{
+			name:    "recurring trigram",
+			content: "The support vector machine separates classes. A support vector machine is linear. Kernels extend it.",
+			opts:    KeywordOptions{Language: "en"},
+			want:    []string{"support vector machine"},
+			notWant: []string{"support", "vector"},
+			first:   "support vector machine",
+		},
+		{
+			name:    "phrase seen once",
+			content: "Gradient descent minimises loss. Loss measures error.",
+			opts:    KeywordOptions{Language: "en"},
+			want:    []string{"loss", "gradient"},
+			notWant: []string{"gradient descent"},
+			first:   "loss",
+		},
+		{
+			name:    "phrases stop at punctuation",
+			content: "Cells divide, membranes form. Cells divide, membranes grow.",
+			opts:    KeywordOptions{Language: "en"},
+			want:    []string{"cells divide"},
+			notWant: []string{"divide membranes"},
+		},
+		{
+			name:    "phrases do not start or end with stop words",
+			content: "The theory of evolution explains diversity. The theory of evolution is tested.",
+			opts:    KeywordOptions{Language: "en"},
+			want:    []string{"theory of evolution"},
+			notWant: []string{"the theory", "theory of", "of evolution"},
+		},
+		{
+			name:    "max n-gram of one",
+			content: "Machine learning needs data. Machine learning finds patterns.",
+			opts:    KeywordOptions{Language: "en", MaxNGram: 1},
+			want:    []string{"machine", "learning"},
+			notWant: []string{"machine learning"},
+		},
+		{
+			name:    "numbers and short words are skipped",
+			content: "In 1990 an ox ran. In 1990 an ox ran again.",
+			opts:    KeywordOptions{Language: "en", MaxNGram: 1},
+			want:    []string{"ran"},
+			notWant: []string{"1990", "ox"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := ExtractKeywords(tt.content, tt.opts, nil, 0)
+			for _, key := range tt.want {
+				if _, ok := findKeyword(ranked, key); !ok {
+					t.Errorf("%q not ranked, got %v", key, keywordKeys(ranked))
+				}
+			}
+			for _, key := range tt.notWant {
+				if _, ok := findKeyword(ranked, key); ok {
+					t.Errorf("%q ranked, got %v", key, keywordKeys(ranked))
+				}
+			}
+			if tt.first != "" && (len(ranked) == 0 || ranked[0].Key != tt.first) {
+				t.Errorf("ranked first: got %v, want %q", keywordKeys(ranked), tt.first)
+			}
+		})
+	}
+}
+
+func TestExtractKeywordsStemming(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		language  string
+		surfaces  []string // variants that share one key when stemmed
+		frequency int
+		term      string
+	}{
+		{
+			name:      "english plurals and verb forms",
+			content:   "Proteins fold. A protein folds quickly. Misfolded proteins clump.",
+			language:  "en",
+			surfaces:  []string{"proteins", "protein"},
+			frequency: 3,
+			term:      "proteins",
+		},
+		{
+			name:      "indonesian affixes",
+			content:   "Pembelajaran itu penting. Belajar setiap hari. Siswa belajar bersama.",
+			language:  "id",
+			surfaces:  []string{"pembelajaran", "belajar"},
+			frequency: 3,
+			term:      "belajar",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := ExtractKeywords(tt.content, KeywordOptions{Language: tt.language, MaxNGram: 1}, nil, 0)
+			for _, surface := range tt.surfaces {
+				if _, ok := findKeyword(plain, surface); !ok {
+					t.Errorf("without stemming %q not ranked on its own, got %v", surface, keywordKeys(plain))
+				}
+			}
+
+			stemmed := ExtractKeywords(tt.content, KeywordOptions{Language: tt.language, Stemming: true, MaxNGram: 1}, nil, 0)
+			key := stemmerFor(tt.language).Stem(tt.surfaces[0])
+			for _, surface := range tt.surfaces[1:] {
+				if other := stemmerFor(tt.language).Stem(surface); other != key {
+					t.Fatalf("%q stems to %q, %q to %q", tt.surfaces[0], key, surface, other)
+				}
+			}
+			kw, ok := findKeyword(stemmed, key)
+			if !ok {
+				t.Fatalf("stem %q not ranked, got %v", key, keywordKeys(stemmed))
+			}
+			if kw.Frequency != tt.frequency {
+				t.Errorf("frequency of %q: got %d, want %d", key, kw.Frequency, tt.frequency)
+			}
+			if kw.Term != tt.term {
+				t.Errorf("term of %q: got %q, want %q", key, kw.Term, tt.term)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"english", "The cell is the basic unit of life and it contains the genetic material.", "en"},
+		{"indonesian", "Sel adalah unit dasar dari kehidupan dan di dalamnya ada materi genetik yang penting.", "id"},
+		{"punctuation around words", "(Yang) dan, ini; untuk: dengan!", "id"},
+		{"no stop words", "Photosynthesis chlorophyll mitochondria", "en"},
+		{"empty", "", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.content); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractKeywordsCorpusRanking(t *testing.T) {
+	content := "Enzymes speed reactions. Enzymes bind substrates. Cells contain enzymes. " +
+		"Cells divide. Cells grow. Cells die."
+
+	// Within the text alone "cells" occurs more often and ranks first
+	alone := ExtractKeywords(content, KeywordOptions{Language: "en"}, nil, 0)
+	if len(alone) == 0 || alone[0].Key != "cells" {
+		t.Fatalf("without a corpus: got %v, want cells first", keywordKeys(alone))
+	}
+
+	// Every document of the library mentions cells, only this one enzymes
+	library := []string{
+		content,
+		"Cells are the unit of life.",
+		"Plant cells have walls.",
+		"Cells respire.",
+		"",
+	}
+	corpus := BuildKeywordCorpus(library, KeywordOptions{Language: "en"})
+	if corpus.Documents != 4 {
+		t.Errorf("corpus documents: got %d, want 4 (empty texts are skipped)", corpus.Documents)
+	}
+	if corpus.DocFreq["cells"] != 4 || corpus.DocFreq["enzymes"] != 1 {
+		t.Errorf("document frequencies: cells %d, enzymes %d", corpus.DocFreq["cells"], corpus.DocFreq["enzymes"])
+	}
+
+	ranked := ExtractKeywords(content, KeywordOptions{Language: "en"}, corpus, 0)
+	if len(ranked) == 0 || ranked[0].Key != "enzymes" {
+		t.Errorf("with a corpus: got %v, want enzymes first", keywordKeys(ranked))
+	}
+
+	// A corpus built with other stemming is ignored
+	stemmedCorpus := BuildKeywordCorpus(library, KeywordOptions{Language: "en", Stemming: true})
+	ranked = ExtractKeywords(content, KeywordOptions{Language: "en"}, stemmedCorpus, 0)
+	if len(ranked) == 0 || ranked[0].Key != "cells" {
+		t.Errorf("with a stemmed corpus: got %v, want cells first", keywordKeys(ranked))
+	}
+
+	limited := ExtractKeywords(content, KeywordOptions{Language: "en"}, corpus, 2)
+	if len(limited) != 2 {
+		t.Errorf("limit: got %d keywords, want 2", len(limited))
+	}
+}
+
+func TestExtractKeywordsStableOrder(t *testing.T) {
+	content := "Alpha beta gamma. Gamma beta alpha. Delta."
+	first := keywordKeys(ExtractKeywords(content, KeywordOptions{Language: "en"}, nil, 0))
+	for i := 0; i < 20; i++ {
+		got := keywordKeys(ExtractKeywords(content, KeywordOptions{Language: "en"}, nil, 0))
+		if strings.Join(got, ",") != strings.Join(first, ",") {
+			t.Fatalf("ranking changed between runs: %v, then %v", first, got)
+		}
+	}
+}
+
+func TestKeywordCorpusCache(t *testing.T) {
+	ds := NewDocumentService(nil, nil, nil)
+	opts := KeywordOptions{Language: "en", Stemming: true, MaxNGram: 3}
+	cached := &models.KeywordCorpus{Documents: 1, DocFreq: map[string]int{"cell": 1}, Stemmed: true}
+	other := &models.KeywordCorpus{Documents: 2, DocFreq: map[string]int{}}
+
+	ds.corpora["u1|en|true|3"] = cached
+	ds.corpora["u1|en|false|3"] = cached
+	ds.corpora["u10|en|true|3"] = other
+
+	corpus, err := ds.GetKeywordCorpus("u1", opts)
+	if err != nil {
+		t.Fatalf("GetKeywordCorpus: %v", err)
+	}
+	if corpus != cached {
+		t.Errorf("cached corpus was not reused")
+	}
+
+	// Uploads only drop the corpora of their own user, not of users sharing a prefix
+	ds.invalidateCorpus("u1")
+	if _, ok := ds.corpora["u1|en|true|3"]; ok {
+		t.Errorf("corpus of u1 kept after invalidation")
+	}
+	if _, ok := ds.corpora["u1|en|false|3"]; ok {
+		t.Errorf("corpus of u1 without stemming kept after invalidation")
+	}
+	if ds.corpora["u10|en|true|3"] != other {
+		t.Errorf("corpus of u10 dropped by invalidating u1")
+	}
+}
+
+// benchmarkDocument builds a text of about the size of a long textbook chapter
+func benchmarkDocument(words int) string {
+	topics := []string{"cell membrane", "protein synthesis", "genetic code", "natural selection", "energy transfer"}
+	filler := []string{"the", "process", "of", "which", "describes", "how", "organisms", "adapt", "and", "change", "over", "time"}
+
+	var b strings.Builder
+	for i := 0; i < words; i++ {
+		switch {
+		case i%40 == 39:
+			b.WriteString(". ")
+		case i%7 == 0:
+			b.WriteString(topics[(i/7)%len(topics)])
+			b.WriteByte(' ')
+		default:
+			// Vary the filler so the text has a realistic vocabulary
+			b.WriteString(filler[i%len(filler)])
+			b.WriteByte(byte('a' + i%26))
+			b.WriteByte(byte('a' + (i/26)%26))
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+func BenchmarkExtractKeywords(b *testing.B) {
+	content := benchmarkDocument(200000)
+	for _, opts := range []KeywordOptions{
+		{Language: "en"},
+		{Language: "en", Stemming: true},
+	} {
+		b.Run(fmt.Sprintf("stemming=%t", opts.Stemming), func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				ExtractKeywords(content, opts, nil, 50)
+			}
+		})
+	}
+}
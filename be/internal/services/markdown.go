@@ -0,0 +1,298 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	mdATXHeading       = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdSetextUnderline  = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdThematicBreak    = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFence            = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+	mdListItem         = regexp.MustCompile(`^([ \t]*)([*+-]|\d{1,9}[.)])[ \t]+(.*)$`)
+	mdBlockquote       = regexp.MustCompile(`^ {0,3}>[ \t]?`)
+	mdTableSeparator   = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdLinkDefinition   = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:[ \t]*\S+`)
+	mdScriptOrStyle    = regexp.MustCompile(`(?is)<script\b.*?</script\s*>|<style\b.*?</style\s*>`)
+	mdHTMLComment      = regexp.MustCompile(`(?s)<!--.*?-->`)
+	mdInlineCode       = regexp.MustCompile("(`+)(.+?)(`+)")
+	mdImage            = regexp.MustCompile(`!\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	mdLink             = regexp.MustCompile(`\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink         = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdHTMLTag          = regexp.MustCompile(`</?[A-Za-z][^>]*>`)
+	mdStrong           = regexp.MustCompile(`(\*\*|__)([^*_\s](?:.*?[^*_\s])?)(\*\*|__)`)
+	mdStarEmphasis     = regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*([^\w*]|$)`)
+	mdUnderlineEmph    = regexp.MustCompile(`(^|[^\w])_([^_\s](?:[^_]*[^_\s])?)_([^\w]|$)`)
+	mdStrikethrough    = regexp.MustCompile(`~~(.+?)~~`)
+	mdBackslashEscape  = regexp.MustCompile(`\\([!-/:-@\[-` + "`" + `{-~])`)
+	mdFrontMatterField = regexp.MustCompile(`^([A-Za-z_]+)\s*:\s*(.*)$`)
+)
+
+// markdownReader turns Markdown into structured text. Markup is dropped and only the
+// text it marks is kept: link and image text, emphasised words, code.
+type markdownReader struct {
+	blocks     []textBlock
+	paragraph  []string
+	listIndent []int // indentation of the enclosing list items
+	counters   []int
+	ordered    []bool
+	code       []string
+	fence      string
+	blank      bool // the previous line was blank
+}
+
+// processMarkdownFile extracts the text of a Markdown file as one page per section
+func (fs *FileService) processMarkdownFile(content []byte) ([]models.DocumentPage, error) {
+	text, err := fs.processTXTFile(content)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := sectionPages(markdownBlocks(text))
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no text content found in Markdown file")
+	}
+	return pages, nil
+}
+
+// markdownBlocks converts Markdown into blocks of structured text
+func markdownBlocks(text string) []textBlock {
+	_, body := markdownFrontMatter(text)
+	body = mdScriptOrStyle.ReplaceAllString(body, "")
+	body = mdHTMLComment.ReplaceAllString(body, "")
+
+	r := &markdownReader{}
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		r.readLine(strings.TrimRight(line, " \t\r"))
+	}
+	r.endCode()
+	r.endParagraph()
+	return r.blocks
+}
+
+func (r *markdownReader) readLine(line string) {
+	// Code is kept verbatim but indented, so "#" comments are not read as headings
+	if r.fence != "" {
+		if strings.HasPrefix(strings.TrimSpace(line), r.fence) {
+			r.endCode()
+			r.fence = ""
+			return
+		}
+		r.code = append(r.code, line)
+		return
+	}
+	if match := mdFence.FindStringSubmatch(line); match != nil {
+		r.endParagraph()
+		r.fence = match[1][:3]
+		return
+	}
+	if strings.HasPrefix(line, "    ") && len(r.paragraph) == 0 && len(r.listIndent) == 0 {
+		r.code = append(r.code, strings.TrimPrefix(line, "    "))
+		return
+	}
+	r.endCode()
+
+	// Quotes are read as the text they quote
+	for mdBlockquote.MatchString(line) {
+		line = mdBlockquote.ReplaceAllString(line, "")
+	}
+
+	// After a blank line, a line that is not indented ends the list
+	blank := r.blank
+	r.blank = strings.TrimSpace(line) == ""
+	if blank && !r.blank && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") && !mdListItem.MatchString(line) {
+		r.listIndent, r.counters, r.ordered = nil, nil, nil
+	}
+
+	switch {
+	case r.blank:
+		r.endParagraph()
+	case len(r.paragraph) > 0 && mdSetextUnderline.MatchString(line):
+		// A line of "=" or "-" under a paragraph makes it a heading
+		level := 1
+		if strings.Contains(line, "-") {
+			level = 2
+		}
+		title := strings.Join(r.paragraph, " ")
+		r.paragraph = nil
+		r.addBlock(textBlock{text: strings.Repeat("#", level) + " " + title})
+	case mdThematicBreak.MatchString(line), mdLinkDefinition.MatchString(line), mdTableSeparator.MatchString(line) && strings.Contains(line, "|"):
+		r.endParagraph()
+	case mdATXHeading.MatchString(line):
+		r.endParagraph()
+		match := mdATXHeading.FindStringSubmatch(line)
+		if title := markdownInline(match[2]); title != "" {
+			r.addBlock(textBlock{text: match[1] + " " + title})
+		}
+	case mdListItem.MatchString(line):
+		r.endParagraph()
+		r.listItem(mdListItem.FindStringSubmatch(line))
+	case strings.HasPrefix(strings.TrimSpace(line), "|"):
+		r.endParagraph()
+		r.tableRow(strings.TrimSpace(line), !blank)
+	default:
+		// Text after a list item that is not a list item itself continues it
+		if len(r.paragraph) == 0 && len(r.listIndent) > 0 && len(r.blocks) > 0 && r.blocks[len(r.blocks)-1].listItem {
+			if text := markdownInline(line); text != "" {
+				r.blocks[len(r.blocks)-1].text += " " + text
+			}
+			return
+		}
+		r.paragraph = append(r.paragraph, markdownInline(strings.TrimSpace(line)))
+	}
+}
+
+// listItem writes a list item indented by its nesting level, which is found from the
+// indentation of the items around it
+func (r *markdownReader) listItem(match []string) {
+	indent := len(strings.ReplaceAll(match[1], "\t", "    "))
+	for len(r.listIndent) > 0 && r.listIndent[len(r.listIndent)-1] > indent {
+		r.listIndent = r.listIndent[:len(r.listIndent)-1]
+		r.counters = r.counters[:len(r.counters)-1]
+		r.ordered = r.ordered[:len(r.ordered)-1]
+	}
+	ordered := !strings.ContainsAny(match[2], "*+-")
+	if len(r.listIndent) == 0 || r.listIndent[len(r.listIndent)-1] < indent {
+		r.listIndent = append(r.listIndent, indent)
+		r.counters = append(r.counters, 0)
+		r.ordered = append(r.ordered, ordered)
+	}
+	level := len(r.listIndent) - 1
+	if r.ordered[level] != ordered {
+		// A different kind of marker starts a new list
+		r.counters[level], r.ordered[level] = 0, ordered
+	}
+	r.counters[level]++
+
+	marker := "-"
+	if ordered {
+		// Markdown renders a list from its first number on, whatever the later items say
+		first, _ := strconv.Atoi(strings.TrimRight(match[2], ".)"))
+		if r.counters[level] == 1 {
+			r.counters[level] = max(first, 1)
+		}
+		marker = strconv.Itoa(r.counters[level]) + "."
+	}
+	r.addBlock(textBlock{text: strings.Repeat("  ", level) + marker + " " + markdownInline(match[3]), listItem: true})
+}
+
+// tableRow writes a table row with the markup removed from every cell
+func (r *markdownReader) tableRow(line string, continues bool) {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	var cells []string
+	for _, cell := range strings.Split(line, "|") {
+		cells = append(cells, markdownInline(cell))
+	}
+	row := "| " + strings.Join(cells, " | ") + " |"
+
+	// Rows of one table stay together in one block
+	if n := len(r.blocks); continues && n > 0 && strings.HasPrefix(r.blocks[n-1].text, "| ") {
+		r.blocks[n-1].text += "\n" + row
+		return
+	}
+	r.addBlock(textBlock{text: row})
+}
+
+func (r *markdownReader) addBlock(block textBlock) {
+	if strings.TrimSpace(block.text) != "" {
+		r.blocks = append(r.blocks, block)
+	}
+}
+
+// endParagraph writes the lines of a paragraph as one block, since Markdown wraps
+// paragraphs over lines only for the writer's convenience
+func (r *markdownReader) endParagraph() {
+	if len(r.paragraph) == 0 {
+		return
+	}
+	r.addBlock(textBlock{text: strings.Join(strings.Fields(strings.Join(r.paragraph, " ")), " ")})
+	r.paragraph = nil
+}
+
+func (r *markdownReader) endCode() {
+	if len(r.code) == 0 {
+		return
+	}
+	for len(r.code) > 0 && strings.TrimSpace(r.code[len(r.code)-1]) == "" {
+		r.code = r.code[:len(r.code)-1]
+	}
+	lines := make([]string, len(r.code))
+	for i, line := range r.code {
+		lines[i] = "    " + line
+	}
+	r.addBlock(textBlock{text: strings.Join(lines, "\n")})
+	r.code = nil
+}
+
+// markdownInline removes the inline markup of a line of Markdown. Code spans are kept as
+// they are written.
+func markdownInline(line string) string {
+	var text strings.Builder
+	last := 0
+	for _, span := range mdInlineCode.FindAllStringSubmatchIndex(line, -1) {
+		text.WriteString(markdownInlineText(line[last:span[0]]))
+		text.WriteString(strings.TrimSpace(line[span[4]:span[5]]))
+		last = span[1]
+	}
+	text.WriteString(markdownInlineText(line[last:]))
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+func markdownInlineText(text string) string {
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllString(text, "$1")
+	text = mdAutolink.ReplaceAllString(text, "$1")
+	text = mdHTMLTag.ReplaceAllString(text, "")
+	text = mdStrong.ReplaceAllString(text, "$2")
+	text = mdStarEmphasis.ReplaceAllString(text, "$1$2$3")
+	text = mdUnderlineEmph.ReplaceAllString(text, "$1$2$3")
+	text = mdStrikethrough.ReplaceAllString(text, "$1")
+	text = mdBackslashEscape.ReplaceAllString(text, "$1")
+	return html.UnescapeString(text)
+}
+
+// markdownFrontMatter splits the YAML front matter off a Markdown file and returns its
+// top-level fields
+func markdownFrontMatter(text string) (map[string]string, string) {
+	text = strings.TrimPrefix(text, "\ufeff")
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return nil, text
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		end := strings.TrimSpace(lines[i])
+		if end != "---" && end != "..." {
+			continue
+		}
+
+		fields := make(map[string]string)
+		for _, line := range lines[1:i] {
+			if match := mdFrontMatterField.FindStringSubmatch(strings.TrimRight(line, "\r")); match != nil {
+				fields[strings.ToLower(match[1])] = strings.Trim(strings.TrimSpace(match[2]), `"'`)
+			}
+		}
+		return fields, strings.Join(lines[i+1:], "\n")
+	}
+	return nil, text
+}
+
+// markdownMetadata reads the title, author and other fields of a Markdown file's front
+// matter
+func markdownMetadata(content []byte) map[string]string {
+	metadata := make(map[string]string)
+	fields, _ := markdownFrontMatter(string(content))
+	for _, key := range []string{"title", "author", "subject", "keywords", "description"} {
+		setMetadata(metadata, key, fields[key])
+	}
+	if _, ok := metadata["subject"]; !ok {
+		setMetadata(metadata, "subject", fields["description"])
+	}
+	delete(metadata, "description")
+	return metadata
+}
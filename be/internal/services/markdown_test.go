@@ -0,0 +1,56 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"testing"
+)
+
+func TestProcessMarkdownFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []models.DocumentPage // nil when the file has no text
+	}{
+		{name: "empty file"},
+		{name: "blank lines", content: "  \n\n\t\n"},
+		{name: "only scripts and comments", content: "<script>alert(1)</script>\n<!-- note -->\n"},
+		{
+			name: "sections, lists, tables and code",
+			content: "---\ntitle: Cells\n---\nIntro with **bold** and [a link](http://example.com).\n\n" +
+				"# Cells\n\nThe *nucleus* holds `DNA`.\n\n- Mitosis\n- Meiosis\n  1. First division\n\n" +
+				"| Part | Role |\n|---|---|\n| Nucleus | Holds DNA |\n\n```\n# not a heading\n```\n\n" +
+				"Osmosis\n-------\n\nWater moves.\n",
+			want: []models.DocumentPage{
+				{Number: 1, Text: "Intro with bold and a link."},
+				{Number: 2, Label: "Cells", Text: "# Cells\n\nThe nucleus holds DNA.\n\n- Mitosis\n- Meiosis\n  1. First division\n\n" +
+					"| Part | Role |\n| Nucleus | Holds DNA |\n\n    # not a heading"},
+				{Number: 3, Label: "Osmosis", Text: "## Osmosis\n\nWater moves."},
+			},
+		},
+		{
+			name:    "unclosed markup",
+			content: "Unclosed **bold and <b>tag\n\n```\nnever closed",
+			want:    []models.DocumentPage{{Number: 1, Text: "Unclosed **bold and tag\n\n    never closed"}},
+		},
+	}
+
+	fs := NewFileService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := fs.processMarkdownFile([]byte(tt.content))
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("no error, pages %+v", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processMarkdownFile: %v", err)
+			}
+			if !reflect.DeepEqual(pages, tt.want) {
+				t.Errorf("pages = %+v\nwant %+v", pages, tt.want)
+			}
+		})
+	}
+}
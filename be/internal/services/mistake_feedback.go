@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// ExplainMistake explains why the chosen option of a question is wrong and why the key is
+// right. The explanation is grounded in the passage of the source document that best
+// matches the question when an index is given. Without an LLM provider the question's
+// stored explanation is used, or else the source passage is quoted.
+func (ai *AIService) ExplainMistake(q models.Question, chosen string, index *DocumentIndex) *models.MistakeExplanation {
+	stem := q.Text
+	if stem == "" {
+		stem = q.Question
+	}
+	key, _ := questionKey(q)
+
+	explanation := &models.MistakeExplanation{
+		QuestionID:    q.ID,
+		QuestionText:  stem,
+		UserAnswer:    chosen,
+		CorrectAnswer: key,
+	}
+	if index != nil {
+		if passages := index.searchBM25(stem+" "+key, 1); len(passages) > 0 {
+			explanation.Source = &passages[0]
+		}
+	}
+
+	reply, err := ai.completeText(
+		"You are a patient tutor giving feedback on a quiz. Explain mistakes kindly and concretely, using only the facts in the question and the source passage.",
+		mistakePrompt(stem, q.Options, chosen, key, q.Explanation, explanation.Source),
+		400,
+	)
+	if err == nil && strings.TrimSpace(reply) != "" {
+		explanation.Explanation = strings.TrimSpace(reply)
+		explanation.Method = "llm"
+		return explanation
+	}
+	if err != nil {
+		ai.logger.Warnf("LLM mistake explanation failed: %v, using stored explanation", err)
+	}
+
+	answers := fmt.Sprintf("You chose %q, but the correct answer is %q.", chosen, key)
+	switch {
+	case strings.TrimSpace(q.Explanation) != "":
+		explanation.Explanation = answers + " " + strings.TrimSpace(q.Explanation)
+		explanation.Method = "stored"
+	case explanation.Source != nil:
+		explanation.Explanation = fmt.Sprintf("%s The document says: %q (%s)",
+			answers, supportingSentence(explanation.Source.Excerpt, key), explanation.Source.Ref())
+		explanation.Method = "source"
+	default:
+		explanation.Explanation = answers
+		explanation.Method = "generic"
+	}
+	return explanation
+}
+
+func mistakePrompt(stem string, options []string, chosen, key, stored string, source *models.TutorCitation) string {
+	var prompt strings.Builder
+
+	fmt.Fprintf(&prompt, "Question: %s\nOptions:\n", stem)
+	for _, option := range options {
+		fmt.Fprintf(&prompt, "- %s\n", option)
+	}
+	fmt.Fprintf(&prompt, "Student's answer: %s\nCorrect answer: %s\n", chosen, key)
+	if strings.TrimSpace(stored) != "" {
+		fmt.Fprintf(&prompt, "Explanation of the correct answer: %s\n", stored)
+	}
+	if source != nil {
+		fmt.Fprintf(&prompt, "\nSource passage (%s):\n%s\n", source.Ref(), source.Excerpt)
+	}
+
+	prompt.WriteString(`
+Requirements:
+- Speak to the student directly in 2 to 4 sentences
+- Explain why their answer is wrong, pointing out the likely confusion
+- Explain why the correct answer is right, based on the source passage when one is given
+- Answer in the same language as the question
+
+Explanation:`)
+	return prompt.String()
+}
+
+// supportingSentence returns the sentence of a passage that mentions the key, or the
+// start of the passage when none does
+func supportingSentence(passage, key string) string {
+	for _, sentence := range strings.SplitAfter(passage, ". ") {
+		if key != "" && containsWholeWord(strings.ToLower(sentence), strings.ToLower(key)) {
+			if cleaned := cleanNoteSentence(sentence); cleaned != "" {
+				return cleaned
+			}
+			return strings.TrimSpace(sentence)
+		}
+	}
+	if utf8.RuneCountInString(passage) > 200 {
+		return trimToWord(firstRunes(passage, 200), true) + "..."
+	}
+	return passage
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSupportingSentenceKeepsCharactersWhole(t *testing.T) {
+	// No sentence mentions the key, so the start of the passage is quoted. Without a space
+	// to trim back to, the quote ends wherever the limit falls.
+	passage := "A" + strings.Repeat("ä", 300)
+
+	got := supportingSentence(passage, "Photosynthese")
+	if !utf8.ValidString(got) {
+		t.Errorf("quote is not valid UTF-8: %q", got)
+	}
+	if !strings.HasSuffix(got, "...") || utf8.RuneCountInString(got) != 203 {
+		t.Errorf("quote was not trimmed to 200 characters: %q", got)
+	}
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NormalizeCase normalises the paragraphs of a golden case input in the case's language.
+// A case is an input file named <case>.<language>.txt holding paragraphs separated by
+// blank lines; it may start with a "steps:" line choosing the steps to run, as the
+// normalize upload field does. The output holds one normalised paragraph per line.
+func NormalizeCase(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	language := filepath.Ext(strings.TrimSuffix(filepath.Base(path), ".txt"))
+	opts := NormalizeOptions{Language: strings.TrimPrefix(language, ".")}
+	if first, rest, _ := strings.Cut(text, "\n"); strings.HasPrefix(first, "steps:") {
+		if opts.Steps, err = ParseNormalizeSteps(strings.TrimPrefix(first, "steps:")); err != nil {
+			return "", err
+		}
+		text = rest
+	}
+	normalizer := opts.Normalizer(text)
+
+	var paragraphs []string
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, normalizer.Normalize(paragraph))
+		}
+	}
+	return strings.Join(paragraphs, "\n") + "\n", nil
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"pbkk-quizlit-backend/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoPDFText is returned for PDFs no text could be read from
+var ErrNoPDFText = errors.New("no text content found in PDF")
+
+// lowOCRConfidence is the confidence below which OCR text is worth checking
+const lowOCRConfidence = 0.6
+
+// OCREngine reads the text of PDF pages that have no text layer, as scanned pages do
+type OCREngine interface {
+	// Name identifies the engine in the pages it read
+	Name() string
+	// Recognize reads the given pages of a PDF. Pages the engine could not read are left
+	// out of the result.
+	Recognize(ctx context.Context, pdf []byte, pages []int) ([]OCRPage, error)
+}
+
+// OCRPage is the text read from a page. Confidence is between 0 and 1, or 0 when the
+// engine does not report it.
+type OCRPage struct {
+	Number     int
+	Text       string
+	Confidence float64
+}
+
+// OCROptions selects and configures an OCR backend
+type OCROptions struct {
+	Backend string        // "" or "none", "command" or "stub"
+	Command string        // shell command of the command backend
+	Timeout time.Duration // time allowed per page
+}
+
+// OpenOCR creates the OCR engine selected by the options. It returns nil when OCR is
+// turned off.
+func OpenOCR(opts OCROptions) (OCREngine, error) {
+	switch opts.Backend {
+	case "", "none":
+		return nil, nil
+	case "command":
+		if strings.TrimSpace(opts.Command) == "" {
+			return nil, fmt.Errorf("the command OCR backend needs a command")
+		}
+		return &CommandOCR{Command: opts.Command, Timeout: opts.Timeout}, nil
+	case "stub":
+		return &StubOCR{Text: "Text of scanned page {page}.", Confidence: 1}, nil
+	default:
+		return nil, fmt.Errorf("unknown OCR backend %q", opts.Backend)
+	}
+}
+
+// CommandOCR runs a command-line OCR engine once per page. The command is run by sh with
+// the path of the PDF as $1 and the page number as $2, and prints the text of the page,
+// e.g.
+//
+//	pdftoppm -f "$2" -l "$2" -r 300 -png "$1" | tesseract stdin stdout -l eng+ind tsv
+//
+// Output in Tesseract's TSV format is read word by word with its confidences; any other
+// output is taken as plain text without a confidence.
+type CommandOCR struct {
+	Command string
+	Timeout time.Duration
+}
+
+func (o *CommandOCR) Name() string {
+	return "command"
+}
+
+func (o *CommandOCR) Recognize(ctx context.Context, pdf []byte, pages []int) ([]OCRPage, error) {
+	file, err := os.CreateTemp("", "ocr-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(pdf)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	var results []OCRPage
+	var lastErr error
+	for _, number := range pages {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		output, err := o.run(ctx, file.Name(), number)
+		if err != nil {
+			lastErr = fmt.Errorf("page %d: %w", number, err)
+			continue
+		}
+
+		page := OCRPage{Number: number, Text: strings.TrimSpace(output)}
+		if text, confidence, ok := parseTesseractTSV(output); ok {
+			page.Text, page.Confidence = text, confidence
+		}
+		if page.Text != "" {
+			results = append(results, page)
+		}
+	}
+
+	// A command that fails on every page is misconfigured rather than unlucky
+	if len(results) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return results, nil
+}
+
+// run runs the command for one page and returns what it printed
+func (o *CommandOCR) run(ctx context.Context, path string, page int) (string, error) {
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", o.Command, "ocr", path, strconv.Itoa(page))
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("OCR timed out after %s", o.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, lastLine(msg))
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// lastLine returns the last line of a command's error output, which usually says what
+// went wrong
+func lastLine(s string) string {
+	return s[strings.LastIndex(s, "\n")+1:]
+}
+
+// parseTesseractTSV reads the words of Tesseract's TSV output back into text, a line per
+// line and a blank line between paragraphs, with the mean confidence of the words
+func parseTesseractTSV(output string) (string, float64, bool) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "level\tpage_num") {
+		return "", 0, false
+	}
+
+	var text strings.Builder
+	var lastPara, lastLineKey string
+	total, words := 0.0, 0
+	for _, line := range lines[1:] {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		// level, page, block, paragraph, line, word, left, top, width, height, conf, text
+		if len(fields) < 12 || fields[0] != "5" || strings.TrimSpace(fields[11]) == "" {
+			continue
+		}
+		para := fields[2] + "." + fields[3]
+		lineKey := para + "." + fields[4]
+		switch {
+		case text.Len() == 0:
+		case para != lastPara:
+			text.WriteString("\n\n")
+		case lineKey != lastLineKey:
+			text.WriteString("\n")
+		default:
+			text.WriteString(" ")
+		}
+		text.WriteString(strings.TrimSpace(fields[11]))
+		lastPara, lastLineKey = para, lineKey
+
+		if conf, err := strconv.ParseFloat(fields[10], 64); err == nil && conf >= 0 {
+			total += conf
+			words++
+		}
+	}
+
+	confidence := 0.0
+	if words > 0 {
+		confidence = total / float64(words) / 100
+	}
+	return text.String(), confidence, true
+}
+
+// StubOCR returns the same text for every page, with "{page}" replaced by the page
+// number. It stands in for a real engine in tests and demos.
+type StubOCR struct {
+	Text       string
+	Confidence float64
+}
+
+func (o *StubOCR) Name() string {
+	return "stub"
+}
+
+func (o *StubOCR) Recognize(ctx context.Context, pdf []byte, pages []int) ([]OCRPage, error) {
+	results := make([]OCRPage, 0, len(pages))
+	for _, number := range pages {
+		results = append(results, OCRPage{
+			Number:     number,
+			Text:       strings.ReplaceAll(o.Text, "{page}", strconv.Itoa(number)),
+			Confidence: o.Confidence,
+		})
+	}
+	return results, nil
+}
+
+// recognizePDFPages reads the pages of a PDF that have no text by OCR and puts them among
+// the other pages in page order. numbers are the numbers of all pages of the PDF.
+func (fs *FileService) recognizePDFPages(ctx context.Context, file io.ReaderAt, size int64, layouts []pdfPageLayout, numbers []int) ([]pdfPageLayout, error) {
+	byNumber := make(map[int]pdfPageLayout, len(layouts))
+	for _, layout := range layouts {
+		if len(layout.paragraphs) > 0 || strings.TrimSpace(layout.plain) != "" {
+			byNumber[layout.number] = layout
+		}
+	}
+	var blank []int
+	for _, number := range numbers {
+		if _, ok := byNumber[number]; !ok {
+			blank = append(blank, number)
+		}
+	}
+	if len(blank) == 0 {
+		return layouts, nil
+	}
+
+	results, err := fs.ocr.Recognize(ctx, readHead(file, int(size)), blank)
+	for _, result := range results {
+		byNumber[result.Number] = pdfPageLayout{
+			number: result.Number,
+			plain:  result.Text,
+			ocr:    &models.PageOCR{Engine: fs.ocr.Name(), Confidence: result.Confidence},
+		}
+	}
+
+	merged := make([]pdfPageLayout, 0, len(byNumber))
+	for _, number := range numbers {
+		if layout, ok := byNumber[number]; ok {
+			merged = append(merged, layout)
+		}
+	}
+	return merged, err
+}
+
+// DocumentOCR sums up the pages of a document read by OCR, or returns nil when no page
+// was
+func DocumentOCR(pages []models.DocumentPage) *models.DocumentOCR {
+	var report *models.DocumentOCR
+	total, rated := 0.0, 0
+	for _, page := range pages {
+		if page.OCR == nil {
+			continue
+		}
+		if report == nil {
+			report = &models.DocumentOCR{Engine: page.OCR.Engine}
+		}
+		report.Pages = append(report.Pages, page.Number)
+		if page.OCR.Confidence > 0 {
+			total += page.OCR.Confidence
+			rated++
+			if page.OCR.Confidence < lowOCRConfidence {
+				report.LowConfidencePages = append(report.LowConfidencePages, page.Number)
+			}
+		}
+	}
+	if report != nil && rated > 0 {
+		report.Confidence = total / float64(rated)
+	}
+	return report
+}
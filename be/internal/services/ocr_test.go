@@ -0,0 +1,148 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTesseractTSV(t *testing.T) {
+	const header = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n"
+	tests := []struct {
+		name       string
+		output     string
+		text       string
+		confidence float64
+		ok         bool
+	}{
+		{name: "empty output"},
+		{name: "plain text", output: "Cells divide by mitosis."},
+		{name: "header only", output: header, ok: true},
+		{
+			name: "lines and paragraphs",
+			output: header +
+				"1\t1\t0\t0\t0\t0\t0\t0\t600\t800\t-1\t\n" +
+				"5\t1\t1\t1\t1\t1\t10\t10\t40\t12\t90\tCells\n" +
+				"5\t1\t1\t1\t1\t2\t60\t10\t40\t12\t80\tdivide\n" +
+				"5\t1\t1\t1\t2\t1\t10\t30\t40\t12\t70\tquickly.\n" +
+				"5\t1\t1\t1\t2\t2\t60\t30\t40\t12\t-1\t \n" +
+				"5\t1\t1\t2\t1\t1\t10\t60\t40\t12\t60\tMitosis\r\n" +
+				"5\t1\t1\t2\t1\t2\t10\t60\t40\t12\tbad\tends\n" +
+				"5\t1\t1\t2\t1\n",
+			text:       "Cells divide\nquickly.\n\nMitosis ends",
+			confidence: 0.75,
+			ok:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, confidence, ok := parseTesseractTSV(tt.output)
+			if ok != tt.ok || text != tt.text || confidence != tt.confidence {
+				t.Errorf("parseTesseractTSV = %q, %v, %v, want %q, %v, %v", text, confidence, ok, tt.text, tt.confidence, tt.ok)
+			}
+		})
+	}
+}
+
+func TestOpenOCR(t *testing.T) {
+	tests := []struct {
+		opts OCROptions
+		name string // name of the engine, "" for none
+		fail bool
+	}{
+		{opts: OCROptions{}},
+		{opts: OCROptions{Backend: "none"}},
+		{opts: OCROptions{Backend: "stub"}, name: "stub"},
+		{opts: OCROptions{Backend: "command", Command: "cat \"$1\""}, name: "command"},
+		{opts: OCROptions{Backend: "command", Command: "  "}, fail: true},
+		{opts: OCROptions{Backend: "tesseract"}, fail: true},
+	}
+
+	for _, tt := range tests {
+		engine, err := OpenOCR(tt.opts)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("OpenOCR(%+v): no error", tt.opts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("OpenOCR(%+v): %v", tt.opts, err)
+			continue
+		}
+		if name := ""; engine != nil {
+			name = engine.Name()
+			if name != tt.name {
+				t.Errorf("OpenOCR(%+v) opened %q, want %q", tt.opts, name, tt.name)
+			}
+		} else if tt.name != "" {
+			t.Errorf("OpenOCR(%+v) opened no engine, want %q", tt.opts, tt.name)
+		}
+	}
+}
+
+func TestCommandOCR(t *testing.T) {
+	ctx := context.Background()
+
+	// Page 2 fails, the others print their number
+	engine := &CommandOCR{Command: `if [ "$2" = 2 ]; then echo "no page" >&2; exit 1; fi; echo "Page $2"`}
+	pages, err := engine.Recognize(ctx, []byte("%PDF-1.4"), []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("Recognize: %v", err)
+	}
+	want := []OCRPage{{Number: 1, Text: "Page 1"}, {Number: 3, Text: "Page 3"}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %+v, want %+v", pages, want)
+	}
+
+	// A command failing on every page is an error, with the last line it printed
+	engine = &CommandOCR{Command: `echo "warming up" >&2; echo "tesseract: not found" >&2; exit 127`}
+	if _, err := engine.Recognize(ctx, nil, []int{1}); err == nil || !strings.Contains(err.Error(), "tesseract: not found") {
+		t.Errorf("Recognize with a failing command: %v", err)
+	}
+}
+
+func TestRecognizePDFPages(t *testing.T) {
+	fs := NewFileService().WithOCR(&StubOCR{Text: "Scanned page {page}.", Confidence: 0.5})
+	layouts := []pdfPageLayout{
+		{number: 1, plain: "Cells divide."},
+		{number: 2, plain: "  "},
+	}
+
+	merged, err := fs.recognizePDFPages(context.Background(), bytes.NewReader(nil), 0, layouts, []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("recognizePDFPages: %v", err)
+	}
+	if len(merged) != 3 {
+		t.Fatalf("%d pages, want 3", len(merged))
+	}
+	for i, layout := range merged {
+		if layout.number != i+1 {
+			t.Errorf("page %d is in position %d", layout.number, i+1)
+		}
+	}
+	if merged[0].ocr != nil || merged[1].plain != "Scanned page 2." || merged[2].ocr == nil || merged[2].ocr.Engine != "stub" {
+		t.Errorf("pages = %+v", merged)
+	}
+}
+
+func TestDocumentOCR(t *testing.T) {
+	if report := DocumentOCR([]models.DocumentPage{{Number: 1, Text: "Cells"}}); report != nil {
+		t.Errorf("report for a document without OCR: %+v", report)
+	}
+
+	pages := []models.DocumentPage{
+		{Number: 1, Text: "Cells"},
+		{Number: 2, OCR: &models.PageOCR{Engine: "command", Confidence: 0.9}},
+		{Number: 3, OCR: &models.PageOCR{Engine: "command", Confidence: 0.4}},
+		{Number: 4, OCR: &models.PageOCR{Engine: "command"}},
+	}
+	want := &models.DocumentOCR{Engine: "command", Pages: []int{2, 3, 4}, Confidence: 0.65, LowConfidencePages: []int{3}}
+	if report := DocumentOCR(pages); !reflect.DeepEqual(report, want) {
+		t.Errorf("DocumentOCR = %+v, want %+v", report, want)
+	}
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// zipArchive is a zip-based document, such as an Office file or an EPUB, read within the
+// limits of its upload, so a small zip cannot expand into gigabytes of XML: it may have
+// up to maxEntries files, and the parts read from it may decompress to maxSize bytes in
+// all. Zero means no limit.
+type zipArchive struct {
+	*zip.Reader
+	maxSize int64
+	read    int64
+}
+
+// openZipArchive opens a zip-based document, refusing archives with more entries than
+// the limit
+func openZipArchive(content []byte, limits UploadLimits) (*zipArchive, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, err
+	}
+	if limits.MaxArchiveEntries > 0 && len(reader.File) > limits.MaxArchiveEntries {
+		return nil, fmt.Errorf("%w: it has %d files, the limit is %d", ErrArchiveTooLarge, len(reader.File), limits.MaxArchiveEntries)
+	}
+	return &zipArchive{Reader: reader, maxSize: limits.MaxArchiveSize}, nil
+}
+
+// officeMetadata reads the title, author and other properties of a Word or PowerPoint
+// file
+func officeMetadata(content []byte, limits UploadLimits) map[string]string {
+	metadata := make(map[string]string)
+
+	archive, err := openZipArchive(content, limits)
+	if err != nil {
+		return metadata
+	}
+
+	if core, err := readZipPart(archive, "docProps/core.xml"); err == nil {
+		var props struct {
+			Title    string `xml:"title"`
+			Creator  string `xml:"creator"`
+			Subject  string `xml:"subject"`
+			Keywords string `xml:"keywords"`
+		}
+		if xml.Unmarshal(core, &props) == nil {
+			setMetadata(metadata, "title", props.Title)
+			setMetadata(metadata, "author", props.Creator)
+			setMetadata(metadata, "subject", props.Subject)
+			setMetadata(metadata, "keywords", props.Keywords)
+		}
+	}
+
+	if app, err := readZipPart(archive, "docProps/app.xml"); err == nil {
+		var props struct {
+			Application string `xml:"Application"`
+		}
+		if xml.Unmarshal(app, &props) == nil {
+			setMetadata(metadata, "creator", props.Application)
+		}
+	}
+
+	return metadata
+}
+
+func setMetadata(metadata map[string]string, key, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		metadata[key] = value
+	}
+}
+
+// readZipPart reads one file of a zip-based document such as an Office file or an EPUB,
+// counting what it decompresses to against the size limit of the archive
+func readZipPart(archive *zipArchive, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+
+		part, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer part.Close()
+
+		var r io.Reader = part
+		if archive.maxSize > 0 {
+			r = io.LimitReader(part, archive.maxSize-archive.read+1)
+		}
+		data, err := io.ReadAll(r)
+		archive.read += int64(len(data))
+		if err != nil {
+			return nil, err
+		}
+		if archive.maxSize > 0 && archive.read > archive.maxSize {
+			return nil, fmt.Errorf("%w: it expands to more than %s", ErrArchiveTooLarge, formatBytes(archive.maxSize))
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+// attr returns the value of an attribute by its local name
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// officeRelationships maps the relationship IDs of a part to the parts they point at
+func officeRelationships(archive *zipArchive, part string) map[string]officeRelationship {
+	relsPath := path.Join(path.Dir(part), "_rels", path.Base(part)+".rels")
+	data, err := readZipPart(archive, relsPath)
+	if err != nil {
+		return nil
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+			Mode   string `xml:"TargetMode,attr"`
+		} `xml:"Relationship"`
+	}
+	if xml.Unmarshal(data, &rels) != nil {
+		return nil
+	}
+
+	result := make(map[string]officeRelationship)
+	for _, rel := range rels.Relationships {
+		if rel.Mode == "External" {
+			continue
+		}
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(rel.Target, "/") {
+			target = path.Join(path.Dir(part), rel.Target)
+		}
+		result[rel.ID] = officeRelationship{Type: path.Base(rel.Type), Target: target}
+	}
+	return result
+}
+
+// officeRelationship is a link from one part of an Office file to another. Type is the
+// last segment of the relationship type URI, e.g. "slide" or "notesSlide".
+type officeRelationship struct {
+	Type   string
+	Target string
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"strconv"
+	"strings"
+)
+
+// outlineEntry is a section being placed in the outline together with where it starts
+type outlineEntry struct {
+	section    models.DocumentSection
+	startsPage bool // the heading is the first thing on its page
+}
+
+// DocumentOutline lists the sections of a document from the heading lines of its pages.
+// Sections are numbered by their place in the hierarchy ("2", "2.1") and span the pages
+// up to the next section that is not nested in them.
+func DocumentOutline(pages []models.DocumentPage) []models.DocumentSection {
+	var entries []outlineEntry
+	var open []int // indexes of the entries enclosing the current heading
+	children := map[int]int{-1: 0}
+
+	for _, page := range pages {
+		first := true
+		for _, line := range strings.Split(page.Text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			level, title := headingLevel(line)
+			if level == 0 {
+				first = false
+				continue
+			}
+
+			for len(open) > 0 && entries[open[len(open)-1]].section.Level >= level {
+				open = open[:len(open)-1]
+			}
+			parent, id := -1, ""
+			if len(open) > 0 {
+				parent = open[len(open)-1]
+				id = entries[parent].section.ID + "."
+			}
+			children[parent]++
+			id += strconv.Itoa(children[parent])
+
+			entries = append(entries, outlineEntry{
+				section:    models.DocumentSection{ID: id, Title: title, Level: level, FirstPage: page.Number},
+				startsPage: first,
+			})
+			open = append(open, len(entries)-1)
+			first = false
+		}
+	}
+
+	sections := make([]models.DocumentSection, len(entries))
+	for i, entry := range entries {
+		section := entry.section
+		section.LastPage = pages[len(pages)-1].Number
+		for _, next := range entries[i+1:] {
+			if next.section.Level > section.Level {
+				continue
+			}
+			section.LastPage = next.section.FirstPage
+			if next.startsPage {
+				section.LastPage--
+			}
+			break
+		}
+		section.LastPage = max(section.LastPage, section.FirstPage)
+		sections[i] = section
+	}
+	return sections
+}
+
+// SelectSections keeps the text of the chosen sections, in document order. Sections are
+// chosen by their ID or by their title, ignoring case. Pages a section starts or ends on
+// keep only the part of their text that belongs to it. Without any choice all pages are
+// kept.
+func SelectSections(pages []models.DocumentPage, choices []string) ([]models.DocumentPage, error) {
+	if len(choices) == 0 {
+		return pages, nil
+	}
+
+	outline := DocumentOutline(pages)
+	if len(outline) == 0 {
+		return nil, fmt.Errorf("document has no sections to choose from")
+	}
+
+	chosen := make(map[int]bool) // indexes into the outline
+	for _, choice := range choices {
+		choice = strings.TrimSpace(choice)
+		found := false
+		for i, section := range outline {
+			if section.ID == choice || strings.EqualFold(section.Title, choice) {
+				chosen[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("section %q not found", choice)
+		}
+	}
+
+	// Walk the headings in the order the outline lists them, keeping lines from a chosen
+	// heading up to the next heading that is not nested in it
+	var selected []models.DocumentPage
+	heading, inside := -1, 0 // level of the chosen section being read, 0 outside
+	for _, page := range pages {
+		var kept []string
+		for _, line := range strings.Split(page.Text, "\n") {
+			if level, _ := headingLevel(line); level > 0 {
+				heading++
+				if inside > 0 && level <= inside {
+					inside = 0
+				}
+				if inside == 0 && chosen[heading] {
+					inside = level
+				}
+			}
+			if inside > 0 {
+				kept = append(kept, line)
+			}
+		}
+		if text := strings.TrimSpace(strings.Join(kept, "\n")); text != "" {
+			page.Text = text
+			selected = append(selected, page)
+		}
+	}
+	return selected, nil
+}
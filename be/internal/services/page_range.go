@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"strconv"
+	"strings"
+)
+
+// PageRange is a span of page numbers. A range without a last page runs to the end of
+// the document.
+type PageRange struct {
+	First int
+	Last  int
+}
+
+// ParsePageRanges reads page ranges such as "40-55", "3,7-9" or "12-" (page 12 to the
+// end). En dashes are accepted, as users copy ranges from tables of contents.
+func ParsePageRanges(spec string) ([]PageRange, error) {
+	var ranges []PageRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(strings.ReplaceAll(part, "–", "-"))
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		r := PageRange{}
+		var err error
+		if r.First, err = strconv.Atoi(strings.TrimSpace(first)); err != nil || r.First < 1 {
+			return nil, fmt.Errorf("invalid page range %q", part)
+		}
+		r.Last = r.First
+		if isRange {
+			r.Last = 0
+			if last = strings.TrimSpace(last); last != "" {
+				if r.Last, err = strconv.Atoi(last); err != nil || r.Last < r.First {
+					return nil, fmt.Errorf("invalid page range %q", part)
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// contains reports whether a page number is in the range
+func (r PageRange) contains(page int) bool {
+	return page >= r.First && (r.Last == 0 || page <= r.Last)
+}
+
+// SelectPages keeps the pages within the given ranges, in document order. Without any
+// range all pages are kept.
+func SelectPages(pages []models.DocumentPage, ranges []PageRange) ([]models.DocumentPage, error) {
+	if len(ranges) == 0 {
+		return pages, nil
+	}
+
+	var selected []models.DocumentPage
+	for _, page := range pages {
+		for _, r := range ranges {
+			if r.contains(page.Number) {
+				selected = append(selected, page)
+				break
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("document has no text on the chosen pages")
+	}
+	return selected, nil
+}
+
+// SelectParts keeps the chosen sections and page ranges of a document. A page both in a
+// chosen range and in a chosen section is kept whole.
+func SelectParts(pages []models.DocumentPage, sections []string, pageSpec string) ([]models.DocumentPage, error) {
+	ranges, err := ParsePageRanges(pageSpec)
+	if err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 || len(ranges) == 0 {
+		selected, err := SelectSections(pages, sections)
+		if err != nil {
+			return nil, err
+		}
+		return SelectPages(selected, ranges)
+	}
+
+	bySection, err := SelectSections(pages, sections)
+	if err != nil {
+		return nil, err
+	}
+	byRange, err := SelectPages(pages, ranges)
+	if err != nil {
+		return nil, err
+	}
+
+	chosen := make(map[int]models.DocumentPage)
+	for _, page := range bySection {
+		chosen[page.Number] = page
+	}
+	for _, page := range byRange {
+		chosen[page.Number] = page
+	}
+	var selected []models.DocumentPage
+	for _, page := range pages {
+		if p, ok := chosen[page.Number]; ok {
+			selected = append(selected, p)
+		}
+	}
+	return selected, nil
+}
+
+// FormatPageRanges describes the numbers of pages as ranges, e.g. "3-5, 9"
+func FormatPageRanges(pages []models.DocumentPage) string {
+	numbers := make([]int, len(pages))
+	for i, page := range pages {
+		numbers[i] = page.Number
+	}
+	return FormatPageNumbers(numbers)
+}
+
+// FormatPageNumbers writes ascending page numbers as ranges, e.g. "3, 7-9"
+func FormatPageNumbers(numbers []int) string {
+	var parts []string
+	for i := 0; i < len(numbers); {
+		j := i
+		for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+			j++
+		}
+		if j == i {
+			parts = append(parts, strconv.Itoa(numbers[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
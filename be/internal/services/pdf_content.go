@@ -0,0 +1,198 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfFont is what drawing text needs from a font, read from the file once per page. The
+// library reads it again for every glyph, which makes a page of text take a third of a
+// second.
+type pdfFont struct {
+	name   string
+	enc    pdf.TextEncoding
+	first  int
+	widths []float64
+}
+
+// width returns the width of a character code in thousandths of the font size
+func (f *pdfFont) width(code int) float64 {
+	if code < f.first || code-f.first >= len(f.widths) {
+		return 0
+	}
+	return f.widths[code-f.first]
+}
+
+// pdfRawEncoding reads text in fonts without an encoding as it is
+type pdfRawEncoding struct{}
+
+func (pdfRawEncoding) Decode(raw string) string { return raw }
+
+type pdfMatrix [3][3]float64
+
+var pdfIdentity = pdfMatrix{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+func (x pdfMatrix) mul(y pdfMatrix) pdfMatrix {
+	var z pdfMatrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				z[i][j] += x[i][k] * y[k][j]
+			}
+		}
+	}
+	return z
+}
+
+func pdfTranslation(tx, ty float64) pdfMatrix {
+	return pdfMatrix{{1, 0, 0}, {0, 1, 0}, {tx, ty, 1}}
+}
+
+// pdfTextState is the part of the graphics state that places text
+type pdfTextState struct {
+	Tc, Th, Tl, Tfs, Trise float64
+	font                   *pdfFont
+	Tm, Tlm, CTM           pdfMatrix
+}
+
+// pdfPageGlyphs returns every glyph drawn on a page with its position, size and font, as
+// the library's Page.Content does. It panics on malformed content like Content does.
+func pdfPageGlyphs(page pdf.Page) []pdf.Text {
+	strm := page.V.Key("Contents")
+	if strm.IsNull() {
+		return nil
+	}
+
+	fonts := make(map[string]*pdfFont)
+	font := func(name string) *pdfFont {
+		if f, ok := fonts[name]; ok {
+			return f
+		}
+		pf := page.Font(name)
+		f := &pdfFont{name: pf.BaseFont(), enc: pf.Encoder(), first: pf.FirstChar(), widths: pf.Widths()}
+		if i := strings.Index(f.name, "+"); i >= 0 {
+			f.name = f.name[i+1:] // subset prefix
+		}
+		if f.enc == nil {
+			f.enc = pdfRawEncoding{}
+		}
+		fonts[name] = f
+		return f
+	}
+
+	g := pdfTextState{Th: 1, CTM: pdfIdentity, font: &pdfFont{enc: pdfRawEncoding{}}}
+	var stack []pdfTextState
+	var glyphs []pdf.Text
+
+	showText := func(s string) {
+		n := 0
+		for _, ch := range g.font.enc.Decode(s) {
+			var w0 float64
+			if n < len(s) {
+				w0 = g.font.width(int(s[n]))
+			}
+			n++
+
+			trm := pdfMatrix{{g.Tfs * g.Th, 0, 0}, {0, g.Tfs, 0}, {0, g.Trise, 1}}.mul(g.Tm).mul(g.CTM)
+			glyphs = append(glyphs, pdf.Text{Font: g.font.name, FontSize: trm[0][0], X: trm[2][0], Y: trm[2][1], W: w0 / 1000 * trm[0][0], S: string(ch)})
+
+			tx := (w0/1000*g.Tfs + g.Tc) * g.Th
+			g.Tm = pdfTranslation(tx, 0).mul(g.Tm)
+		}
+	}
+	matrixArgs := func(args []pdf.Value) pdfMatrix {
+		var m pdfMatrix
+		for i := 0; i < 6; i++ {
+			m[i/2][i%2] = args[i].Float64()
+		}
+		m[2][2] = 1
+		return m
+	}
+
+	pdf.Interpret(strm, func(stk *pdf.Stack, op string) {
+		n := stk.Len()
+		args := make([]pdf.Value, n)
+		for i := n - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+
+		switch op {
+		case "cm":
+			if n == 6 {
+				g.CTM = matrixArgs(args).mul(g.CTM)
+			}
+		case "q":
+			stack = append(stack, g)
+		case "Q":
+			if len(stack) > 0 {
+				g = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "BT":
+			g.Tm, g.Tlm = pdfIdentity, pdfIdentity
+		case "T*":
+			g.Tlm = pdfTranslation(0, -g.Tl).mul(g.Tlm)
+			g.Tm = g.Tlm
+		case "Tc":
+			if n == 1 {
+				g.Tc = args[0].Float64()
+			}
+		case "TD", "Td":
+			if n == 2 {
+				if op == "TD" {
+					g.Tl = -args[1].Float64()
+				}
+				g.Tlm = pdfTranslation(args[0].Float64(), args[1].Float64()).mul(g.Tlm)
+				g.Tm = g.Tlm
+			}
+		case "Tf":
+			if n == 2 {
+				g.font = font(args[0].Name())
+				g.Tfs = args[1].Float64()
+			}
+		case "\"", "'":
+			if op == "\"" && n == 3 {
+				g.Tc = args[1].Float64()
+				args = args[2:]
+			}
+			if len(args) == 1 {
+				g.Tlm = pdfTranslation(0, -g.Tl).mul(g.Tlm)
+				g.Tm = g.Tlm
+				showText(args[0].RawString())
+			}
+		case "Tj":
+			if n == 1 {
+				showText(args[0].RawString())
+			}
+		case "TJ":
+			if n == 1 {
+				for i := 0; i < args[0].Len(); i++ {
+					if x := args[0].Index(i); x.Kind() == pdf.String {
+						showText(x.RawString())
+					} else {
+						g.Tm = pdfTranslation(-x.Float64()/1000*g.Tfs*g.Th, 0).mul(g.Tm)
+					}
+				}
+			}
+		case "TL":
+			if n == 1 {
+				g.Tl = args[0].Float64()
+			}
+		case "Tm":
+			if n == 6 {
+				g.Tm = matrixArgs(args)
+				g.Tlm = g.Tm
+			}
+		case "Ts":
+			if n == 1 {
+				g.Trise = args[0].Float64()
+			}
+		case "Tz":
+			if n == 1 {
+				g.Th = args[0].Float64() / 100
+			}
+		}
+	})
+	return glyphs
+}
@@ -0,0 +1,747 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"pbkk-quizlit-backend/internal/models"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// pdfLine is a run of text on one baseline of one column of a page
+type pdfLine struct {
+	x, right, y float64
+	size        float64
+	bold, mono  bool
+	text        string
+	chunks      []pdfChunk
+}
+
+// pdfChunk is a run of words of a line set apart from the rest by more than a space,
+// as the cells of a table row are
+type pdfChunk struct {
+	x, right float64
+	text     string
+}
+
+// pdfParagraph is a block of consecutive lines of a page
+type pdfParagraph struct {
+	lines    []pdfLine
+	size     float64
+	bold     bool
+	mono     bool
+	listItem bool
+	heading  int        // heading level, 0 for body text
+	table    [][]string // rows of cells of a table, which has no lines of its own
+}
+
+// pdfPageLayout is the text of a page as paragraphs in reading order. Pages whose
+// drawing operators cannot be interpreted keep the library's plain text instead.
+type pdfPageLayout struct {
+	number     int
+	paragraphs []*pdfParagraph
+	plain      string
+	ocr        *models.PageOCR // set when plain was read by OCR
+}
+
+// pdfBullets are the characters that start a list item
+// and the replacement character symbol fonts without a character map are read as
+const pdfBullets = "•◦▪▫●○■□‣⁃–-*\ufffd"
+
+// pdfPageLines reads the text drawn on a page as lines. Glyphs are grouped by baseline,
+// then split where a gap is too wide to be a space, which separates columns. Spaces
+// are inserted where glyphs are drawn apart without one, since many PDFs position
+// words instead of drawing spaces.
+func pdfPageLines(page pdf.Page) (lines []pdfLine, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to read page content: %v", r)
+		}
+	}()
+
+	var glyphs []pdf.Text
+	widths := false
+	for _, glyph := range pdfPageGlyphs(page) {
+		if glyph.S != "\n" && glyph.S != "" && glyph.FontSize > 0 {
+			glyphs = append(glyphs, glyph)
+			widths = widths || glyph.W > 0
+		}
+	}
+	if widths {
+		// Glyphs without width that are not accents are markers, such as the line ends
+		// TeX fonts without a character map are read as "Ω"
+		drawn := glyphs[:0]
+		for _, glyph := range glyphs {
+			if r := []rune(glyph.S); glyph.W > 0 || len(r) != 1 || r[0] < unicode.MaxASCII || unicode.Is(unicode.Mn, r[0]) {
+				drawn = append(drawn, glyph)
+			}
+		}
+		glyphs = drawn
+	}
+	sort.SliceStable(glyphs, func(i, j int) bool { return glyphs[i].Y > glyphs[j].Y })
+
+	// Group glyphs into rows sharing a baseline
+	var rows [][]pdf.Text
+	for _, glyph := range glyphs {
+		if n := len(rows); n > 0 {
+			first := rows[n-1][0]
+			if math.Abs(first.Y-glyph.Y) <= 0.3*math.Max(first.FontSize, glyph.FontSize) {
+				rows[n-1] = append(rows[n-1], glyph)
+				continue
+			}
+		}
+		rows = append(rows, []pdf.Text{glyph})
+	}
+
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool { return row[i].X < row[j].X })
+		// Fake bold draws every glyph twice, slightly shifted
+		drawn := row[:1]
+		for _, glyph := range row[1:] {
+			prev := drawn[len(drawn)-1]
+			if glyph.S != prev.S || math.Abs(glyph.X-prev.X) >= 0.2*glyph.FontSize {
+				drawn = append(drawn, glyph)
+			}
+		}
+		row = drawn
+		if !widths {
+			// Without font widths a glyph reaches to the next one, so only drawn spaces
+			// and wide gaps separate words
+			for i := range row {
+				row[i].W = 0.5 * row[i].FontSize
+				if i+1 < len(row) {
+					row[i].W = math.Min(row[i+1].X-row[i].X, row[i].FontSize)
+				}
+			}
+		}
+		var segment []pdf.Text
+		for i, glyph := range row {
+			if i > 0 {
+				prev := row[i-1]
+				if glyph.X-(prev.X+prev.W) > math.Max(2*glyph.FontSize, 12) {
+					lines = appendPDFLine(lines, segment)
+					segment = nil
+				}
+			}
+			segment = append(segment, glyph)
+		}
+		lines = appendPDFLine(lines, segment)
+	}
+	return lines, nil
+}
+
+// appendPDFLine turns the glyphs of one segment of a row into a line
+func appendPDFLine(lines []pdfLine, glyphs []pdf.Text) []pdfLine {
+	if len(glyphs) == 0 {
+		return lines
+	}
+
+	var text, chunk strings.Builder
+	var chunks []pdfChunk
+	chunkX := glyphs[0].X
+	endChunk := func(right float64) {
+		if content := strings.Join(strings.Fields(chunk.String()), " "); content != "" {
+			chunks = append(chunks, pdfChunk{x: chunkX, right: right, text: content})
+		}
+		chunk.Reset()
+	}
+
+	sizes := make(map[float64]int)
+	boldChars, monoChars := 0, 0
+	for i, glyph := range glyphs {
+		if i > 0 {
+			prev := glyphs[i-1]
+			gap := glyph.X - (prev.X + prev.W)
+			if gap > 0.15*glyph.FontSize && !strings.HasSuffix(prev.S, " ") && !strings.HasPrefix(glyph.S, " ") {
+				text.WriteString(" ")
+				chunk.WriteString(" ")
+			}
+			if gap >= pdfCellGap*glyph.FontSize {
+				endChunk(prev.X + prev.W)
+				chunkX = glyph.X
+			}
+		}
+		text.WriteString(glyph.S)
+		chunk.WriteString(glyph.S)
+		sizes[math.Round(glyph.FontSize*2)/2]++
+		if isBoldFont(glyph.Font) {
+			boldChars++
+		}
+		if isMonospaceFont(glyph.Font) {
+			monoChars++
+		}
+	}
+
+	content := strings.Join(strings.Fields(text.String()), " ")
+	if content == "" {
+		return lines
+	}
+
+	size, count := 0.0, 0
+	for s, n := range sizes {
+		if n > count || (n == count && s > size) {
+			size, count = s, n
+		}
+	}
+	last := glyphs[len(glyphs)-1]
+	endChunk(last.X + last.W)
+	return append(lines, pdfLine{
+		x:      glyphs[0].X,
+		right:  last.X + last.W,
+		y:      glyphs[0].Y,
+		size:   size,
+		bold:   boldChars*2 > len(glyphs),
+		mono:   monoChars*2 > len(glyphs),
+		text:   content,
+		chunks: chunks,
+	})
+}
+
+// Font names say how a font is set, e.g. "Helvetica-Bold", "NimbusRomNo9L-Medi" or
+// TeX's "CMBX12" (bold extended)
+func isBoldFont(font string) bool {
+	return fontNameHas(font, "bold", "black", "heavy", "medi", "cmbx")
+}
+
+func isMonospaceFont(font string) bool {
+	return fontNameHas(font, "mono", "courier", "cmtt", "nimbusmon", "consola", "menlo")
+}
+
+func fontNameHas(font string, names ...string) bool {
+	font = strings.ToLower(font)
+	for _, name := range names {
+		if strings.Contains(font, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// orderPDFLines puts the lines of a page in reading order. A page in two columns has a
+// gutter that hardly any line crosses; lines that do cross it, such as titles spanning
+// both columns, split the page into bands that are read one after the other, each
+// left column first.
+func orderPDFLines(lines []pdfLine) []pdfLine {
+	sort.SliceStable(lines, func(i, j int) bool {
+		if math.Abs(lines[i].y-lines[j].y) > 0.3*lines[i].size {
+			return lines[i].y > lines[j].y
+		}
+		return lines[i].x < lines[j].x
+	})
+	left, right, crossing, ok := splitPDFColumns(lines)
+	if !ok {
+		return lines
+	}
+
+	// A line belongs to the band below the crossing lines above it
+	band := func(line pdfLine) int {
+		n := 0
+		for _, c := range crossing {
+			if c.y > line.y {
+				n++
+			}
+		}
+		return n
+	}
+	ordered := make([]pdfLine, 0, len(lines))
+	for b := 0; b <= len(crossing); b++ {
+		for _, line := range left {
+			if band(line) == b {
+				ordered = append(ordered, line)
+			}
+		}
+		for _, line := range right {
+			if band(line) == b {
+				ordered = append(ordered, line)
+			}
+		}
+		if b < len(crossing) {
+			ordered = append(ordered, crossing[b])
+		}
+	}
+	return ordered
+}
+
+// splitPDFColumns splits the lines of a page in two columns at the gutter between them,
+// keeping apart the lines that cross it. It fails for pages in one column.
+func splitPDFColumns(lines []pdfLine) (left, right, crossing []pdfLine, ok bool) {
+	if len(lines) < 6 {
+		return nil, nil, nil, false
+	}
+
+	minX, maxX := lines[0].x, lines[0].right
+	for _, line := range lines {
+		minX, maxX = math.Min(minX, line.x), math.Max(maxX, line.right)
+	}
+	width := maxX - minX
+	if width < 100 {
+		return nil, nil, nil, false
+	}
+
+	// Count the lines covering every point across the page and look for the least
+	// covered point in its middle
+	coverage := make([]int, int(width)+1)
+	for _, line := range lines {
+		for x := int(line.x - minX); x < int(line.right-minX) && x < len(coverage); x++ {
+			coverage[x]++
+		}
+	}
+	gutter, best := -1, len(lines)
+	center := len(coverage) / 2
+	for x := int(0.3 * width); x <= int(0.7*width); x++ {
+		if coverage[x] < best || (coverage[x] == best && abs(x-center) < abs(gutter-center)) {
+			gutter, best = x, coverage[x]
+		}
+	}
+	split := minX + float64(gutter)
+
+	for _, line := range lines {
+		switch {
+		case line.right <= split:
+			left = append(left, line)
+		case line.x >= split:
+			right = append(right, line)
+		default:
+			crossing = append(crossing, line)
+		}
+	}
+	if len(crossing) > len(lines)/10 || len(left) < len(lines)/5 || len(right) < len(lines)/5 {
+		return nil, nil, nil, false
+	}
+	return left, right, crossing, true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// pdfParagraphs groups lines in reading order into paragraphs. A paragraph ends at a gap
+// wider than the usual line spacing, a change of font size or weight, a jump back up the
+// page (the next column), an indented first line, a short line ending a sentence, or a
+// bullet.
+func pdfParagraphs(lines []pdfLine) []*pdfParagraph {
+	// The usual distance between the lines of a paragraph
+	var gaps []float64
+	for i := 1; i < len(lines); i++ {
+		if gap := lines[i-1].y - lines[i].y; gap > 0 && gap < 3*lines[i].size && lines[i].size == lines[i-1].size {
+			gaps = append(gaps, gap)
+		}
+	}
+	leading := 0.0
+	if len(gaps) > 0 {
+		sort.Float64s(gaps)
+		leading = gaps[len(gaps)/2]
+	}
+
+	var paragraphs []*pdfParagraph
+	var para *pdfParagraph
+	for _, line := range lines {
+		bullet := startsWithBullet(line.text)
+		if para != nil && !pdfParagraphContinues(para, line, leading) {
+			para = nil
+		}
+		if para == nil || bullet {
+			para = &pdfParagraph{size: line.size, bold: line.bold, mono: line.mono, listItem: bullet}
+			paragraphs = append(paragraphs, para)
+		}
+		para.lines = append(para.lines, line)
+	}
+	return paragraphs
+}
+
+func pdfParagraphContinues(para *pdfParagraph, line pdfLine, leading float64) bool {
+	prev := para.lines[len(para.lines)-1]
+	gap := prev.y - line.y
+	if leading == 0 {
+		leading = 1.2 * prev.size
+	}
+
+	switch {
+	case gap <= 0, gap > 1.4*leading+0.5:
+		return false
+	case math.Abs(line.size-para.size) > 0.12*para.size, line.bold != para.bold, line.mono != para.mono:
+		return false
+	case para.mono && !para.listItem:
+		return true
+	case len(para.lines) > 0 && !para.listItem && line.x-prev.x > prev.size:
+		return false
+	}
+
+	// A sentence ending well before the right edge of the paragraph ends the paragraph
+	right := 0.0
+	for _, l := range para.lines {
+		right = math.Max(right, l.right)
+	}
+	if len(para.lines) > 1 && prev.right < right-3*prev.size && strings.ContainsAny(prev.text[len(prev.text)-1:], ".!?:") {
+		return false
+	}
+	return true
+}
+
+func startsWithBullet(text string) bool {
+	r := []rune(text)
+	return len(r) > 2 && strings.ContainsRune(pdfBullets, r[0]) && r[1] == ' '
+}
+
+// text joins the lines of a paragraph, keeping the line breaks for dehyphenation
+func (p *pdfParagraph) text() string {
+	if p.table != nil {
+		rows := make([]string, len(p.table))
+		for i, row := range p.table {
+			rows[i] = strings.Join(row, " ")
+		}
+		return strings.Join(rows, "\n")
+	}
+	parts := make([]string, len(p.lines))
+	for i, line := range p.lines {
+		parts[i] = line.text
+	}
+	text := strings.Join(parts, "\n")
+	if p.listItem {
+		text = strings.TrimSpace(string([]rune(text)[1:]))
+	}
+	return text
+}
+
+// dropRunningHeads removes page numbers and the headers and footers repeated at the top
+// or bottom of most pages, which would otherwise interrupt the text running from one
+// page into the next
+func dropRunningHeads(layouts []pdfPageLayout) {
+	key := func(para *pdfParagraph) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return '#'
+			}
+			return unicode.ToLower(r)
+		}, para.text())
+	}
+	edges := func(layout pdfPageLayout) []*pdfParagraph {
+		n := len(layout.paragraphs)
+		if n <= 4 {
+			return layout.paragraphs
+		}
+		return append(append([]*pdfParagraph{}, layout.paragraphs[:2]...), layout.paragraphs[n-2:]...)
+	}
+
+	pages := make(map[string]int)
+	for _, layout := range layouts {
+		seen := make(map[string]bool)
+		for _, para := range edges(layout) {
+			if k := key(para); !seen[k] {
+				seen[k] = true
+				pages[k]++
+			}
+		}
+	}
+
+	for i := range layouts {
+		drop := make(map[*pdfParagraph]bool)
+		for _, para := range edges(layouts[i]) {
+			k := key(para)
+			number := strings.Trim(k, "#ivxlc -") == "" && len(k) <= 8
+			if number || len(layouts) >= 3 && pages[k] >= 3 && pages[k]*2 >= len(layouts) {
+				drop[para] = true
+			}
+		}
+		kept := layouts[i].paragraphs[:0]
+		for _, para := range layouts[i].paragraphs {
+			if !drop[para] {
+				kept = append(kept, para)
+			}
+		}
+		layouts[i].paragraphs = kept
+	}
+}
+
+// detectPDFHeadings marks paragraphs set larger than the body text, or short bold lines,
+// as headings. The body size is the size most text of the document is set in; larger
+// sizes become heading levels from the largest down.
+func detectPDFHeadings(layouts []pdfPageLayout) {
+	chars := make(map[float64]int)
+	for _, layout := range layouts {
+		for _, para := range layout.paragraphs {
+			for _, line := range para.lines {
+				chars[line.size] += len(line.text)
+			}
+		}
+	}
+	body, count := 0.0, 0
+	for size, n := range chars {
+		if n > count {
+			body, count = size, n
+		}
+	}
+	if body == 0 {
+		return
+	}
+
+	isHeading := func(para *pdfParagraph) bool {
+		text := para.text()
+		words := len(strings.Fields(text))
+		if para.listItem || para.mono || words == 0 || words > 15 || len(para.lines) > 3 || strings.ContainsAny(text[len(text)-1:], ".,;") {
+			return false
+		}
+		first := []rune(text)[0]
+		return unicode.IsUpper(first) || unicode.IsDigit(first)
+	}
+
+	var sizes []float64
+	seen := make(map[float64]bool)
+	for _, layout := range layouts {
+		for _, para := range layout.paragraphs {
+			if para.size >= 1.15*body && isHeading(para) && !seen[para.size] {
+				seen[para.size] = true
+				sizes = append(sizes, para.size)
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+
+	for _, layout := range layouts {
+		for _, para := range layout.paragraphs {
+			if !isHeading(para) {
+				continue
+			}
+			switch {
+			case para.size >= 1.15*body:
+				para.heading = min(sort.Search(len(sizes), func(i int) bool { return sizes[i] <= para.size })+1, 3)
+			case para.bold && para.size >= 0.95*body && len(para.lines) == 1 && len(strings.Fields(para.text())) <= 10:
+				para.heading = min(len(sizes)+1, 4)
+			}
+		}
+	}
+}
+
+// pdfOutlineEntry is a bookmark of a PDF: its title, depth and the page it points at, 0
+// when the destination cannot be resolved
+type pdfOutlineEntry struct {
+	title string
+	level int
+	page  int
+}
+
+// pdfOutline reads the bookmarks of a PDF with the pages they point at
+func pdfOutline(reader *pdf.Reader) (entries []pdfOutlineEntry) {
+	defer func() {
+		if recover() != nil {
+			entries = nil
+		}
+	}()
+
+	root := reader.Trailer().Key("Root")
+	outlines := root.Key("Outlines")
+	if outlines.Kind() != pdf.Dict {
+		return nil
+	}
+
+	// Destinations point at page objects, which are matched by their contents
+	pages := make(map[string]int)
+	for i := 1; i <= reader.NumPage(); i++ {
+		if page := reader.Page(i); !page.V.IsNull() {
+			pages[page.V.String()] = i
+		}
+	}
+
+	var walk func(item pdf.Value, level int)
+	walk = func(item pdf.Value, level int) {
+		for child := item.Key("First"); child.Kind() == pdf.Dict && len(entries) < 2000; child = child.Key("Next") {
+			title := strings.Join(strings.Fields(child.Key("Title").Text()), " ")
+			if title != "" {
+				entries = append(entries, pdfOutlineEntry{title: title, level: min(level, 6), page: pdfDestinationPage(root, child, pages)})
+			}
+			if level < 6 {
+				walk(child, level+1)
+			}
+		}
+	}
+	walk(outlines, 1)
+	return entries
+}
+
+// pdfDestinationPage finds the page a bookmark points at, either directly or through a
+// GoTo action, by explicit destination or by name
+func pdfDestinationPage(root, item pdf.Value, pages map[string]int) int {
+	dest := item.Key("Dest")
+	if dest.IsNull() {
+		if action := item.Key("A"); action.Key("S").Name() == "GoTo" {
+			dest = action.Key("D")
+		}
+	}
+
+	if dest.Kind() == pdf.Name || dest.Kind() == pdf.String {
+		name := dest.RawString()
+		if dest.Kind() == pdf.Name {
+			name = dest.Name()
+		}
+		dest = root.Key("Dests").Key(name)
+		if dest.IsNull() {
+			dest = pdfNameTreeLookup(root.Key("Names").Key("Dests"), name, 0)
+		}
+	}
+	if dest.Kind() == pdf.Dict {
+		dest = dest.Key("D")
+	}
+	if dest.Kind() != pdf.Array || dest.Len() == 0 {
+		return 0
+	}
+	return pages[dest.Index(0).String()]
+}
+
+// pdfNameTreeLookup finds a value in a PDF name tree
+func pdfNameTreeLookup(node pdf.Value, name string, depth int) pdf.Value {
+	if node.Kind() != pdf.Dict || depth > 10 {
+		return pdf.Value{}
+	}
+	names := node.Key("Names")
+	for i := 0; i+1 < names.Len(); i += 2 {
+		if names.Index(i).RawString() == name {
+			return names.Index(i + 1)
+		}
+	}
+	kids := node.Key("Kids")
+	for i := 0; i < kids.Len(); i++ {
+		if value := pdfNameTreeLookup(kids.Index(i), name, depth+1); !value.IsNull() {
+			return value
+		}
+	}
+	return pdf.Value{}
+}
+
+// applyPDFOutline marks the paragraphs the bookmarks point at as headings, at the depth
+// of their bookmark. Bookmarks whose title is not found on their page are added at the
+// top of it, so the outline of the document stays complete.
+func applyPDFOutline(layouts []pdfPageLayout, entries []pdfOutlineEntry) {
+	byPage := make(map[int]int)
+	for i, layout := range layouts {
+		byPage[layout.number] = i
+	}
+
+	added := make(map[int]int) // headings added at the top of a page so far
+	for _, entry := range entries {
+		i, ok := byPage[entry.page]
+		if !ok {
+			continue
+		}
+		layout := &layouts[i]
+		title := outlineKey(entry.title)
+
+		found := false
+		for _, para := range layout.paragraphs {
+			// Headings often carry a section number their bookmark leaves out
+			text := outlineKey(para.text())
+			unnumbered := outlineKey(strings.TrimLeft(para.text(), "0123456789. "))
+			if para.heading == 0 && para.table == nil && text != "" && (text == title || unnumbered == title || strings.HasPrefix(text, title) && len(text) < 2*len(title)) {
+				para.heading = entry.level
+				found = true
+				break
+			}
+		}
+		if found || layout.plain != "" {
+			continue
+		}
+
+		heading := &pdfParagraph{lines: []pdfLine{{text: entry.title}}, heading: entry.level}
+		n := added[i]
+		layout.paragraphs = append(layout.paragraphs[:n], append([]*pdfParagraph{heading}, layout.paragraphs[n:]...)...)
+		added[i]++
+	}
+}
+
+// pdfLigatures spell out the ligatures fonts draw as one glyph
+var pdfLigatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st")
+
+// outlineKey reduces a title to its letters and digits for matching
+func outlineKey(title string) string {
+	var key strings.Builder
+	for _, r := range strings.ToLower(pdfLigatures.Replace(title)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(r)
+		}
+	}
+	return key.String()
+}
+
+// pdfTextSample returns text from the start of a document to detect its language from
+func pdfTextSample(layouts []pdfPageLayout) string {
+	var sample strings.Builder
+	for _, layout := range layouts {
+		if layout.plain != "" {
+			sample.WriteString(layout.plain + "\n")
+		}
+		for _, para := range layout.paragraphs {
+			sample.WriteString(para.text() + "\n")
+		}
+		if sample.Len() > 20000 {
+			break
+		}
+	}
+	return sample.String()
+}
+
+// pdfPageText writes a page as structured text: headings as "#" lines, bullets as list
+// items, tables as rows of cells and every paragraph as a block of its own. Each
+// paragraph is normalised on its own, so normalising never runs paragraphs together.
+func (fs *FileService) pdfPageText(layout pdfPageLayout, normalizer *TextNormalizer) string {
+	if layout.ocr != nil {
+		var blocks []textBlock
+		for _, para := range strings.Split(layout.plain, "\n\n") {
+			if para = strings.TrimSpace(para); para != "" {
+				blocks = append(blocks, textBlock{text: normalizer.Normalize(para)})
+			}
+		}
+		return joinBlocks(blocks)
+	}
+	if layout.plain != "" {
+		return normalizer.Normalize(layout.plain)
+	}
+
+	var blocks []textBlock
+	for _, para := range layout.paragraphs {
+		if para.table != nil {
+			rows := make([]string, len(para.table))
+			for i, row := range para.table {
+				cells := make([]string, len(row))
+				for j, cell := range row {
+					cells[j] = normalizer.Normalize(cell)
+				}
+				rows[i] = "| " + strings.Join(cells, " | ") + " |"
+			}
+			blocks = append(blocks, textBlock{text: strings.Join(rows, "\n")})
+			continue
+		}
+		if para.mono && para.heading == 0 && !para.listItem {
+			// Code keeps its lines, indented as code
+			lines := make([]string, len(para.lines))
+			for i, line := range para.lines {
+				lines[i] = "    " + line.text
+			}
+			blocks = append(blocks, textBlock{text: strings.Join(lines, "\n")})
+			continue
+		}
+
+		// Identifiers in code keep their spelling
+		text := para.text()
+		if para.mono {
+			text = strings.ReplaceAll(text, "\n", " ")
+		} else {
+			text = normalizer.Normalize(text)
+		}
+		switch {
+		case text == "":
+			continue
+		case para.heading > 0:
+			text = strings.Repeat("#", para.heading) + " " + text
+		case para.listItem:
+			blocks = append(blocks, textBlock{text: "- " + text, listItem: true})
+			continue
+		}
+		blocks = append(blocks, textBlock{text: text})
+	}
+	return joinBlocks(blocks)
+}
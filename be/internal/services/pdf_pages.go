@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"pbkk-quizlit-backend/internal/models"
+	"sync"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// How a page of a PDF was extracted
+const (
+	PageOK      = "ok"      // read from its text layout
+	PagePlain   = "plain"   // layout unreadable, read as plain text
+	PageOCR     = "ocr"     // no text layer, read by OCR
+	PageEmpty   = "empty"   // no text found
+	PageMissing = "missing" // the page object is missing from the file
+	PageFailed  = "failed"  // reading the page failed or panicked
+	PageTimeout = "timeout" // reading the page took longer than allowed
+)
+
+// pdfPageResult is the layout of a page read by a worker, nil when it has no text
+type pdfPageResult struct {
+	layout *pdfPageLayout
+	status models.PageStatus
+}
+
+// extractPDFPages reads the pages of a PDF in parallel with at most workers pages at a
+// time, each within timeout. A page that panics or times out is reported rather than
+// stopping the others, and so is a page whose content is larger than maxStream once
+// decompressed. Pages not read when ctx is done time out. The layouts come back in page
+// order, with the status of every page.
+func extractPDFPages(ctx context.Context, reader *pdf.Reader, workers int, timeout time.Duration, maxStream int64) ([]pdfPageLayout, []models.PageStatus) {
+	numPages := reader.NumPage()
+	results := make([]pdfPageResult, numPages)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), numPages); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range jobs {
+				results[number-1] = readPDFPageWithin(ctx, reader, number, timeout, maxStream)
+			}
+		}()
+	}
+	for number := 1; number <= numPages; number++ {
+		jobs <- number
+	}
+	close(jobs)
+	wg.Wait()
+
+	var layouts []pdfPageLayout
+	statuses := make([]models.PageStatus, numPages)
+	for i, result := range results {
+		if result.layout != nil {
+			layouts = append(layouts, *result.layout)
+		}
+		statuses[i] = result.status
+	}
+	return layouts, statuses
+}
+
+// readPDFPageWithin reads a page, giving up after timeout or when ctx is done. A page
+// given up on is left to finish in the background, as page content cannot be
+// interrupted; its result is dropped.
+func readPDFPageWithin(ctx context.Context, reader *pdf.Reader, number int, timeout time.Duration, maxStream int64) pdfPageResult {
+	if ctx.Err() != nil {
+		return pdfPageResult{status: models.PageStatus{Page: number, Status: PageTimeout, Error: "the document took too long to extract"}}
+	}
+
+	start := time.Now()
+	done := make(chan pdfPageResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- pdfPageResult{status: models.PageStatus{Page: number, Status: PageFailed, Error: fmt.Sprintf("panic: %v", r)}}
+			}
+		}()
+		done <- readPDFPage(reader, number, maxStream)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case result := <-done:
+		result.status.DurationMs = time.Since(start).Milliseconds()
+		return result
+	case <-expired:
+		return pdfPageResult{status: models.PageStatus{
+			Page:       number,
+			Status:     PageTimeout,
+			Error:      fmt.Sprintf("not read within %s", timeout),
+			DurationMs: time.Since(start).Milliseconds(),
+		}}
+	case <-ctx.Done():
+		return pdfPageResult{status: models.PageStatus{
+			Page:       number,
+			Status:     PageTimeout,
+			Error:      "the document took too long to extract",
+			DurationMs: time.Since(start).Milliseconds(),
+		}}
+	}
+}
+
+// readPDFPage reads a page as paragraphs in reading order, falling back to its plain
+// text when its layout cannot be read
+func readPDFPage(reader *pdf.Reader, number int, maxStream int64) pdfPageResult {
+	status := models.PageStatus{Page: number, Status: PageOK}
+	page := reader.Page(number)
+	if page.V.IsNull() {
+		status.Status = PageMissing
+		return pdfPageResult{status: status}
+	}
+
+	// The reader holds the whole decompressed content of a page in memory, so a small
+	// stream that inflates to gigabytes is refused before it is read
+	if maxStream > 0 && pdfContentSize(page, maxStream) > maxStream {
+		status.Status, status.Error = PageFailed, fmt.Sprintf("%v: the limit is %s", ErrStreamTooLarge, formatBytes(maxStream))
+		return pdfPageResult{status: status}
+	}
+
+	lines, err := pdfPageLines(page)
+	if err == nil && len(lines) > 0 {
+		lines, tables := extractPDFTables(lines)
+		paragraphs := placePDFTables(pdfParagraphs(orderPDFLines(lines)), tables)
+		return pdfPageResult{layout: &pdfPageLayout{number: number, paragraphs: paragraphs}, status: status}
+	}
+
+	text, plainErr := page.GetPlainText(nil)
+	switch {
+	case plainErr != nil && err != nil:
+		status.Status, status.Error = PageFailed, err.Error()
+		return pdfPageResult{status: status}
+	case plainErr != nil || text == "":
+		status.Status = PageEmpty
+		return pdfPageResult{status: status}
+	}
+	status.Status = PagePlain
+	return pdfPageResult{layout: &pdfPageLayout{number: number, plain: text}, status: status}
+}
+
+// FailedPages returns the pages that could not be read, as they failed or timed out
+func FailedPages(statuses []models.PageStatus) []int {
+	var failed []int
+	for _, status := range statuses {
+		if status.Status == PageFailed || status.Status == PageTimeout {
+			failed = append(failed, status.Page)
+		}
+	}
+	return failed
+}
+
+// pdfContentSize returns the decompressed size of the content streams of a page, counting
+// no further than just past limit
+func pdfContentSize(page pdf.Page, limit int64) int64 {
+	contents := page.V.Key("Contents")
+	streams := []pdf.Value{contents}
+	if contents.Kind() == pdf.Array {
+		streams = streams[:0]
+		for i := 0; i < contents.Len(); i++ {
+			streams = append(streams, contents.Index(i))
+		}
+	}
+
+	var size int64
+	for _, stream := range streams {
+		if stream.Kind() != pdf.Stream {
+			continue
+		}
+		r := stream.Reader()
+		n, _ := io.CopyN(io.Discard, r, limit+1-size)
+		r.Close()
+		if size += n; size > limit {
+			break
+		}
+	}
+	return size
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pdfCellGap is the gap between words, in multiples of the font size, that sets cells
+// apart. Justified text stretches spaces to about half the font size at most.
+const pdfCellGap = 1.0
+
+// pdfRow is the text on one baseline of a page, across all its columns
+type pdfRow struct {
+	y, size float64
+	lines   []int // indexes of the lines of the row
+	chunks  []pdfChunk
+	owners  []int // index of the line of each chunk
+}
+
+// pdfTable is a table found on a page, as rows of cells
+type pdfTable struct {
+	top, bottom float64
+	x, right    float64
+	rows        [][]string
+	lines       []int
+}
+
+// listMarkerPattern matches the bullet or number of a list item standing apart from its
+// text, which would otherwise read as a column
+var listMarkerPattern = regexp.MustCompile(`^([•◦▪▫●○■□‣⁃–*-]|\(?[0-9]{1,2}[.)]|\(?[a-zA-Z][.)])$`)
+
+// extractPDFTables takes the tables out of the lines of a page. A table is a run of
+// rows whose cells line up in columns: there is a gap in every row at the same place.
+// Tables are looked for across the whole page first, then in each of its columns, where
+// the rows of a table share their baselines with the text of the other column.
+func extractPDFTables(lines []pdfLine) ([]pdfLine, []pdfTable) {
+	rest, tables := findPDFTables(lines)
+	if left, right, crossing, ok := splitPDFColumns(rest); ok {
+		var leftTables, rightTables []pdfTable
+		left, leftTables = findPDFTables(left)
+		right, rightTables = findPDFTables(right)
+		if len(leftTables)+len(rightTables) > 0 {
+			tables = append(append(tables, leftTables...), rightTables...)
+			rest = append(append(left, right...), crossing...)
+		}
+	}
+	return rest, tables
+}
+
+// findPDFTables finds tables in lines, returning the lines outside them
+func findPDFTables(lines []pdfLine) ([]pdfLine, []pdfTable) {
+	rows := pdfRows(lines)
+	used := make([]bool, len(lines))
+	var tables []pdfTable
+
+	for i := 0; i < len(rows); {
+		if len(rows[i].chunks) < 2 {
+			i++
+			continue
+		}
+
+		// Grow the run over rows set close below each other. A row with a single cell
+		// may sit between rows with more, as a wrapped cell or a group label does.
+		last := i
+		for j := i + 1; j < len(rows); j++ {
+			if rows[j-1].y-rows[j].y > 3*math.Max(rows[j-1].size, rows[j].size) {
+				break
+			}
+			if len(rows[j].chunks) >= 2 {
+				last = j
+			} else if j-last > 1 {
+				break
+			}
+		}
+
+		table, ok := pdfTableFromRows(rows[i : last+1])
+		if !ok {
+			i++
+			continue
+		}
+		for _, line := range table.lines {
+			used[line] = true
+		}
+		tables = append(tables, table)
+		i = last + 1
+	}
+
+	if len(tables) == 0 {
+		return lines, nil
+	}
+	var rest []pdfLine
+	for i, line := range lines {
+		if !used[i] {
+			rest = append(rest, line)
+		}
+	}
+	return rest, tables
+}
+
+// pdfRows groups lines sharing a baseline into rows, top to bottom. Code is left out, as
+// the alignment of code is not a table's.
+func pdfRows(lines []pdfLine) []pdfRow {
+	order := make([]int, 0, len(lines))
+	for i, line := range lines {
+		if !line.mono {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return lines[order[a]].y > lines[order[b]].y })
+
+	var rows []pdfRow
+	for _, i := range order {
+		line := lines[i]
+		if n := len(rows); n > 0 && math.Abs(rows[n-1].y-line.y) <= 0.3*math.Max(rows[n-1].size, line.size) {
+			rows[n-1].lines = append(rows[n-1].lines, i)
+			rows[n-1].size = math.Max(rows[n-1].size, line.size)
+			continue
+		}
+		rows = append(rows, pdfRow{y: line.y, size: line.size, lines: []int{i}})
+	}
+
+	for r := range rows {
+		row := &rows[r]
+		for _, i := range row.lines {
+			for _, chunk := range lines[i].chunks {
+				row.chunks = append(row.chunks, chunk)
+				row.owners = append(row.owners, i)
+			}
+		}
+		sort.Sort(chunksByX{row})
+	}
+	return rows
+}
+
+// chunksByX sorts the chunks of a row together with the lines they belong to
+type chunksByX struct{ row *pdfRow }
+
+func (c chunksByX) Len() int           { return len(c.row.chunks) }
+func (c chunksByX) Less(i, j int) bool { return c.row.chunks[i].x < c.row.chunks[j].x }
+func (c chunksByX) Swap(i, j int) {
+	c.row.chunks[i], c.row.chunks[j] = c.row.chunks[j], c.row.chunks[i]
+	c.row.owners[i], c.row.owners[j] = c.row.owners[j], c.row.owners[i]
+}
+
+// pdfTableFromRows reads a run of rows as a table, if its cells line up in columns
+func pdfTableFromRows(run []pdfRow) (pdfTable, bool) {
+	// Columns are where the cells of the rows with several cells overlap
+	type column struct{ x, right float64 }
+	var spans []column
+	for _, row := range run {
+		if len(row.chunks) >= 2 {
+			for _, chunk := range row.chunks {
+				spans = append(spans, column{chunk.x, chunk.right})
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].x < spans[j].x })
+	var columns []column
+	for _, span := range spans {
+		if n := len(columns); n > 0 && span.x <= columns[n-1].right {
+			columns[n-1].right = math.Max(columns[n-1].right, span.right)
+			continue
+		}
+		columns = append(columns, span)
+	}
+	if len(columns) < 2 {
+		return pdfTable{}, false
+	}
+
+	// Place every cell in its column. The run ends at a row with text across columns,
+	// such as a paragraph below the table.
+	columnOf := func(chunk pdfChunk) int {
+		for c, col := range columns {
+			if chunk.x >= col.x-1 && chunk.right <= col.right+1 {
+				return c
+			}
+		}
+		return -1
+	}
+	var cells [][]string
+	var rowLines [][]int
+	for _, row := range run {
+		cellRow := make([]string, len(columns))
+		var owners []int
+		fits := true
+		for k, chunk := range row.chunks {
+			c := columnOf(chunk)
+			if c < 0 {
+				fits = false
+				break
+			}
+			cellRow[c] = strings.TrimSpace(cellRow[c] + " " + chunk.text)
+			owners = append(owners, row.owners[k])
+		}
+		if !fits {
+			break
+		}
+		cells = append(cells, cellRow)
+		rowLines = append(rowLines, owners)
+	}
+
+	// Columns of running text at either side are the text of the other column of the
+	// page, not part of the table
+	keep := make([]bool, len(columns))
+	for c := range keep {
+		keep[c] = true
+	}
+	for c := 0; c < len(columns) && medianCellLength(cells, c) >= 30; c++ {
+		keep[c] = false
+	}
+	for c := len(columns) - 1; c >= 0 && keep[c] && medianCellLength(cells, c) >= 30; c-- {
+		keep[c] = false
+	}
+
+	table := pdfTable{top: math.Inf(-1), bottom: math.Inf(1), x: math.Inf(1), right: math.Inf(-1)}
+	wide, rowSize := 0, 0.0
+	for r, cellRow := range cells {
+		var kept []string
+		filled := 0
+		for c, cell := range cellRow {
+			if keep[c] {
+				kept = append(kept, cell)
+				if cell != "" {
+					filled++
+				}
+			}
+		}
+		if filled == 0 {
+			continue
+		}
+		if filled >= 2 {
+			wide++
+		}
+
+		// A row without a first cell continues the row above, as a wrapped cell does, and
+		// so does a single cell in smaller type, as a description of an item does
+		if len(table.rows) > 0 && (kept[0] == "" || filled == 1 && run[r].size < 0.95*rowSize) {
+			prev := table.rows[len(table.rows)-1]
+			for c, cell := range kept {
+				prev[c] = strings.TrimSpace(prev[c] + " " + cell)
+			}
+		} else {
+			table.rows = append(table.rows, kept)
+			rowSize = run[r].size
+		}
+
+		for k, chunk := range run[r].chunks {
+			if c := columnOf(chunk); keep[c] {
+				table.lines = append(table.lines, rowLines[r][k])
+				table.x, table.right = math.Min(table.x, chunk.x), math.Max(table.right, chunk.right)
+			}
+		}
+		table.top, table.bottom = math.Max(table.top, run[r].y), math.Min(table.bottom, run[r].y)
+	}
+
+	columnCount := 0
+	for _, k := range keep {
+		if k {
+			columnCount++
+		}
+	}
+	if columnCount < 2 || wide < 3 && !(wide == 2 && columnCount >= 3) {
+		return pdfTable{}, false
+	}
+
+	// A column of bullets or numbers is a list
+	markers := 0
+	for _, row := range table.rows {
+		if listMarkerPattern.MatchString(row[0]) {
+			markers++
+		}
+	}
+	if markers*2 > len(table.rows) {
+		return pdfTable{}, false
+	}
+
+	// A line split between the table and the text beside it belongs to neither
+	for r := range cells {
+		for k, chunk := range run[r].chunks {
+			if !keep[columnOf(chunk)] {
+				for _, line := range table.lines {
+					if line == rowLines[r][k] {
+						return pdfTable{}, false
+					}
+				}
+			}
+		}
+	}
+	table.lines = uniqueInts(table.lines)
+	return table, true
+}
+
+// medianCellLength returns the median length of the filled cells of a column
+func medianCellLength(cells [][]string, column int) int {
+	var lengths []int
+	for _, row := range cells {
+		if row[column] != "" {
+			lengths = append(lengths, len([]rune(row[column])))
+		}
+	}
+	if len(lengths) == 0 {
+		return 0
+	}
+	sort.Ints(lengths)
+	return lengths[len(lengths)/2]
+}
+
+func uniqueInts(values []int) []int {
+	seen := make(map[int]bool)
+	unique := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// placePDFTables puts the tables of a page among its paragraphs, before the first
+// paragraph below the top of the table in the same column
+func placePDFTables(paragraphs []*pdfParagraph, tables []pdfTable) []*pdfParagraph {
+	for _, table := range tables {
+		para := &pdfParagraph{table: table.rows}
+		at := len(paragraphs)
+		for i, p := range paragraphs {
+			if p.table != nil || len(p.lines) == 0 {
+				continue
+			}
+			first := p.lines[0]
+			overlaps := first.x < table.right && first.right > table.x
+			if first.y < table.top && overlaps {
+				at = i
+				break
+			}
+		}
+		paragraphs = append(paragraphs[:at], append([]*pdfParagraph{para}, paragraphs[at:]...)...)
+	}
+	return paragraphs
+}
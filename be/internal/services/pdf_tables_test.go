@@ -0,0 +1,93 @@
+package services
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// pdfTestLine lays out a line at height y whose cells start at the given x positions,
+// 6 points per character in 10 point type
+func pdfTestLine(y float64, cells map[float64]string) pdfLine {
+	line := pdfLine{y: y, size: 10, x: 1e9}
+	var xs []float64
+	for x := range cells {
+		xs = append(xs, x)
+	}
+	sort.Float64s(xs)
+
+	var texts []string
+	for _, x := range xs {
+		text := cells[x]
+		right := x + 6*float64(len(text))
+		line.chunks = append(line.chunks, pdfChunk{x: x, right: right, text: text})
+		line.x, line.right = math.Min(line.x, x), right
+		texts = append(texts, text)
+	}
+	line.text = strings.Join(texts, " ")
+	return line
+}
+
+func TestFindPDFTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []pdfLine
+		want  [][]string // rows of the one table found, nil for none
+		rest  int        // lines left outside the table
+	}{
+		{name: "no lines"},
+		{
+			name: "prose",
+			lines: []pdfLine{
+				pdfTestLine(700, map[float64]string{72: "Cells divide by mitosis, which makes two cells."}),
+				pdfTestLine(688, map[float64]string{72: "Meiosis makes four cells with half the DNA."}),
+			},
+			rest: 2,
+		},
+		{
+			name: "list with bullets standing apart",
+			lines: []pdfLine{
+				pdfTestLine(700, map[float64]string{72: "•", 100: "Mitosis"}),
+				pdfTestLine(688, map[float64]string{72: "•", 100: "Meiosis"}),
+				pdfTestLine(676, map[float64]string{72: "•", 100: "Osmosis"}),
+			},
+			rest: 3,
+		},
+		{
+			name: "table with a wrapped cell and text below",
+			lines: []pdfLine{
+				pdfTestLine(700, map[float64]string{72: "Group", 200: "Mean", 300: "SD"}),
+				pdfTestLine(688, map[float64]string{72: "A", 200: "4.1", 300: "0.5"}),
+				pdfTestLine(676, map[float64]string{72: "B", 200: "5.3", 300: "0.7"}),
+				pdfTestLine(664, map[float64]string{200: "adj."}),
+				pdfTestLine(652, map[float64]string{72: "C", 200: "6.0", 300: "0.4"}),
+				pdfTestLine(620, map[float64]string{72: "The means rise from group A to group C, as the dose does."}),
+			},
+			want: [][]string{{"Group", "Mean", "SD"}, {"A", "4.1", "0.5"}, {"B", "5.3 adj.", "0.7"}, {"C", "6.0", "0.4"}},
+			rest: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, tables := findPDFTables(tt.lines)
+			if tt.want == nil {
+				if len(tables) != 0 {
+					t.Fatalf("tables found: %v", tables[0].rows)
+				}
+			} else {
+				if len(tables) != 1 {
+					t.Fatalf("%d tables found, want 1", len(tables))
+				}
+				if !reflect.DeepEqual(tables[0].rows, tt.want) {
+					t.Errorf("rows = %q\nwant %q", tables[0].rows, tt.want)
+				}
+			}
+			if len(rest) != tt.rest {
+				t.Errorf("%d lines outside tables, want %d", len(rest), tt.rest)
+			}
+		})
+	}
+}
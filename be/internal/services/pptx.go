@@ -0,0 +1,300 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"pbkk-quizlit-backend/internal/models"
+	"strconv"
+	"strings"
+)
+
+// pptxShape collects the paragraphs of one shape of a slide while it is read
+type pptxShape struct {
+	placeholder string // placeholder type, "body" for content placeholders, "" for none
+	plain       bool   // never bulleted, as in speaker notes
+	lines       []string
+	counters    []int
+}
+
+// pptxParagraph collects the text and bullet properties of an <a:p>
+type pptxParagraph struct {
+	text   strings.Builder
+	level  int
+	bullet string // "char", "number" or "none"; "" when the paragraph does not say
+}
+
+// pptxSlide is the text of one slide, split by role
+type pptxSlide struct {
+	title  string
+	body   []string
+	hidden bool
+}
+
+// processPPTXFile extracts every visible slide as one page, numbered like PowerPoint
+// numbers it: the title as a "#" line, bullets as "-" lines indented by level, tables
+// as rows of cells, and the speaker notes at the end.
+func (fs *FileService) processPPTXFile(content []byte) ([]models.DocumentPage, error) {
+	archive, err := openZipArchive(content, fs.limits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PPTX file: %w", err)
+	}
+
+	slidePaths, err := pptxSlideOrder(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []models.DocumentPage
+	for i, slidePath := range slidePaths {
+		data, err := readZipPart(archive, slidePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read slide %d: %w", i+1, err)
+		}
+		slide, err := readPPTXSlide(data, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse slide %d: %w", i+1, err)
+		}
+		if slide.hidden {
+			continue
+		}
+
+		var notes []string
+		for _, rel := range officeRelationships(archive, slidePath) {
+			if rel.Type != "notesSlide" {
+				continue
+			}
+			if data, err := readZipPart(archive, rel.Target); err == nil {
+				if notesSlide, err := readPPTXSlide(data, true); err == nil {
+					notes = notesSlide.body
+				}
+			}
+		}
+
+		if text := slide.text(notes); text != "" {
+			pages = append(pages, models.DocumentPage{Number: i + 1, Label: fmt.Sprintf("Slide %d", i+1), Text: text})
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no text content found in PPTX")
+	}
+	return pages, nil
+}
+
+// pptxSlideOrder returns the slide parts in presentation order
+func pptxSlideOrder(archive *zipArchive) ([]string, error) {
+	const presentationPath = "ppt/presentation.xml"
+	data, err := readZipPart(archive, presentationPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PPTX presentation: %w", err)
+	}
+
+	var presentation struct {
+		Slides []struct {
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := xml.Unmarshal(data, &presentation); err != nil {
+		return nil, fmt.Errorf("failed to parse PPTX presentation: %w", err)
+	}
+
+	rels := officeRelationships(archive, presentationPath)
+	var paths []string
+	for _, slide := range presentation.Slides {
+		for _, a := range slide.Attrs {
+			// The relationship ID is the r:id attribute; the plain id is the slide ID
+			if a.Name.Local != "id" || a.Name.Space == "" {
+				continue
+			}
+			if rel, ok := rels[a.Value]; ok && rel.Type == "slide" {
+				paths = append(paths, rel.Target)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// readPPTXSlide reads the shapes of a slide or notes slide in document order. Notes
+// slides also hold a picture of the slide and its number, which are skipped.
+func readPPTXSlide(data []byte, notes bool) (*pptxSlide, error) {
+	slide := &pptxSlide{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var shape *pptxShape
+	var para *pptxParagraph
+	var table *docxTable
+	inText := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sld":
+				slide.hidden = attr(t, "show") == "0"
+			case "Fallback":
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			case "sp":
+				shape = &pptxShape{plain: notes}
+			case "ph":
+				if shape != nil {
+					shape.placeholder = attr(t, "type")
+					if shape.placeholder == "" || shape.placeholder == "obj" {
+						shape.placeholder = "body"
+					}
+				}
+			case "tbl":
+				table = &docxTable{}
+			case "tr":
+				if table != nil {
+					table.row = nil
+				}
+			case "tc":
+				if table != nil {
+					table.cell = []string{}
+				}
+			case "p":
+				para = &pptxParagraph{}
+			case "pPr":
+				if para != nil {
+					para.level, _ = strconv.Atoi(attr(t, "lvl"))
+				}
+			case "buNone":
+				if para != nil {
+					para.bullet = "none"
+				}
+			case "buChar", "buBlip":
+				if para != nil {
+					para.bullet = "char"
+				}
+			case "buAutoNum":
+				if para != nil {
+					para.bullet = "number"
+				}
+			case "t":
+				inText = para != nil
+			case "br":
+				if para != nil {
+					para.text.WriteString("\n")
+				}
+			}
+
+		case xml.CharData:
+			if inText {
+				para.text.Write(t)
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if para == nil {
+					break
+				}
+				text := strings.Join(strings.Fields(para.text.String()), " ")
+				switch {
+				case text == "":
+				case table != nil && table.cell != nil:
+					table.cell = append(table.cell, text)
+				case shape != nil:
+					shape.addParagraph(para, text)
+				}
+				para = nil
+			case "tc":
+				if table != nil && table.cell != nil {
+					table.row = append(table.row, strings.Join(table.cell, " "))
+					table.cell = nil
+				}
+			case "tr":
+				if table != nil && len(table.row) > 0 {
+					table.rows = append(table.rows, table.row)
+				}
+			case "tbl":
+				if table != nil {
+					for _, row := range table.rows {
+						slide.body = append(slide.body, "| "+strings.Join(row, " | ")+" |")
+					}
+				}
+				table = nil
+			case "sp":
+				if shape != nil {
+					slide.addShape(shape, notes)
+				}
+				shape = nil
+			}
+		}
+	}
+
+	return slide, nil
+}
+
+// addParagraph formats a paragraph as a bullet when the shape is a content placeholder
+// (which PowerPoint bullets by default) or the paragraph asks for a bullet itself.
+// Speaker notes are prose and never bulleted.
+func (s *pptxShape) addParagraph(para *pptxParagraph, text string) {
+	bulleted := para.bullet == "char" || para.bullet == "number" ||
+		(para.bullet == "" && s.placeholder == "body")
+	if !bulleted || s.plain {
+		s.lines = append(s.lines, text)
+		return
+	}
+
+	for len(s.counters) <= para.level {
+		s.counters = append(s.counters, 0)
+	}
+	s.counters[para.level]++
+	for i := para.level + 1; i < len(s.counters); i++ {
+		s.counters[i] = 0
+	}
+
+	marker := "-"
+	if para.bullet == "number" {
+		marker = strconv.Itoa(s.counters[para.level]) + "."
+	}
+	s.lines = append(s.lines, strings.Repeat("  ", para.level)+marker+" "+text)
+}
+
+// addShape files the text of a finished shape as the slide's title or body
+func (s *pptxSlide) addShape(shape *pptxShape, notes bool) {
+	if len(shape.lines) == 0 {
+		return
+	}
+
+	switch shape.placeholder {
+	case "title", "ctrTitle":
+		if !notes && s.title == "" {
+			s.title = strings.Join(shape.lines, " ")
+			return
+		}
+	case "sldNum", "sldImg", "dt", "ftr", "hdr":
+		return
+	}
+	s.body = append(s.body, shape.lines...)
+}
+
+// text lays the slide out as one page
+func (s *pptxSlide) text(notes []string) string {
+	var parts []string
+	if s.title != "" {
+		parts = append(parts, "# "+s.title)
+	}
+	if len(s.body) > 0 {
+		parts = append(parts, strings.Join(s.body, "\n"))
+	}
+	if len(notes) > 0 {
+		parts = append(parts, "Speaker notes: "+strings.Join(notes, " "))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const (
+	pptxNS = `xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" ` +
+		`xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	pptxRelType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+)
+
+// pptxPresentation lists the slides of a presentation.xml by relationship ID
+func pptxPresentation(ids ...string) string {
+	list := ""
+	for i, id := range ids {
+		list += `<p:sldId id="` + strconv.Itoa(256+i) + `" r:id="` + id + `"/>`
+	}
+	return `<p:presentation ` + pptxNS + `><p:sldIdLst>` + list + `</p:sldIdLst></p:presentation>`
+}
+
+// pptxRels writes a relationships part linking IDs to targets of a type
+func pptxRels(relType string, targets map[string]string) string {
+	rels := ""
+	for id, target := range targets {
+		rels += `<Relationship Id="` + id + `" Type="` + pptxRelType + relType + `" Target="` + target + `"/>`
+	}
+	return `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + rels + `</Relationships>`
+}
+
+// pptxShapeXML writes a shape of a placeholder type ("" for a plain text box) holding
+// paragraphs
+func pptxShapeXML(placeholder string, paragraphs ...string) string {
+	ph := ""
+	if placeholder != "" {
+		ph = `<p:nvSpPr><p:nvPr><p:ph type="` + placeholder + `"/></p:nvPr></p:nvSpPr>`
+	}
+	body := ""
+	for _, p := range paragraphs {
+		body += `<a:p><a:r><a:t>` + p + `</a:t></a:r></a:p>`
+	}
+	return `<p:sp>` + ph + `<p:txBody>` + body + `</p:txBody></p:sp>`
+}
+
+func pptxSlideXML(attrs, shapes string) string {
+	return `<p:sld ` + pptxNS + attrs + `><p:cSld><p:spTree>` + shapes + `</p:spTree></p:cSld></p:sld>`
+}
+
+func TestProcessPPTXFile(t *testing.T) {
+	table := `<p:graphicFrame><a:graphic><a:graphicData><a:tbl>` +
+		`<a:tr><a:tc><a:txBody><a:p><a:r><a:t>Organ</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>Role</a:t></a:r></a:p></a:txBody></a:tc></a:tr>` +
+		`<a:tr><a:tc><a:txBody><a:p><a:r><a:t>Heart</a:t></a:r></a:p></a:txBody></a:tc><a:tc><a:txBody><a:p><a:r><a:t>Pumps blood</a:t></a:r></a:p></a:txBody></a:tc></a:tr>` +
+		`</a:tbl></a:graphicData></a:graphic></p:graphicFrame>`
+
+	deck := map[string]string{
+		"ppt/presentation.xml":            pptxPresentation("rId1", "rId2", "rId3"),
+		"ppt/_rels/presentation.xml.rels": pptxRels("slide", map[string]string{"rId1": "slides/slide1.xml", "rId2": "slides/slide2.xml", "rId3": "slides/slide3.xml"}),
+		"ppt/slides/slide1.xml": pptxSlideXML("", pptxShapeXML("title", "The Heart")+
+			pptxShapeXML("body", "Four chambers", "Two valves")),
+		"ppt/slides/_rels/slide1.xml.rels": pptxRels("notesSlide", map[string]string{"rId9": "../notesSlides/notesSlide1.xml"}),
+		"ppt/notesSlides/notesSlide1.xml": pptxSlideXML("", pptxShapeXML("sldImg")+
+			pptxShapeXML("body", "Mention the septum.")+pptxShapeXML("sldNum", "1")),
+		"ppt/slides/slide2.xml": pptxSlideXML(` show="0"`, pptxShapeXML("title", "Hidden")),
+		"ppt/slides/slide3.xml": pptxSlideXML("", pptxShapeXML("title", "Organs")+table),
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string // nil for an empty file
+		want  []models.DocumentPage
+	}{
+		{name: "empty file"},
+		{name: "no presentation", files: map[string]string{"ppt/slides/slide1.xml": pptxSlideXML("", "")}},
+		{name: "malformed presentation", files: map[string]string{"ppt/presentation.xml": `<p:presentation ` + pptxNS + `><p:sldIdLst>`}},
+		{
+			name: "malformed slide",
+			files: map[string]string{
+				"ppt/presentation.xml":            pptxPresentation("rId1"),
+				"ppt/_rels/presentation.xml.rels": pptxRels("slide", map[string]string{"rId1": "slides/slide1.xml"}),
+				"ppt/slides/slide1.xml":           `<p:sld ` + pptxNS + `><p:cSld><a:t>Heart</p:cSld>`,
+			},
+		},
+		{
+			name: "missing slide",
+			files: map[string]string{
+				"ppt/presentation.xml":            pptxPresentation("rId1"),
+				"ppt/_rels/presentation.xml.rels": pptxRels("slide", map[string]string{"rId1": "slides/slide1.xml"}),
+			},
+		},
+		{
+			name: "no text",
+			files: map[string]string{
+				"ppt/presentation.xml":            pptxPresentation("rId1"),
+				"ppt/_rels/presentation.xml.rels": pptxRels("slide", map[string]string{"rId1": "slides/slide1.xml"}),
+				"ppt/slides/slide1.xml":           pptxSlideXML("", pptxShapeXML("title", " ")),
+			},
+		},
+		{
+			name:  "titles, bullets, tables and notes",
+			files: deck,
+			want: []models.DocumentPage{
+				{Number: 1, Label: "Slide 1", Text: "# The Heart\n\n- Four chambers\n- Two valves\n\nSpeaker notes: Mention the septum."},
+				{Number: 3, Label: "Slide 3", Text: "# Organs\n\n| Organ | Role |\n| Heart | Pumps blood |"},
+			},
+		},
+	}
+
+	fs := NewFileService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content []byte
+			if tt.files != nil {
+				content = buildZip(t, tt.files)
+			}
+
+			pages, err := fs.processPPTXFile(content)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("no error, pages %+v", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processPPTXFile: %v", err)
+			}
+			if !reflect.DeepEqual(pages, tt.want) {
+				t.Errorf("pages = %+v\nwant %+v", pages, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlideGenerationText(t *testing.T) {
+	slides := []models.DocumentPage{
+		{Number: 1, Text: "# The Heart\n\n- Four chambers\n  1. Two atria\n\nSpeaker notes: Mention the septum."},
+		{Number: 2, Text: "| Organ | Role |\n| Heart | Pumps blood |\n\nWhat does the heart do?"},
+	}
+	want := "The Heart. Four chambers. Two atria. Speaker notes: Mention the septum. " +
+		"Organ, Role. Heart, Pumps blood. What does the heart do?"
+
+	if got := slideGenerationText(slides); got != want {
+		t.Errorf("slideGenerationText = %q\nwant %q", got, want)
+	}
+}
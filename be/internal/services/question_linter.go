@@ -0,0 +1,326 @@
+package services
+
+import (
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Lint severities
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+	LintInfo    = "info"
+)
+
+var (
+	// Options that let students answer by elimination instead of knowledge
+	catchAllOptionPattern = regexp.MustCompile(`(?i)^(all|none|both|neither) of the (above|below|options)$|^(semua|tidak ada) (jawaban )?(di atas|benar|salah)$`)
+
+	// Negative words in a stem, which students easily overlook
+	negativeStemPattern = regexp.MustCompile(`(?i)\b(not|except|never|least|incorrect|false|bukan|kecuali|tidak|salah)\b`)
+
+	// Question-type prefixes that are not part of the stem itself
+	stemPrefixPattern = regexp.MustCompile(`^(Complete the sentence|True or False|Fill in the blank|Multiple Choice):\s*`)
+
+	sentenceEndPattern = regexp.MustCompile(`[.!?]+`)
+)
+
+// LintQuiz checks every question of a quiz for common item-writing flaws. When source is
+// not empty the answer keys are also checked against the source text.
+func LintQuiz(quiz *models.Quiz, source string) *models.LintReport {
+	report := &models.LintReport{
+		QuizID:        quiz.ID,
+		Questions:     len(quiz.Questions),
+		SourceChecked: strings.TrimSpace(source) != "",
+		Issues:        []models.LintIssue{},
+	}
+
+	sourceLower := strings.ToLower(source)
+	for i, q := range quiz.Questions {
+		for _, issue := range LintQuestion(q, sourceLower) {
+			issue.QuestionID = q.ID
+			issue.QuestionIndex = i
+			report.Issues = append(report.Issues, issue)
+
+			switch issue.Severity {
+			case LintError:
+				report.Errors++
+			case LintWarning:
+				report.Warnings++
+			}
+		}
+	}
+
+	// Keys that always sit in the same position can be guessed
+	if position, ok := sameKeyPosition(quiz.Questions); ok {
+		report.Issues = append(report.Issues, models.LintIssue{
+			QuestionIndex: -1,
+			Rule:          "key-position",
+			Severity:      LintWarning,
+			Message:       fmt.Sprintf("The correct answer is option %d in every multiple-choice question", position+1),
+		})
+		report.Warnings++
+	}
+
+	return report
+}
+
+// sameKeyPosition reports whether every multiple-choice question of a quiz with at
+// least four of them has its key at the same option index
+func sameKeyPosition(questions []models.Question) (int, bool) {
+	position, count := -1, 0
+	for _, q := range questions {
+		if isTrueFalse(q) || len(q.Options) < 3 {
+			continue
+		}
+		if count > 0 && q.CorrectAnswer != position {
+			return 0, false
+		}
+		position = q.CorrectAnswer
+		count++
+	}
+	return position, count >= 4
+}
+
+// LintQuestion returns the issues of a single question. sourceLower is the lowercased
+// source text, or empty to skip the source checks.
+func LintQuestion(q models.Question, sourceLower string) []models.LintIssue {
+	var issues []models.LintIssue
+	add := func(rule, severity, format string, args ...interface{}) {
+		issues = append(issues, models.LintIssue{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	stem := q.Text
+	if stem == "" {
+		stem = q.Question
+	}
+	stem = strings.TrimSpace(stemPrefixPattern.ReplaceAllString(stem, ""))
+
+	if stem == "" {
+		add("empty-stem", LintError, "Question has no text")
+		return issues
+	}
+	if len(q.Options) < 2 {
+		add("too-few-options", LintError, "Question has %d options, at least 2 are needed", len(q.Options))
+		return issues
+	}
+
+	key, hasKey := questionKey(q)
+	if !hasKey {
+		add("missing-key", LintError, "Correct answer does not match any option")
+	} else if q.Correct != "" && !strings.EqualFold(strings.TrimSpace(q.Correct), strings.TrimSpace(key)) {
+		add("key-mismatch", LintError, "Correct answer %q is not the option marked as correct (%q)", q.Correct, key)
+	}
+
+	// Duplicate and empty options
+	seen := make(map[string]int)
+	for i, option := range q.Options {
+		normalized := strings.ToLower(strings.Join(strings.Fields(option), " "))
+		if normalized == "" {
+			add("empty-option", LintError, "Option %d is empty", i+1)
+			continue
+		}
+		if first, ok := seen[normalized]; ok {
+			add("duplicate-options", LintError, "Options %d and %d are the same", first+1, i+1)
+			continue
+		}
+		seen[normalized] = i
+	}
+
+	// Catch-all options
+	for i, option := range q.Options {
+		if catchAllOptionPattern.MatchString(strings.TrimSpace(strings.TrimRight(option, "."))) {
+			add("catch-all-option", LintWarning, "Option %d (%q) can be answered by elimination", i+1, option)
+		}
+	}
+
+	// Negative stems
+	if match := negativeStemPattern.FindString(stem); match != "" && !isTrueFalse(q) && match != strings.ToUpper(match) {
+		add("negative-stem", LintWarning, "Stem is phrased negatively (%q); rephrase it or emphasise the word", match)
+	}
+
+	if hasKey && !isTrueFalse(q) {
+		// Answer leakage: the key, or a distinctive word of it, is given away by the stem
+		if len([]rune(key)) >= 4 && containsWholeWord(strings.ToLower(stem), strings.ToLower(key)) {
+			add("answer-leak", LintError, "Stem contains the correct answer %q", key)
+		} else if word := leakedKeyWord(stem, key, q.Options); word != "" {
+			add("answer-leak", LintWarning, "Only the correct answer shares the word %q with the stem", word)
+		}
+
+		// Option lengths
+		lintOptionLengths(q.Options, q.CorrectAnswer, add)
+	}
+
+	// Key must be supported by the source
+	if hasKey && sourceLower != "" && !isTrueFalse(q) && !keyInSource(key, sourceLower) {
+		add("key-not-in-source", LintWarning, "Correct answer %q was not found in the source document", key)
+	}
+
+	// Readability of the stem
+	words := len(strings.Fields(stem))
+	if words > 40 {
+		add("long-stem", LintInfo, "Stem has %d words; consider shortening it", words)
+	}
+	if grade := readingGrade(stem); grade > 14 {
+		add("readability", LintInfo, "Stem reads at grade level %.0f; consider simpler wording", grade)
+	}
+
+	return issues
+}
+
+// questionKey returns the text of the correct option
+func questionKey(q models.Question) (string, bool) {
+	if q.CorrectAnswer >= 0 && q.CorrectAnswer < len(q.Options) {
+		return q.Options[q.CorrectAnswer], true
+	}
+	return "", false
+}
+
+func isTrueFalse(q models.Question) bool {
+	if q.Type == "true-false" {
+		return true
+	}
+	if len(q.Options) != 2 {
+		return false
+	}
+	a, b := strings.ToLower(q.Options[0]), strings.ToLower(q.Options[1])
+	return (a == "true" && b == "false") || (a == "benar" && b == "salah")
+}
+
+// lintOptionLengths flags options of very different lengths, and keys that stand out by
+// being much longer than every distractor
+func lintOptionLengths(options []string, correct int, add func(rule, severity, format string, args ...interface{})) {
+	shortest, longest := -1, 0
+	longestDistractor := 0
+	for i, option := range options {
+		length := len([]rune(strings.TrimSpace(option)))
+		if shortest < 0 || length < shortest {
+			shortest = length
+		}
+		if length > longest {
+			longest = length
+		}
+		if i != correct && length > longestDistractor {
+			longestDistractor = length
+		}
+	}
+
+	keyLength := len([]rune(strings.TrimSpace(options[correct])))
+	if longestDistractor > 0 && keyLength >= 20 && float64(keyLength) > 1.5*float64(longestDistractor) {
+		add("long-key", LintWarning, "Correct answer is much longer than every other option")
+		return
+	}
+	if shortest > 0 && longest >= 20 && longest > 3*shortest {
+		add("option-length", LintInfo, "Options range from %d to %d characters", shortest, longest)
+	}
+}
+
+// leakedKeyWord returns a content word that the stem shares with the key but with no
+// distractor, which hints at the answer
+func leakedKeyWord(stem, key string, options []string) string {
+	stemWords := make(map[string]bool)
+	for _, word := range lintWords(stem) {
+		stemWords[word] = true
+	}
+
+	for _, word := range lintWords(key) {
+		if len([]rune(word)) < 5 || !stemWords[word] {
+			continue
+		}
+		inDistractor := false
+		for _, option := range options {
+			if option != key && containsWholeWord(strings.ToLower(option), word) {
+				inDistractor = true
+				break
+			}
+		}
+		if !inDistractor {
+			return word
+		}
+	}
+	return ""
+}
+
+// keyInSource reports whether the key, or most of its content words, appear in the source
+func keyInSource(key, sourceLower string) bool {
+	keyLower := strings.ToLower(strings.TrimSpace(key))
+	if strings.Contains(sourceLower, keyLower) {
+		return true
+	}
+
+	words := lintWords(key)
+	if len(words) == 0 {
+		return true
+	}
+	found := 0
+	for _, word := range words {
+		if strings.Contains(sourceLower, word) {
+			found++
+		}
+	}
+	return float64(found) >= 0.6*float64(len(words))
+}
+
+// lintWords returns the lowercased words of a text that are not stop words
+func lintWords(text string) []string {
+	var words []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !englishStopWords[word] && !indonesianStopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+func containsWholeWord(text, word string) bool {
+	pattern, err := wholeWordPattern(word)
+	if err != nil {
+		return false
+	}
+	return pattern.MatchString(text)
+}
+
+// readingGrade estimates the Flesch-Kincaid grade level of a text, counting syllables as
+// vowel groups. It is only a rough guide for Indonesian text.
+func readingGrade(text string) float64 {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(words) < 12 {
+		return 0
+	}
+
+	sentences := len(sentenceEndPattern.FindAllString(text, -1))
+	if sentences == 0 {
+		sentences = 1
+	}
+
+	syllables := 0
+	for _, word := range words {
+		syllables += countSyllables(word)
+	}
+
+	return 0.39*float64(len(words))/float64(sentences) + 11.8*float64(syllables)/float64(len(words)) - 15.59
+}
+
+func countSyllables(word string) int {
+	count := 0
+	previousVowel := false
+	for _, r := range strings.ToLower(word) {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+	if strings.HasSuffix(strings.ToLower(word), "e") && count > 1 {
+		count--
+	}
+	if count == 0 {
+		count = 1
+	}
+	return count
+}
@@ -28,6 +28,7 @@ func (qs *QuizService) CreateQuiz(quiz *models.Quiz, userID string) error {
 	quiz.CreatedAt = time.Now()
 	quiz.UpdatedAt = time.Now()
 	quiz.TotalQuestions = len(quiz.Questions)
+	TagBloomLevels(quiz.Questions)
 
 	// Try to save to database
 	ctx := context.Background()
@@ -89,3 +90,38 @@ func (qs *QuizService) ListUserAttempts(userID string) ([]map[string]interface{}
 	}
 	return attempts, nil
 }
+
+func (qs *QuizService) GetTranslationStats(quizID string) ([]models.QuizTranslationStats, error) {
+	ctx := context.Background()
+	stats, err := qs.repo.GetTranslationStats(ctx, quizID)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetBloomCoverage reports the cognitive levels covered by a quiz and how each was answered
+func (qs *QuizService) GetBloomCoverage(quiz *models.Quiz) (*models.BloomCoverage, error) {
+	ctx := context.Background()
+	attempts, err := qs.repo.GetAttemptAnswers(ctx, quiz.ID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildBloomCoverage(quiz, attempts), nil
+}
+
+// GetAttemptRecord returns the owner, quiz and chosen answers of an attempt
+func (qs *QuizService) GetAttemptRecord(attemptID string) (*models.AttemptAnswers, error) {
+	ctx := context.Background()
+	return qs.repo.GetAttemptRecord(ctx, attemptID)
+}
+
+func (qs *QuizService) GetAnswerExplanation(questionID, option string) (*models.MistakeExplanation, error) {
+	ctx := context.Background()
+	return qs.repo.GetAnswerExplanation(ctx, questionID, option)
+}
+
+func (qs *QuizService) SaveAnswerExplanation(questionID, option string, explanation *models.MistakeExplanation) error {
+	ctx := context.Background()
+	return qs.repo.SaveAnswerExplanation(ctx, questionID, option, explanation)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strings"
+)
+
+// quizSlideGroups is the largest number of slide groups a deck is split into for quiz
+// generation. Each group is one generation request.
+const quizSlideGroups = 5
+
+// slideLineMarker matches the heading, bullet and number markers of extracted slide text
+var slideLineMarker = regexp.MustCompile(`^(#+|-|\d+\.)\s+`)
+
+// IsSlideDeck reports whether a file is a slide deck, whose pages are slides
+func IsSlideDeck(filename string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".pptx"
+}
+
+// GenerateQuizFromSlides generates a quiz that covers a slide deck evenly. Slides are
+// natural chunks, so the deck is split into runs of consecutive slides and each run
+// gets its share of the questions, instead of the questions clustering on the slides
+// that fit into one prompt.
+func (ai *AIService) GenerateQuizFromSlides(slides []models.DocumentPage, req *models.QuizGenerationRequest) (*models.Quiz, error) {
+	if req.QuestionCount == 0 {
+		req.QuestionCount = 10
+	}
+
+	groups := min(min(quizSlideGroups, len(slides)), req.QuestionCount)
+	if groups <= 1 {
+		return ai.GenerateQuizFromContent(slideGenerationText(slides), req)
+	}
+
+	var quiz *models.Quiz
+	for g := 0; g < groups; g++ {
+		first, last := g*len(slides)/groups, (g+1)*len(slides)/groups
+
+		groupReq := *req
+		groupReq.QuestionCount = req.QuestionCount / groups
+		if g < req.QuestionCount%groups {
+			groupReq.QuestionCount++
+		}
+
+		ai.logger.Infof("Generating %d questions from slides %d-%d", groupReq.QuestionCount, slides[first].Number, slides[last-1].Number)
+		part, err := ai.GenerateQuizFromContent(slideGenerationText(slides[first:last]), &groupReq)
+		if err != nil {
+			return nil, err
+		}
+
+		if quiz == nil {
+			quiz = part
+		} else {
+			quiz.Questions = append(quiz.Questions, part.Questions...)
+		}
+	}
+
+	quiz.TotalQuestions = len(quiz.Questions)
+	return quiz, nil
+}
+
+// slideGenerationText turns slides into prose for the generators: every title, bullet
+// and table row becomes a sentence, so bullets without full stops are not run together
+func slideGenerationText(slides []models.DocumentPage) string {
+	var sentences []string
+	for _, slide := range slides {
+		for _, line := range strings.Split(slide.Text, "\n") {
+			line = strings.TrimSpace(slideLineMarker.ReplaceAllString(strings.TrimSpace(line), ""))
+			if strings.HasPrefix(line, "|") {
+				line = strings.Join(strings.Fields(strings.ReplaceAll(strings.Trim(line, "| "), " | ", ", ")), " ")
+			}
+			if line == "" {
+				continue
+			}
+			if !strings.ContainsAny(line[len(line)-1:], ".!?:") {
+				line += "."
+			}
+			sentences = append(sentences, line)
+		}
+	}
+	return strings.Join(sentences, " ")
+}
@@ -0,0 +1,180 @@
+package services
+
+import "strings"
+
+// Stemmer reduces an inflected word to a stem shared by its variants
+type Stemmer interface {
+	Stem(word string) string
+}
+
+// stemmerFor returns the stemmer for a language code, or nil when none is available
+func stemmerFor(language string) Stemmer {
+	switch language {
+	case "en":
+		return englishStemmer{}
+	case "id":
+		return indonesianStemmer{}
+	default:
+		return nil
+	}
+}
+
+// englishStemmer is a light suffix stripper modelled on the first steps of the Porter
+// algorithm. It only has to map variants to the same key, not produce dictionary words.
+type englishStemmer struct{}
+
+func (englishStemmer) Stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	// Plurals
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	// Past tense and progressive forms
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		if strings.HasSuffix(word, suffix) {
+			stem := word[:len(word)-len(suffix)]
+			if len(stem) >= 3 && containsVowel(stem) {
+				word = restoreEnglishStem(stem)
+			}
+			break
+		}
+	}
+
+	// Derivational suffixes
+	replacements := []struct{ suffix, replacement string }{
+		{"ational", "ate"},
+		{"ization", "ize"},
+		{"fulness", "ful"},
+		{"ousness", "ous"},
+		{"iveness", "ive"},
+		{"tional", "tion"},
+		{"biliti", "ble"},
+		{"ement", ""},
+		{"ness", ""},
+		{"ment", ""},
+		{"ally", "al"},
+		{"ly", ""},
+	}
+	for _, r := range replacements {
+		if strings.HasSuffix(word, r.suffix) && len(word)-len(r.suffix)+len(r.replacement) >= 4 {
+			return word[:len(word)-len(r.suffix)] + r.replacement
+		}
+	}
+
+	return word
+}
+
+// restoreEnglishStem repairs stems left behind by removing -ed/-ing
+func restoreEnglishStem(stem string) string {
+	for _, suffix := range []string{"at", "bl", "iz"} {
+		if strings.HasSuffix(stem, suffix) {
+			return stem + "e"
+		}
+	}
+
+	n := len(stem)
+	if n >= 2 && stem[n-1] == stem[n-2] && !strings.ContainsRune("aeiouylsz", rune(stem[n-1])) {
+		return stem[:n-1]
+	}
+
+	return stem
+}
+
+func containsVowel(word string) bool {
+	return strings.ContainsAny(word, "aeiouy")
+}
+
+// indonesianStemmer removes inflectional particles, possessive pronouns, derivational
+// suffixes and prefixes following the order of the Nazief-Adriani algorithm. Without
+// a root-word dictionary it applies the common recoding rules for nasal prefixes.
+type indonesianStemmer struct{}
+
+func (indonesianStemmer) Stem(word string) string {
+	if len(word) <= 4 {
+		return word
+	}
+	original := word
+
+	// Inflectional particles and possessive pronouns
+	word = trimIndonesianSuffix(word, []string{"lah", "kah", "tah", "pun"})
+	word = trimIndonesianSuffix(word, []string{"nya", "ku", "mu"})
+
+	// Derivational suffixes
+	word = trimIndonesianSuffix(word, []string{"kan", "an", "i"})
+
+	// Derivational prefixes, at most two layers (e.g. "memper-", "diper-")
+	for i := 0; i < 2; i++ {
+		stripped := stripIndonesianPrefix(word)
+		if stripped == word || len(stripped) < 4 {
+			break
+		}
+		word = stripped
+	}
+
+	if len(word) < 3 {
+		return original
+	}
+	return word
+}
+
+func trimIndonesianSuffix(word string, suffixes []string) string {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 4 {
+			return word[:len(word)-len(suffix)]
+		}
+	}
+	return word
+}
+
+func stripIndonesianPrefix(word string) string {
+	isVowel := func(b byte) bool { return strings.IndexByte("aeiou", b) >= 0 }
+
+	for _, prefix := range []string{"ter", "ber", "per", "di", "ke", "se"} {
+		if strings.HasPrefix(word, prefix) {
+			return word[len(prefix):]
+		}
+	}
+
+	// Nasal prefixes me- and pe- with their assimilated forms
+	for _, base := range []string{"me", "pe"} {
+		if !strings.HasPrefix(word, base) || len(word) < 5 {
+			continue
+		}
+		rest := word[len(base):]
+		switch {
+		case strings.HasPrefix(rest, "ng"):
+			// meng-ambil -> ambil, meng-gali -> gali
+			return rest[2:]
+		case strings.HasPrefix(rest, "ny"):
+			// meny-apu -> sapu
+			return "s" + rest[2:]
+		case strings.HasPrefix(rest, "m"):
+			// mem-ukul -> pukul, mem-baca -> baca
+			if isVowel(rest[1]) {
+				return "p" + rest[1:]
+			}
+			return rest[1:]
+		case strings.HasPrefix(rest, "n"):
+			// men-ulis -> tulis, men-dengar -> dengar
+			if isVowel(rest[1]) {
+				return "t" + rest[1:]
+			}
+			return rest[1:]
+		case strings.IndexByte("lrwy", rest[0]) >= 0:
+			// me-lihat -> lihat
+			return rest
+		}
+	}
+
+	return word
+}
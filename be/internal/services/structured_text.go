@@ -0,0 +1,185 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strings"
+)
+
+// Extractors of formats with structure (PDF, DOCX, PPTX, Markdown, HTML, EPUB) write their
+// text the same way: headings as "#" lines, list items as "-" or "1." lines indented two
+// spaces per level, table rows as cells between "|", and code indented by four spaces.
+// Blocks are separated by blank lines, except for the items of a list.
+
+var (
+	// headingLinePattern matches a heading line of structured text
+	headingLinePattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*$`)
+	// listLinePattern matches a list item line of structured text
+	listLinePattern = regexp.MustCompile(`^( *)(?:[-*•]|\d+[.)])\s+\S`)
+	// listItemMarkerPattern matches the bullet or number of a list item block
+	listItemMarkerPattern = regexp.MustCompile(`^(?:[-*•]|\d+[.)])\s+`)
+)
+
+// textBlock is a paragraph, list item, heading or table of a document in reading order
+type textBlock struct {
+	text     string
+	listItem bool
+}
+
+// joinBlocks lays blocks out as text. List items stay on consecutive lines, other blocks
+// are separated by blank lines.
+func joinBlocks(blocks []textBlock) string {
+	var text strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			if block.listItem && blocks[i-1].listItem {
+				text.WriteString("\n")
+			} else {
+				text.WriteString("\n\n")
+			}
+		}
+		text.WriteString(block.text)
+	}
+	return text.String()
+}
+
+// headingLevel returns the level and title of a heading line, or 0 for other lines
+func headingLevel(line string) (int, string) {
+	match := headingLinePattern.FindStringSubmatch(line)
+	if match == nil {
+		return 0, ""
+	}
+	return len(match[1]), match[2]
+}
+
+// PageBlocks reads the structured text of a page back into its blocks. Text without
+// structure, such as that of plain text files, reads as paragraphs.
+func PageBlocks(page models.DocumentPage) []models.DocumentBlock {
+	var blocks []models.DocumentBlock
+	var lines []string
+	kind := ""
+
+	endBlock := func() {
+		if len(lines) > 0 {
+			text := strings.Join(lines, "\n")
+			if kind == "paragraph" {
+				text = strings.Join(strings.Fields(text), " ")
+			}
+			block := models.DocumentBlock{Kind: kind, Text: text}
+			if kind == "table" {
+				block.Rows = tableRows(lines)
+			}
+			blocks = append(blocks, block)
+		}
+		lines, kind = nil, ""
+	}
+
+	for _, line := range strings.Split(page.Text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			endBlock()
+			continue
+		}
+
+		lineKind := "paragraph"
+		switch {
+		case strings.HasPrefix(line, "    "):
+			lineKind = "code"
+		case strings.HasPrefix(line, "#"):
+			if level, title := headingLevel(line); level > 0 {
+				endBlock()
+				blocks = append(blocks, models.DocumentBlock{Kind: "heading", Level: level, Text: title})
+				continue
+			}
+		case strings.HasPrefix(line, "|"):
+			lineKind = "table"
+		case listLinePattern.MatchString(line):
+			// Every item is a block, at the depth of its indent
+			endBlock()
+			indent := len(line) - len(strings.TrimLeft(line, " "))
+			blocks = append(blocks, models.DocumentBlock{Kind: "list", Level: indent/2 + 1, Text: strings.TrimSpace(line)})
+			continue
+		}
+
+		if kind != lineKind {
+			endBlock()
+			kind = lineKind
+		}
+		lines = append(lines, line)
+	}
+	endBlock()
+	captionTables(blocks)
+
+	return blocks
+}
+
+// sectionPages splits the blocks of a format without pages of its own into one page per
+// section, labelled with its heading. Headings that directly follow each other share a
+// page, labelled with the last of them; text before the first heading is a page of its
+// own.
+func sectionPages(blocks []textBlock) []models.DocumentPage {
+	var pages []models.DocumentPage
+	var page []textBlock
+	label := ""
+	hasText := false
+
+	endPage := func() {
+		if len(page) > 0 {
+			pages = append(pages, models.DocumentPage{Number: len(pages) + 1, Label: label, Text: joinBlocks(page)})
+		}
+		page, label, hasText = nil, "", false
+	}
+
+	for _, block := range blocks {
+		level, title := headingLevel(block.text)
+		if level > 0 {
+			if hasText {
+				endPage()
+			}
+			label = title
+		} else {
+			hasText = true
+		}
+		page = append(page, block)
+	}
+	endPage()
+
+	return pages
+}
+
+// PlainText lays structured text out as prose for sentence and keyword extraction and
+// for prompts: heading marks, list markers and code indentation are dropped, and every
+// heading and list item ends a sentence, so it does not run into the next block. Tables
+// are kept as "|" rows when keepTables is set and left out otherwise.
+func PlainText(text string, keepTables bool) string {
+	var parts []string
+	for _, block := range PageBlocks(models.DocumentPage{Text: text}) {
+		switch block.Kind {
+		case "heading":
+			parts = append(parts, endSentence(block.Text))
+		case "list":
+			parts = append(parts, endSentence(listItemMarkerPattern.ReplaceAllString(block.Text, "")))
+		case "table":
+			if keepTables {
+				parts = append(parts, block.Text)
+			}
+		case "code":
+			lines := strings.Split(block.Text, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimPrefix(line, "    ")
+			}
+			parts = append(parts, strings.Join(lines, "\n"))
+		default:
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// endSentence adds a full stop to text that does not end with punctuation
+func endSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.ContainsAny(text[len(text)-1:], ".!?:;") {
+		return text
+	}
+	return text + "."
+}
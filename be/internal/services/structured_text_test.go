@@ -0,0 +1,65 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlainText(t *testing.T) {
+	structured := "# Photosynthesis\n\n" +
+		"Plants turn light into energy.\n\n" +
+		"- Light reactions\n" +
+		"  - Water is split\n" +
+		"1. Calvin cycle:\n\n" +
+		"Table 1: Rates\n\n" +
+		"| Plant | Rate |\n" +
+		"| Corn | 40 |\n\n" +
+		"    rate = light * 0.4"
+
+	tests := []struct {
+		name       string
+		keepTables bool
+		want       string
+	}{
+		{
+			name:       "without tables",
+			keepTables: false,
+			want: "Photosynthesis.\n\nPlants turn light into energy.\n\nLight reactions.\n\nWater is split.\n\n" +
+				"Calvin cycle:\n\nTable 1: Rates\n\nrate = light * 0.4",
+		},
+		{
+			name:       "with tables",
+			keepTables: true,
+			want: "Photosynthesis.\n\nPlants turn light into energy.\n\nLight reactions.\n\nWater is split.\n\n" +
+				"Calvin cycle:\n\nTable 1: Rates\n\n| Plant | Rate |\n| Corn | 40 |\n\nrate = light * 0.4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(structured, tt.keepTables); got != tt.want {
+				t.Errorf("PlainText:\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractSentencesIgnoresMarkup(t *testing.T) {
+	ai := NewAIService("")
+	structured := "# Cell Biology Basics\n\n" +
+		"- The nucleus stores the genetic material of the cell\n" +
+		"- Ribosomes build proteins from amino acids in the cytoplasm\n\n" +
+		"| Organelle | Function of the organelle in the cell |\n" +
+		"| Nucleus | Stores the genetic material of the cell |\n\n" +
+		"Mitochondria release energy from glucose during cellular respiration."
+
+	want := []string{
+		"The nucleus stores the genetic material of the cell.",
+		"Ribosomes build proteins from amino acids in the cytoplasm.",
+		"Mitochondria release energy from glucose during cellular respiration.",
+	}
+	got := ai.extractSentences(structured)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("extractSentences:\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// notesChunkSize is the number of characters of text summarised per LLM call
+	notesChunkSize = 3000
+	// notesMaxChunks caps the number of map calls; longer documents get larger chunks
+	notesMaxChunks = 12
+	// notesMaxSections is the number of top-level outline sections before they are grouped
+	notesMaxSections      = 6
+	notesPointsPerSection = 3
+	notesKeyPoints        = 5
+)
+
+// trailingListMarker matches the number of the next list item at the end of a sentence
+var trailingListMarker = regexp.MustCompile(`([.:]?)\s+\d+\.$`)
+
+// notesChunk is a run of consecutive pages summarised together
+type notesChunk struct {
+	Pages []int
+	Text  string
+}
+
+// GenerateStudyNotes summarises a document into an outline and key points. With useLLM the
+// configured provider summarises each chunk and then merges the chunk summaries; the
+// extractive summariser is used otherwise or when the provider fails.
+func (ai *AIService) GenerateStudyNotes(pages []models.DocumentPage, useLLM bool) *models.StudyNotes {
+	if useLLM {
+		notes, err := ai.generateNotesWithLLM(pages)
+		if err == nil {
+			return notes
+		}
+		ai.logger.Warnf("LLM study notes failed: %v, using extractive summary", err)
+	}
+
+	return ai.generateExtractiveNotes(pages)
+}
+
+// generateNotesWithLLM summarises every chunk (map) and merges the summaries into one
+// outline (reduce)
+func (ai *AIService) generateNotesWithLLM(pages []models.DocumentPage) (*models.StudyNotes, error) {
+	chunks := chunkPagesForNotes(pages)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("document has no text to summarise")
+	}
+
+	var sections []models.OutlineSection
+	for i, chunk := range chunks {
+		section, err := ai.summarizeChunk(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarise chunk %d: %w", i+1, err)
+		}
+		sections = append(sections, *section)
+	}
+
+	notes, err := ai.mergeChunkSummaries(sections)
+	if err != nil {
+		// The chunk summaries still make a usable flat outline
+		ai.logger.Warnf("Merging chunk summaries failed: %v, using them as the outline", err)
+		notes = &models.StudyNotes{Outline: sections}
+		for _, section := range sections {
+			if len(section.Points) > 0 && len(notes.KeyPoints) < notesKeyPoints {
+				notes.KeyPoints = append(notes.KeyPoints, section.Points[0])
+			}
+		}
+	}
+
+	notes.Method = "llm"
+	notes.GeneratedAt = time.Now()
+	return notes, nil
+}
+
+// summarizeChunk asks the provider for a title and the main points of one chunk
+func (ai *AIService) summarizeChunk(chunk notesChunk) (*models.OutlineSection, error) {
+	prompt := fmt.Sprintf(`Summarise the following part of a study document.
+
+Content:
+%s
+
+Requirements:
+- "title" is a short heading for this part
+- "points" are %d to %d concise study notes covering its main ideas
+- Write in the same language as the content
+- Return the response as a JSON object
+
+JSON Format:
+{
+  "title": "Heading",
+  "points": ["First main idea", "Second main idea"]
+}
+
+Return ONLY the JSON object, no additional text.`, PlainText(chunk.Text, true), 2, 5)
+
+	reply, err := ai.completeText(
+		"You are an expert study assistant that writes accurate, concise study notes. Return ONLY valid JSON without any additional text or formatting.",
+		prompt,
+		800,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var section struct {
+		Title  string   `json:"title"`
+		Points []string `json:"points"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(reply)), &section); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if strings.TrimSpace(section.Title) == "" || len(section.Points) == 0 {
+		return nil, fmt.Errorf("summary has no title or points")
+	}
+
+	return &models.OutlineSection{
+		Title:  strings.TrimSpace(section.Title),
+		Points: trimNonEmpty(section.Points),
+		Pages:  chunk.Pages,
+	}, nil
+}
+
+// mergeChunkSummaries asks the provider to organise the chunk summaries into a
+// hierarchical outline and pick the key points of the whole document
+func (ai *AIService) mergeChunkSummaries(sections []models.OutlineSection) (*models.StudyNotes, error) {
+	var summaries strings.Builder
+	for i, section := range sections {
+		fmt.Fprintf(&summaries, "Section %d: %s\n", i+1, section.Title)
+		for _, point := range section.Points {
+			summaries.WriteString("- " + point + "\n")
+		}
+	}
+
+	prompt := fmt.Sprintf(`The following are summaries of consecutive sections of a study document.
+
+%s
+Requirements:
+- Organise them into an outline of at most %d top-level topics, each with subtopics
+- List each topic's source section numbers in "sections"
+- "keyPoints" are the %d most important takeaways of the whole document
+- Write in the same language as the summaries
+- Return the response as a JSON object
+
+JSON Format:
+{
+  "outline": [
+    {
+      "title": "Topic",
+      "points": ["Note about the topic"],
+      "sections": [1, 2],
+      "children": [{"title": "Subtopic", "points": ["Note"], "sections": [2]}]
+    }
+  ],
+  "keyPoints": ["Most important takeaway"]
+}
+
+Return ONLY the JSON object, no additional text.`, summaries.String(), notesMaxSections, notesKeyPoints)
+
+	reply, err := ai.completeText(
+		"You are an expert study assistant that organises study notes. Return ONLY valid JSON without any additional text or formatting.",
+		prompt,
+		2000,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged struct {
+		Outline   []mergedOutlineSection `json:"outline"`
+		KeyPoints []string               `json:"keyPoints"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(reply)), &merged); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+	}
+	if len(merged.Outline) == 0 {
+		return nil, fmt.Errorf("merged outline is empty")
+	}
+
+	notes := &models.StudyNotes{KeyPoints: trimNonEmpty(merged.KeyPoints)}
+	for _, section := range merged.Outline {
+		notes.Outline = append(notes.Outline, section.toOutline(sections))
+	}
+	return notes, nil
+}
+
+// mergedOutlineSection is an outline section as returned by the reduce step, referring
+// to chunk summaries by number
+type mergedOutlineSection struct {
+	Title    string                 `json:"title"`
+	Points   []string               `json:"points"`
+	Sections []int                  `json:"sections"`
+	Children []mergedOutlineSection `json:"children"`
+}
+
+// toOutline resolves the section numbers of a merged section to the pages they cover
+func (m mergedOutlineSection) toOutline(chunks []models.OutlineSection) models.OutlineSection {
+	section := models.OutlineSection{
+		Title:  strings.TrimSpace(m.Title),
+		Points: trimNonEmpty(m.Points),
+	}
+
+	seen := make(map[int]bool)
+	for _, number := range m.Sections {
+		if number < 1 || number > len(chunks) {
+			continue
+		}
+		for _, page := range chunks[number-1].Pages {
+			if !seen[page] {
+				seen[page] = true
+				section.Pages = append(section.Pages, page)
+			}
+		}
+	}
+	sort.Ints(section.Pages)
+
+	for _, child := range m.Children {
+		section.Children = append(section.Children, child.toOutline(chunks))
+	}
+	return section
+}
+
+// generateExtractiveNotes builds notes from the document's own sentences, ranked by the
+// TF-IDF weight of the keywords they contain
+func (ai *AIService) generateExtractiveNotes(pages []models.DocumentPage) *models.StudyNotes {
+	notes := &models.StudyNotes{
+		Method:      "extractive",
+		GeneratedAt: time.Now(),
+	}
+
+	content := JoinPages(pages)
+	keywords := ExtractKeywords(content, KeywordOptions{}, nil, 50)
+	if len(keywords) == 0 {
+		return notes
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(keywords))
+	for _, kw := range keywords {
+		pattern, err := wholeWordPattern(kw.Term)
+		if err == nil {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	type rankedSentence struct {
+		text     string
+		score    float64
+		position int
+	}
+	scoreSentence := func(sentence string) float64 {
+		score := 0.0
+		for i, pattern := range patterns {
+			if pattern.MatchString(sentence) {
+				score += keywords[i].Score
+			}
+		}
+		// Favour dense sentences over long ones that mention everything
+		return score / math.Sqrt(float64(len(strings.Fields(sentence))))
+	}
+
+	var all []rankedSentence
+	position := 0
+	var sections []models.OutlineSection
+
+	for _, chunk := range chunkPagesForExtractiveNotes(pages) {
+		var ranked []rankedSentence
+		for _, sentence := range ai.extractSentences(chunk.Text) {
+			if sentence = cleanNoteSentence(sentence); sentence == "" {
+				continue
+			}
+			ranked = append(ranked, rankedSentence{text: sentence, score: scoreSentence(sentence), position: position})
+			position++
+		}
+		if len(ranked) == 0 {
+			continue
+		}
+		all = append(all, ranked...)
+
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+		if len(ranked) > notesPointsPerSection {
+			ranked = ranked[:notesPointsPerSection]
+		}
+		sort.Slice(ranked, func(i, j int) bool { return ranked[i].position < ranked[j].position })
+
+		section := models.OutlineSection{
+			Title: sectionTitle(chunk.Text, keywords, sections),
+			Pages: chunk.Pages,
+		}
+		for _, r := range ranked {
+			section.Points = append(section.Points, r.text)
+		}
+		sections = append(sections, section)
+	}
+
+	notes.Outline = groupOutlineSections(sections, keywords)
+
+	// Key points are the best sentences of the whole document, in reading order
+	sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+	var best []rankedSentence
+	seen := make(map[string]bool)
+	for _, r := range all {
+		if len(best) >= notesKeyPoints {
+			break
+		}
+		if !seen[r.text] {
+			seen[r.text] = true
+			best = append(best, r)
+		}
+	}
+	sort.Slice(best, func(i, j int) bool { return best[i].position < best[j].position })
+	for _, r := range best {
+		notes.KeyPoints = append(notes.KeyPoints, r.text)
+	}
+
+	return notes
+}
+
+// sectionTitle names a section after the document keyword that is most prominent in it
+// and not already used by an earlier section
+func sectionTitle(text string, keywords []RankedKeyword, earlier []models.OutlineSection) string {
+	var used []string
+	for _, section := range earlier {
+		used = append(used, section.Title)
+	}
+
+	best, bestScore := "", 0.0
+	for _, kw := range keywords {
+		if overlapsTerm(kw.Term, used) {
+			continue
+		}
+		pattern, err := wholeWordPattern(kw.Term)
+		if err != nil {
+			continue
+		}
+		if count := len(pattern.FindAllStringIndex(text, -1)); count > 0 {
+			if score := float64(count) * kw.Score; score > bestScore {
+				best, bestScore = kw.Term, score
+			}
+		}
+	}
+
+	if best == "" {
+		return "Overview"
+	}
+	return capitalizeFirst(best)
+}
+
+// groupOutlineSections nests consecutive sections under shared headings when there are
+// too many of them for a flat outline
+func groupOutlineSections(sections []models.OutlineSection, keywords []RankedKeyword) []models.OutlineSection {
+	if len(sections) <= notesMaxSections {
+		return sections
+	}
+
+	size := int(math.Ceil(float64(len(sections)) / float64(notesMaxSections)))
+	var groups []models.OutlineSection
+	for start := 0; start < len(sections); start += size {
+		end := start + size
+		if end > len(sections) {
+			end = len(sections)
+		}
+		children := sections[start:end]
+
+		var text strings.Builder
+		group := models.OutlineSection{Children: children}
+		seen := make(map[int]bool)
+		for _, child := range children {
+			text.WriteString(strings.Join(child.Points, " ") + " ")
+			for _, page := range child.Pages {
+				if !seen[page] {
+					seen[page] = true
+					group.Pages = append(group.Pages, page)
+				}
+			}
+		}
+		group.Title = sectionTitle(text.String(), keywords, groups)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// chunkPagesForNotes groups consecutive pages into chunks of about notesChunkSize
+// characters, growing the chunks so there are at most notesMaxChunks
+func chunkPagesForNotes(pages []models.DocumentPage) []notesChunk {
+	total := 0
+	for _, page := range pages {
+		total += len(page.Text)
+	}
+
+	size := notesChunkSize
+	if total/size >= notesMaxChunks {
+		size = total/notesMaxChunks + 1
+	}
+	return chunkPages(pages, size)
+}
+
+// chunkPagesForExtractiveNotes splits the document into roughly equal sections, one per
+// page for short documents
+func chunkPagesForExtractiveNotes(pages []models.DocumentPage) []notesChunk {
+	total := 0
+	for _, page := range pages {
+		total += len(page.Text)
+	}
+
+	size := total / (notesMaxSections * 3)
+	if size < 1500 {
+		size = 1500
+	}
+	return chunkPages(pages, size)
+}
+
+// chunkPages groups consecutive pages into chunks of at most size characters. Pages
+// longer than size are split between their blocks, or at sentence boundaries when a
+// block is too long.
+func chunkPages(pages []models.DocumentPage, size int) []notesChunk {
+	var chunks []notesChunk
+	var current notesChunk
+
+	flush := func() {
+		if strings.TrimSpace(current.Text) != "" {
+			chunks = append(chunks, current)
+		}
+		current = notesChunk{}
+	}
+
+	for _, page := range pages {
+		text := strings.TrimSpace(page.Text)
+		if text == "" {
+			continue
+		}
+
+		if len(current.Text)+len(text) > size {
+			flush()
+		}
+
+		for len(text) > size {
+			cut := strings.LastIndex(text[:size], "\n\n")
+			if cut < size/2 {
+				cut = strings.LastIndex(text[:size], ". ") + 1
+			}
+			if cut <= 0 {
+				cut = size
+			}
+			chunks = append(chunks, notesChunk{Pages: []int{page.Number}, Text: strings.TrimSpace(text[:cut])})
+			text = strings.TrimSpace(text[cut:])
+		}
+
+		if current.Text != "" {
+			current.Text += "\n\n"
+		}
+		current.Text += text
+		current.Pages = append(current.Pages, page.Number)
+	}
+	flush()
+
+	return chunks
+}
+
+// cleanNoteSentence joins a sentence onto one line and drops a trailing list number that
+// the sentence splitter left attached to it
+func cleanNoteSentence(sentence string) string {
+	sentence = strings.Join(strings.Fields(sentence), " ")
+	sentence = trailingListMarker.ReplaceAllString(sentence, "$1")
+	if sentence != "" && !strings.ContainsAny(sentence[len(sentence)-1:], ".:!?") {
+		sentence += "."
+	}
+	if len(strings.Fields(sentence)) < 4 {
+		return ""
+	}
+	return sentence
+}
+
+func trimNonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// transcriptPause is the silence between cues that starts a new paragraph
+	transcriptPause = 3 * time.Second
+	// transcriptParagraph is how long a paragraph runs before it ends at the next
+	// sentence end
+	transcriptParagraph = time.Minute
+	// transcriptMaxParagraph ends paragraphs of captions without punctuation
+	transcriptMaxParagraph = 2 * time.Minute
+)
+
+var (
+	cueTimingPattern = regexp.MustCompile(`^\s*((?:\d+:)?\d{1,2}:\d{2}(?:[,.]\d{1,3})?)\s*-->\s*((?:\d+:)?\d{1,2}:\d{2}(?:[,.]\d{1,3})?)`)
+	cueTagPattern    = regexp.MustCompile(`<[^>]*>|\{\\[^}]*\}`)
+	cueSoundPattern  = regexp.MustCompile(`^(\[[^\]]*\]|\([^)]*\)|♪[^♪]*♪?)$`)
+	cueSpeakerMarker = regexp.MustCompile(`^(>>|-)\s*`)
+)
+
+// subtitleCue is one caption with the time it is shown
+type subtitleCue struct {
+	start, end time.Duration
+	lines      []string
+}
+
+// IsTranscript reports whether a file is a caption file, whose pages are stretches of a
+// recording labelled with the time they start
+func IsTranscript(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".srt", ".vtt":
+		return true
+	}
+	return false
+}
+
+// processSubtitleFile reads the captions of an SRT or WebVTT file into paragraphs. Every
+// paragraph is a page labelled with the time it starts, e.g. "14:32", so citations point
+// into the recording.
+func (fs *FileService) processSubtitleFile(content []byte) ([]models.DocumentPage, error) {
+	text, err := fs.processTXTFile(content)
+	if err != nil {
+		return nil, err
+	}
+
+	cues := parseSubtitleCues(text)
+	if len(cues) == 0 {
+		return nil, fmt.Errorf("no captions found in subtitle file")
+	}
+
+	pages := transcriptPages(cues)
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no text content found in subtitle file")
+	}
+	return pages, nil
+}
+
+// parseSubtitleCues reads the cues of SRT and WebVTT files, which both separate cues by
+// blank lines and give each a "start --> end" line. Cue numbers and identifiers before
+// that line, and WebVTT headers, notes and styles, have no timing line and are skipped.
+func parseSubtitleCues(text string) []subtitleCue {
+	text = strings.TrimPrefix(text, "\ufeff")
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	var cues []subtitleCue
+	var block []string
+	endBlock := func() {
+		for i, line := range block {
+			match := cueTimingPattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			start, err1 := parseCueTime(match[1])
+			end, err2 := parseCueTime(match[2])
+			if err1 == nil && err2 == nil {
+				cues = append(cues, subtitleCue{start: start, end: end, lines: block[i+1:]})
+			}
+			break
+		}
+		block = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			endBlock()
+			continue
+		}
+		block = append(block, line)
+	}
+	endBlock()
+	return cues
+}
+
+// parseCueTime reads a cue timestamp: "01:02:03,500" in SRT, "01:02:03.500" or
+// "02:03.500" in WebVTT. Files written by hand often shorten or leave out the
+// fraction, as in "01:02:03,5" or "01:02:03".
+func parseCueTime(value string) (time.Duration, error) {
+	clock, fraction, _ := strings.Cut(strings.ReplaceAll(value, ",", "."), ".")
+	parts := strings.Split(clock, ":")
+
+	var total time.Duration
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, err
+		}
+		total = total*60 + time.Duration(n)
+	}
+	total *= time.Second
+
+	millis, err := strconv.Atoi((fraction + "000")[:3])
+	if err != nil {
+		return 0, err
+	}
+	return total + time.Duration(millis)*time.Millisecond, nil
+}
+
+// transcriptPages merges cues into paragraphs. A paragraph ends at a pause, or once it is
+// long enough at the end of a sentence. Captions that scroll repeat the previous line
+// at the top of every cue; the repeats are dropped.
+func transcriptPages(cues []subtitleCue) []models.DocumentPage {
+	var pages []models.DocumentPage
+	var paragraph []string
+	var start, end time.Duration
+	lastLine := ""
+
+	endParagraph := func() {
+		if len(paragraph) > 0 {
+			pages = append(pages, models.DocumentPage{
+				Number: len(pages) + 1,
+				Label:  formatTimestamp(start),
+				Text:   strings.Join(paragraph, " "),
+			})
+		}
+		paragraph = nil
+	}
+
+	for _, cue := range cues {
+		var lines []string
+		for _, line := range cue.lines {
+			line = cleanCueLine(line)
+			if line == "" || line == lastLine {
+				continue
+			}
+			lines = append(lines, line)
+			lastLine = line
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		if len(paragraph) > 0 {
+			length := cue.start - start
+			last := paragraph[len(paragraph)-1]
+			sentenceEnd := strings.ContainsAny(last[len(last)-1:], ".!?")
+			if cue.start-end >= transcriptPause || length >= transcriptMaxParagraph ||
+				(length >= transcriptParagraph && sentenceEnd) {
+				endParagraph()
+			}
+		}
+		if len(paragraph) == 0 {
+			start = cue.start
+		}
+		paragraph = append(paragraph, lines...)
+		end = cue.end
+	}
+	endParagraph()
+
+	return pages
+}
+
+// cleanCueLine removes styling, voice tags, speaker markers and sound descriptions such
+// as "[Music]" from a caption line
+func cleanCueLine(line string) string {
+	line = html.UnescapeString(cueTagPattern.ReplaceAllString(line, ""))
+	line = strings.Join(strings.Fields(line), " ")
+	line = cueSpeakerMarker.ReplaceAllString(line, "")
+	if cueSoundPattern.MatchString(line) {
+		return ""
+	}
+	return line
+}
+
+// formatTimestamp writes a position in a recording as "14:32", or "1:02:03" past the
+// first hour
+func formatTimestamp(d time.Duration) string {
+	seconds := int(d / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// CiteTimestamps points every question of a quiz from a recording at the moment of the
+// recording it is about, by adding "See 14:32 in the lecture." to its explanation
+func CiteTimestamps(quiz *models.Quiz, pages []models.DocumentPage) {
+	index := NewDocumentIndex(pages)
+	for i := range quiz.Questions {
+		q := &quiz.Questions[i]
+		stem := q.Text
+		if stem == "" {
+			stem = q.Question
+		}
+		key, _ := questionKey(*q)
+
+		passages := index.searchBM25(stem+" "+key, 1)
+		if len(passages) == 0 || passages[0].Label == "" {
+			continue
+		}
+		see := fmt.Sprintf("See %s in the lecture.", passages[0].Label)
+		if explanation := strings.TrimSpace(q.Explanation); explanation != "" {
+			see = explanation + " " + see
+		}
+		q.Explanation = see
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCueTime(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"01:02:03,500", time.Hour + 2*time.Minute + 3500*time.Millisecond},
+		{"01:02:03.500", time.Hour + 2*time.Minute + 3500*time.Millisecond},
+		{"02:03.250", 2*time.Minute + 3250*time.Millisecond},
+		{"00:00:01,5", 1500 * time.Millisecond},
+		{"00:00:01,05", 1050 * time.Millisecond},
+		{"00:00:01", time.Second},
+		{"2:03", 2*time.Minute + 3*time.Second},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCueTime(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("parseCueTime(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestProcessSubtitleFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []models.DocumentPage // nil when the file has no captions
+	}{
+		{name: "empty file"},
+		{name: "no timing lines", content: "1\nHello there.\n\n2\nGeneral Kenobi.\n"},
+		{name: "malformed timing", content: "1\n00:00:01,000 -> 00:00:02,000\nHello.\n"},
+		{name: "only sounds", content: "1\n00:00:01,000 --> 00:00:02,000\n[Music]\n\n2\n00:00:03,000 --> 00:00:04,000\n♪ la la ♪\n"},
+		{
+			name: "SRT with short fractions and a pause",
+			content: "\ufeff1\r\n00:00:01,5 --> 00:00:03\r\n<i>Cells</i> divide\r\n\r\n" +
+				"2\r\n00:00:03,2 --> 00:00:05,000\r\n>> by mitosis.\r\n\r\n" +
+				"3\r\n00:00:09,000 --> 00:00:11,000\r\nNext, osmosis &amp; diffusion.\r\n",
+			want: []models.DocumentPage{
+				{Number: 1, Label: "0:01", Text: "Cells divide by mitosis."},
+				{Number: 2, Label: "0:09", Text: "Next, osmosis & diffusion."},
+			},
+		},
+		{
+			name: "WebVTT with header, notes and scrolling captions",
+			content: "WEBVTT - Lecture 3\n\nNOTE recorded live\n\nSTYLE\n::cue { color: red }\n\n" +
+				"intro\n01:02:03.000 --> 01:02:05.000 align:start\n<v Anna>Plants make sugar\n\n" +
+				"01:02:05.000 --> 01:02:07.000\n<v Anna>Plants make sugar\nfrom light.\n",
+			want: []models.DocumentPage{
+				{Number: 1, Label: "1:02:03", Text: "Plants make sugar from light."},
+			},
+		},
+	}
+
+	fs := NewFileService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, err := fs.processSubtitleFile([]byte(tt.content))
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("no error, pages %+v", pages)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processSubtitleFile: %v", err)
+			}
+			if !reflect.DeepEqual(pages, tt.want) {
+				t.Errorf("pages = %+v\nwant %+v", pages, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"strings"
+)
+
+// tableCaptionPattern matches the caption of a table, e.g. "Table 2: Sample means" or
+// "Tabel 3.1 Distribusi frekuensi"
+var tableCaptionPattern = regexp.MustCompile(`^(?i)(table|tabel)\s+[A-Z]?\d+(\.\d+)*\b`)
+
+// tableRows splits the "|" lines of a table block into cells
+func tableRows(lines []string) [][]string {
+	rows := make([][]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+		var cells []string
+		for _, cell := range strings.Split(line, "|") {
+			cells = append(cells, strings.TrimSpace(cell))
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// captionTables gives each table the caption paragraph set directly above it, or failing
+// that directly below it
+func captionTables(blocks []models.DocumentBlock) {
+	isCaption := func(i int) bool {
+		return i >= 0 && i < len(blocks) && blocks[i].Kind != "table" && tableCaptionPattern.MatchString(blocks[i].Text)
+	}
+	for i := range blocks {
+		if blocks[i].Kind != "table" {
+			continue
+		}
+		switch {
+		case isCaption(i - 1):
+			blocks[i].Caption = blocks[i-1].Text
+		case isCaption(i + 1):
+			blocks[i].Caption = blocks[i+1].Text
+		}
+	}
+}
+
+// DocumentTables lists the tables of a document with their captions, in document order
+func DocumentTables(pages []models.DocumentPage) []models.DocumentTable {
+	var tables []models.DocumentTable
+	for _, page := range pages {
+		for _, block := range PageBlocks(page) {
+			if block.Kind == "table" && len(block.Rows) > 0 {
+				tables = append(tables, models.DocumentTable{Page: page.Number, Caption: block.Caption, Rows: block.Rows})
+			}
+		}
+	}
+	return tables
+}
+
+// tableLabel returns how a question refers to a table: by the number in its caption, or
+// by its columns when it has no caption
+func tableLabel(table models.DocumentTable) string {
+	if match := tableCaptionPattern.FindString(table.Caption); match != "" {
+		return match
+	}
+	return "the table of " + joinWithAnd(table.Rows[0])
+}
+
+// joinWithAnd lists words as "a, b and c"
+func joinWithAnd(words []string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " and " + words[len(words)-1]
+}
+
+// tableQuestions asks for the values of the tables in content, e.g. "According to Table
+// 2, what is the Mean for Group B?", with the other values of the same column as
+// distractors. Tables need a header row and at least four rows below it, each named by
+// its first cell.
+func tableQuestions(content string, limit int) []models.Question {
+	var questions []models.Question
+	for _, table := range DocumentTables([]models.DocumentPage{{Number: 1, Text: content}}) {
+		header, body := table.Rows[0], table.Rows[1:]
+		if len(header) < 2 || len(body) < 4 {
+			continue
+		}
+
+		// Rows must be told apart by their first cell
+		labels := make(map[string]bool)
+		for _, row := range body {
+			labels[row[0]] = true
+		}
+		if len(labels) < len(body) {
+			continue
+		}
+
+		for r, row := range body {
+			if len(questions) >= limit {
+				return questions
+			}
+			// Take the columns in turn so the questions cover the whole table
+			for k := 0; k < len(header)-1; k++ {
+				c := 1 + (r+k)%(len(header)-1)
+				if question, ok := tableQuestion(table, c, row); ok {
+					questions = append(questions, question)
+					break
+				}
+			}
+		}
+	}
+	return questions
+}
+
+// tableQuestion asks for the cell of a row in column c
+func tableQuestion(table models.DocumentTable, c int, row []string) (models.Question, bool) {
+	header := table.Rows[0]
+	if c >= len(row) || row[0] == "" || row[c] == "" || header[c] == "" {
+		return models.Question{}, false
+	}
+	answer := row[c]
+
+	seen := map[string]bool{answer: true}
+	var wrong []string
+	for _, other := range table.Rows[1:] {
+		if c < len(other) && other[c] != "" && !seen[other[c]] {
+			seen[other[c]] = true
+			wrong = append(wrong, other[c])
+		}
+	}
+	if len(wrong) < 3 {
+		return models.Question{}, false
+	}
+	rand.Shuffle(len(wrong), func(i, j int) { wrong[i], wrong[j] = wrong[j], wrong[i] })
+
+	options := append([]string{answer}, wrong[:3]...)
+	rand.Shuffle(len(options), func(i, j int) { options[i], options[j] = options[j], options[i] })
+	correctAnswer := 0
+	for i, option := range options {
+		if option == answer {
+			correctAnswer = i
+		}
+	}
+
+	metadata := map[string]interface{}{"source": "rule-based-table"}
+	if table.Caption != "" {
+		metadata["table"] = table.Caption
+	}
+
+	label := tableLabel(table)
+	return models.Question{
+		Type:          "multiple-choice",
+		Text:          fmt.Sprintf("According to %s, what is the %s for %s?", label, header[c], row[0]),
+		Options:       options,
+		Correct:       answer,
+		CorrectAnswer: correctAnswer,
+		Points:        1,
+		Explanation:   fmt.Sprintf("%s lists %s as the %s for %s.", capitalizeFirst(label), answer, header[c], row[0]),
+		BloomLevel:    "remember",
+		Metadata:      metadata,
+	}, true
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTableQuestions(t *testing.T) {
+	caption := "Table 2: Sample means by group\n\n"
+	tests := []struct {
+		name  string
+		table string
+		want  int // questions asked
+	}{
+		{name: "no table", table: "Cells divide by mitosis.", want: 0},
+		{name: "too few rows", table: "| Group | Mean |\n| A | 4.1 |\n| B | 5.3 |\n| C | 6.0 |", want: 0},
+		{name: "rows not told apart", table: "| Group | Mean |\n| A | 4.1 |\n| A | 5.3 |\n| C | 6.0 |\n| D | 7.2 |", want: 0},
+		{
+			name:  "fewer than four distinct values in the column",
+			table: "| Group | Sex |\n| A | F |\n| B | M |\n| C | F |\n| D | M |\n| E | F |",
+			want:  0,
+		},
+		{
+			name:  "one column with too few values",
+			table: "| Group | Sex | Mean |\n| A | F | 4.1 |\n| B | M | 5.3 |\n| C | F | 6.0 |\n| D | M | 7.2 |",
+			want:  4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			questions := tableQuestions(caption+tt.table, 10)
+			if len(questions) != tt.want {
+				t.Fatalf("%d questions, want %d", len(questions), tt.want)
+			}
+			for _, q := range questions {
+				if len(q.Options) != 4 || q.Options[q.CorrectAnswer] != q.Correct {
+					t.Errorf("%q: options %q with key %d, want 4 options with %q as the key", q.Text, q.Options, q.CorrectAnswer, q.Correct)
+				}
+				if !strings.HasPrefix(q.Text, "According to Table 2, what is the Mean for ") {
+					t.Errorf("question %q does not ask for the mean", q.Text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,535 @@
+package services
+
+import (
+	"embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Text extracted from PDFs carries the artifacts of how it was drawn: ligature glyphs,
+// words hyphenated at line ends, and words drawn without a space between them. The
+// normaliser repairs them with a pipeline of steps that can each be switched off, per
+// document or per language, as a step that helps one document can harm another.
+
+// Normalisation steps, in the order they run
+const (
+	NormalizeArtifacts   = "artifacts"   // drop replacement characters, soft hyphens and odd spaces
+	NormalizeLigatures   = "ligatures"   // spell out ligature glyphs such as "ﬁ"
+	NormalizeMath        = "math"        // unify operator glyphs and leave formulas as they are
+	NormalizeDehyphenate = "dehyphenate" // join words hyphenated across line breaks
+	NormalizeSpacing     = "spacing"     // add missing spaces after punctuation and brackets
+	NormalizeSplit       = "split"       // split words glued together, using a dictionary
+)
+
+// NormalizeSteps are all steps in pipeline order. They all run by default.
+var NormalizeSteps = []string{NormalizeArtifacts, NormalizeLigatures, NormalizeMath, NormalizeDehyphenate, NormalizeSpacing, NormalizeSplit}
+
+// ParseNormalizeSteps reads the steps to run from a spec: "default" or "" for all steps,
+// "none", a list such as "ligatures,dehyphenate", or the defaults without some steps,
+// such as "-split".
+func ParseNormalizeSteps(spec string) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+	switch spec {
+	case "", "default", "all":
+		return NormalizeSteps, nil
+	case "none":
+		return []string{}, nil
+	}
+
+	chosen := make(map[string]bool)
+	removing := strings.HasPrefix(spec, "-")
+	if removing {
+		for _, step := range NormalizeSteps {
+			chosen[step] = true
+		}
+	}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, "-") != removing {
+			return nil, fmt.Errorf("invalid normalisation steps %q: list steps to run or steps to skip, not both", spec)
+		}
+		name = strings.TrimPrefix(name, "-")
+		if !isNormalizeStep(name) {
+			return nil, fmt.Errorf("unknown normalisation step %q, expected one of %s", name, strings.Join(NormalizeSteps, ", "))
+		}
+		chosen[name] = !removing
+	}
+
+	steps := []string{}
+	for _, step := range NormalizeSteps {
+		if chosen[step] {
+			steps = append(steps, step)
+		}
+	}
+	return steps, nil
+}
+
+func isNormalizeStep(name string) bool {
+	for _, step := range NormalizeSteps {
+		if step == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeOptions choose how the text of a document is normalised
+type NormalizeOptions struct {
+	Language   string              // "en", "id" or "" to detect from the document
+	Steps      []string            // steps for every language; nil leaves the choice to ByLanguage
+	ByLanguage map[string][]string // steps for documents in a language
+}
+
+// ParseNormalizeOptions reads per-language steps such as
+// "default; id=ligatures,dehyphenate,split". An entry without a language sets the steps
+// for every language without an entry of its own.
+func ParseNormalizeOptions(spec string) (NormalizeOptions, error) {
+	opts := NormalizeOptions{ByLanguage: make(map[string][]string)}
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		language, stepSpec, ok := strings.Cut(entry, "=")
+		if !ok {
+			language, stepSpec = "", entry
+		}
+		steps, err := ParseNormalizeSteps(stepSpec)
+		if err != nil {
+			return opts, err
+		}
+		if language = strings.ToLower(strings.TrimSpace(language)); language == "" {
+			opts.ByLanguage[""] = steps
+		} else {
+			opts.ByLanguage[language] = steps
+		}
+	}
+	return opts, nil
+}
+
+// Merge returns the options with those chosen for a document taking precedence
+func (o NormalizeOptions) Merge(document NormalizeOptions) NormalizeOptions {
+	merged := NormalizeOptions{Language: o.Language, Steps: o.Steps, ByLanguage: make(map[string][]string)}
+	for language, steps := range o.ByLanguage {
+		merged.ByLanguage[language] = steps
+	}
+	for language, steps := range document.ByLanguage {
+		merged.ByLanguage[language] = steps
+	}
+	if document.Language != "" {
+		merged.Language = document.Language
+	}
+	if document.Steps != nil {
+		merged.Steps = document.Steps
+	}
+	return merged
+}
+
+// Normalizer returns the normaliser for a document, detecting its language from a sample
+// of its text unless the options fix it
+func (o NormalizeOptions) Normalizer(sample string) *TextNormalizer {
+	language := o.Language
+	if language == "" {
+		language = DetectLanguage(sample)
+	}
+
+	steps := o.Steps
+	if steps == nil {
+		steps = o.ByLanguage[language]
+	}
+	if steps == nil {
+		steps = o.ByLanguage[""]
+	}
+	if steps == nil {
+		steps = NormalizeSteps
+	}
+	return NewTextNormalizer(language, steps)
+}
+
+// TextNormalizer cleans up paragraphs of extracted text
+type TextNormalizer struct {
+	language string
+	steps    map[string]bool
+	words    *wordList
+}
+
+// NewTextNormalizer creates a normaliser running the given steps. Dehyphenation and
+// splitting use the word list of the language when there is one.
+func NewTextNormalizer(language string, steps []string) *TextNormalizer {
+	n := &TextNormalizer{language: language, steps: make(map[string]bool), words: loadWordList(language)}
+	for _, step := range steps {
+		n.steps[step] = true
+	}
+	return n
+}
+
+// Language returns the language the normaliser splits and joins words for
+func (n *TextNormalizer) Language() string {
+	return n.language
+}
+
+var (
+	// pdfArtifacts are characters extraction leaves in text that are not part of it
+	pdfArtifacts = strings.NewReplacer(
+		"\u00ad\n", "", "\u00ad", "", // soft hyphens
+		"□", " ", "\ufffd", "", // glyphs without a character
+		"\u00a0", " ", "\u2009", " ", "\u202f", " ", // odd spaces
+		"\u200b", "", "\ufeff", "", // zero-width spaces
+	)
+	// mathGlyphs are operators fonts draw with characters of their own
+	mathGlyphs = strings.NewReplacer("−", "-", "∗", "*", "⁄", "/", "∕", "/", "＝", "=")
+
+	// hyphenatedPattern matches a word broken across a line end
+	hyphenatedPattern = regexp.MustCompile(`(\p{L}+)[-\x{2010}][ \t]*\n\s*(\p{L}+)`)
+)
+
+// Normalize runs the pipeline over a paragraph. Line breaks in the paragraph are kept
+// until words hyphenated across them are joined; the result is on one line.
+func (n *TextNormalizer) Normalize(text string) string {
+	if n.steps[NormalizeArtifacts] {
+		text = pdfArtifacts.Replace(text)
+	}
+	if n.steps[NormalizeLigatures] {
+		text = pdfLigatures.Replace(text)
+	}
+	if n.steps[NormalizeMath] {
+		text = mathGlyphs.Replace(text)
+	}
+	if n.steps[NormalizeDehyphenate] {
+		text = n.dehyphenate(text)
+	}
+
+	fields := strings.Fields(text)
+	if n.steps[NormalizeSpacing] {
+		fields = n.addSpacing(fields)
+	}
+	if n.steps[NormalizeSplit] && n.words != nil {
+		for i, field := range fields {
+			fields[i] = n.splitGlued(field)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// dehyphenate joins words broken across line ends. The hyphen stays when both halves
+// are words but their join is not, as in "self-test" or "anak-anak".
+func (n *TextNormalizer) dehyphenate(text string) string {
+	return hyphenatedPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := hyphenatedPattern.FindStringSubmatch(match)
+		left, right := parts[1], parts[2]
+		joined := left + right
+
+		switch {
+		case strings.EqualFold(left, right) || unicode.IsUpper([]rune(right)[0]):
+			// Repeated words and compounds of names, as in "Indo-European"
+			return left + "-" + right
+		case n.words == nil:
+			return joined
+		case n.words.known(strings.ToLower(joined)):
+			return joined
+		case n.words.known(strings.ToLower(left)) && n.words.known(strings.ToLower(right)):
+			return left + "-" + right
+		default:
+			return joined
+		}
+	})
+}
+
+// addSpacing adds the spaces missing after punctuation and around brackets in running
+// text, such as in "end.Next", "word,word" and "(see above)The". With math handling,
+// formulas and identifiers are left as they are.
+func (n *TextNormalizer) addSpacing(fields []string) []string {
+	var spaced []string
+	for _, field := range fields {
+		if n.steps[NormalizeMath] && isFormula(field) || strings.Contains(field, "://") || strings.Contains(field, "@") {
+			spaced = append(spaced, field)
+			continue
+		}
+
+		r := []rune(field)
+		var out strings.Builder
+		open := 0 // brackets opened in this field
+		for i, c := range r {
+			out.WriteRune(c)
+			if i+1 >= len(r) {
+				break
+			}
+			next := r[i+1]
+			switch {
+			case c == '(':
+				open++
+			case c == ')' && open > 0:
+				open--
+			}
+
+			switch {
+			case strings.ContainsRune(",;:", c) && i > 0 && unicode.IsLetter(r[i-1]) && unicode.IsLetter(next):
+				out.WriteRune(' ')
+			case c == '.' && i > 1 && unicode.IsLower(r[i-1]) && unicode.IsLower(r[i-2]) &&
+				unicode.IsUpper(next) && i+2 < len(r) && unicode.IsLower(r[i+2]):
+				out.WriteRune(' ')
+			case c == ')' && unicode.IsLetter(next) && open == 0 && strings.IndexRune(field, '(') <= 0:
+				// The bracket closes an aside opened before this field, or at its start
+				out.WriteRune(' ')
+			case unicode.IsLetter(c) && next == '[' && letterRun(r, i) >= 2 && i+3 < len(r) && unicode.IsLetter(r[i+2]) && unicode.IsLetter(r[i+3]):
+				// A citation, as in "database[SharedMIME]"
+				out.WriteRune(' ')
+			case unicode.IsLetter(c) && next == '(' && letterRun(r, i) >= 2 && !strings.ContainsRune(string(r[i+1:]), ')') &&
+				i+4 < len(r) && unicode.IsLetter(r[i+2]) && unicode.IsLetter(r[i+3]) && unicode.IsLetter(r[i+4]):
+				// The bracket opens an aside running on past this field
+				out.WriteRune(' ')
+			}
+		}
+		spaced = append(spaced, out.String())
+	}
+	return spaced
+}
+
+// letterRun counts the letters ending at r[i]
+func letterRun(r []rune, i int) int {
+	n := 0
+	for ; i >= 0 && unicode.IsLetter(r[i]); i-- {
+		n++
+	}
+	return n
+}
+
+// isFormula reports whether a field is part of a formula or an identifier, which spacing
+// and splitting must not break up: it has operators, a function application such as
+// "f(x)", or letters and digits mixed as in "x2", "H2O" or "COVID19".
+func isFormula(field string) bool {
+	if strings.ContainsAny(field, "=+<>^_*/\\|~≤≥≠±×÷∑∏∫√∂∞≈∈") {
+		return true
+	}
+	r := []rune(field)
+	for i := 1; i < len(r); i++ {
+		prev, c := r[i-1], r[i]
+		if c == '(' && unicode.IsLetter(prev) && strings.ContainsRune(string(r[i:]), ')') {
+			return true
+		}
+		if unicode.IsLetter(prev) && unicode.IsDigit(c) || unicode.IsDigit(prev) && unicode.IsLetter(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadingNumberPattern matches a number glued to the word after it, as in "2021Results"
+var leadingNumberPattern = regexp.MustCompile(`^(\d+)(\p{L}{4,})$`)
+
+// splitGlued splits a field made of words drawn without spaces between them. Only
+// splits into dictionary words are made, so names and terms the dictionary does not
+// know are left alone.
+func (n *TextNormalizer) splitGlued(field string) string {
+	core := strings.TrimFunc(field, func(r rune) bool { return !isWordRune(r) })
+	if core == "" {
+		return field
+	}
+	start := strings.Index(field, core)
+	prefix, suffix := field[:start], field[start+len(core):]
+
+	if m := leadingNumberPattern.FindStringSubmatch(core); m != nil {
+		if n.words.known(strings.ToLower(m[2])) {
+			return prefix + m[1] + " " + m[2] + suffix
+		}
+		return field
+	}
+	for _, r := range core {
+		if !unicode.IsLetter(r) {
+			return field
+		}
+	}
+
+	var parts []string
+	for _, piece := range n.splitCase(core) {
+		lower := strings.ToLower(piece)
+		r := []rune(piece)
+		// Capitalised words are mostly names, so short ones are left alone
+		capitalised := unicode.IsUpper(r[0]) && string(r[1:]) == strings.ToLower(string(r[1:]))
+		if piece == lower && len(r) >= 5 || capitalised && len(r) >= 8 {
+			if words := n.words.segment(lower); words != nil {
+				i := 0
+				for _, word := range words {
+					parts = append(parts, string(r[i:i+len([]rune(word))]))
+					i += len([]rune(word))
+				}
+				continue
+			}
+		}
+		parts = append(parts, piece)
+	}
+	return prefix + strings.Join(parts, " ") + suffix
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// splitCase splits a word at changes of case that join two dictionary words, as in
+// "matriksSelisih" or "PDFfile". Words such as "iPhone" and "McDonald" stay whole, as
+// one side is not a word of its own.
+func (n *TextNormalizer) splitCase(word string) []string {
+	r := []rune(word)
+	var cuts []int
+	for i := 1; i < len(r); i++ {
+		camel := unicode.IsLower(r[i-1]) && unicode.IsUpper(r[i])
+		acronym := i >= 2 && unicode.IsUpper(r[i-2]) && unicode.IsUpper(r[i-1]) && unicode.IsLower(r[i])
+		if camel || acronym {
+			cuts = append(cuts, i)
+		}
+	}
+	if len(cuts) == 0 {
+		return []string{word}
+	}
+
+	var pieces []string
+	from := 0
+	for k, cut := range cuts {
+		to := len(r)
+		if k+1 < len(cuts) {
+			to = cuts[k+1]
+		}
+		left, right := string(r[from:cut]), string(r[cut:to])
+
+		split := false
+		if unicode.IsUpper(r[cut]) {
+			split = cut-from >= 2 && n.words.known(strings.ToLower(left)) && n.words.known(strings.ToLower(right))
+		} else {
+			// An acronym followed by a lowercase word, unless its last letter starts
+			// a word as in "USBCable"
+			split = len([]rune(right)) >= 3 && n.words.known(right) && !n.words.known(strings.ToLower(string(r[cut-1:to])))
+		}
+		if split {
+			pieces = append(pieces, left)
+			from = cut
+		}
+	}
+	return append(pieces, string(r[from:]))
+}
+
+//go:embed dictionaries/*.txt
+var dictionaries embed.FS
+
+// wordList is the dictionary of a language. Inflected forms of its words are known
+// through the stemmer.
+type wordList struct {
+	words   map[string]bool
+	stems   map[string]bool
+	stop    map[string]bool
+	stemmer Stemmer
+}
+
+var (
+	wordListsOnce sync.Once
+	wordLists     map[string]*wordList
+)
+
+// loadWordList returns the dictionary of a language, or nil when there is none
+func loadWordList(language string) *wordList {
+	wordListsOnce.Do(func() {
+		wordLists = map[string]*wordList{
+			"en": readWordList("en", englishStopWords),
+			"id": readWordList("id", indonesianStopWords),
+		}
+	})
+	return wordLists[language]
+}
+
+func readWordList(language string, stop map[string]bool) *wordList {
+	data, err := dictionaries.ReadFile("dictionaries/" + language + ".txt")
+	if err != nil {
+		return nil
+	}
+
+	list := &wordList{words: make(map[string]bool), stems: make(map[string]bool), stop: stop, stemmer: stemmerFor(language)}
+	for _, line := range strings.Split(string(data), "\n") {
+		word := strings.TrimSpace(line)
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		list.words[word] = true
+		list.stems[list.stemmer.Stem(word)] = true
+	}
+	for word := range stop {
+		list.words[word] = true
+	}
+	return list
+}
+
+// known reports whether a lowercase word, or a word sharing its stem, is in the list
+func (l *wordList) known(word string) bool {
+	if l.words[word] {
+		return true
+	}
+	return len(word) >= 5 && l.stems[l.stemmer.Stem(word)]
+}
+
+// compoundPrefixes are function words that also start compounds
+var compoundPrefixes = wordSet(`after back by down for in off on out over through under up with
+di ke se`)
+
+// segment splits a lowercase word into dictionary words of two letters or more, using as
+// few as possible, or returns nil when the word is a word itself or cannot be split with
+// confidence. A split must contain a function word, as in "ofthe" or "dankemudian",
+// unless the word is long, as in "matriksselisih".
+func (l *wordList) segment(word string) []string {
+	if l.known(word) {
+		return nil
+	}
+	r := []rune(word)
+	n := len(r)
+
+	// best[i] is the split of r[:i] into the fewest words, preferring longer words
+	type split struct {
+		parts []string
+		score int
+	}
+	best := make([]*split, n+1)
+	best[0] = &split{}
+	for i := 1; i <= n; i++ {
+		for j := 0; j < i; j++ {
+			if best[j] == nil || len(best[j].parts) >= 4 {
+				continue
+			}
+			part := string(r[j:i])
+			if i-j < 2 || i-j == 2 && !l.stop[part] || i-j >= 3 && !l.known(part) {
+				continue
+			}
+			score := best[j].score + (i-j)*(i-j)
+			if c := best[i]; c == nil || len(best[j].parts)+1 < len(c.parts) ||
+				len(best[j].parts)+1 == len(c.parts) && score > c.score {
+				best[i] = &split{parts: append(append([]string{}, best[j].parts...), part), score: score}
+			}
+		}
+	}
+
+	found := best[n]
+	if found == nil || len(found.parts) < 2 {
+		return nil
+	}
+
+	// Short words glue into longer ones too, as "in", "her" and "it" into "inherit", so
+	// other words must be long and function words alone make only two words
+	stops, long := 0, 0
+	for i, part := range found.parts {
+		switch {
+		case i == 0 && compoundPrefixes[part] && !l.stop[found.parts[1]]:
+			// A prefix of a compound, as in "overwrite" or "byproduct"
+			return nil
+		case l.stop[part]:
+			stops++
+		case len([]rune(part)) >= 4:
+			long++
+		default:
+			return nil
+		}
+	}
+	switch {
+	case stops > 0 && long > 0, stops == 2 && long == 0, stops == 0 && n >= 12:
+		return found.parts
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of the normaliser cases")
+
+func TestNormalizeGolden(t *testing.T) {
+	inputs, err := filepath.Glob(filepath.Join("testdata", "normalize", "*.txt"))
+	if err != nil || len(inputs) == 0 {
+		t.Fatalf("no normalisation cases found: %v", err)
+	}
+
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		golden := strings.TrimSuffix(input, ".txt") + ".golden"
+
+		t.Run(name, func(t *testing.T) {
+			output, err := NormalizeCase(input)
+			if err != nil {
+				t.Fatalf("NormalizeCase: %v", err)
+			}
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(output), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Split(string(expected), "\n")
+			got := strings.Split(output, "\n")
+			for i := 0; i < len(want) || i < len(got); i++ {
+				var w, g string
+				if i < len(want) {
+					w = want[i]
+				}
+				if i < len(got) {
+					g = got[i]
+				}
+				if w != g {
+					t.Errorf("line %d\n- %s\n+ %s", i+1, w, g)
+				}
+			}
+		})
+	}
+}
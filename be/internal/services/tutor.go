@@ -0,0 +1,207 @@
+package services
+
+import (
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// tutorPassages is the number of passages given to the LLM as context
+	tutorPassages = 4
+	// tutorHistoryTurns is the number of earlier messages included in the prompt
+	tutorHistoryTurns = 6
+	// tutorFollowUpWords is the length below which a question is treated as a follow-up
+	// and searched for together with the previous question
+	tutorFollowUpWords = 6
+)
+
+// pageCitationPattern matches page references such as [p. 3], [p. 3, 5] or [pp. 3-4]
+var pageCitationPattern = regexp.MustCompile(`(?i)\[(?:pp?|pages?)\.?\s*([\d,\s\-–]+)\]`)
+
+// AnswerQuestion answers a student's question from the passages of a document that are
+// relevant to it, citing the pages it used. history is the earlier conversation, oldest
+// first. Without an LLM provider the most relevant sentences are returned instead.
+func (ai *AIService) AnswerQuestion(index *DocumentIndex, question string, history []models.TutorMessage, retrieval string) *models.TutorAnswer {
+	answer := &models.TutorAnswer{Question: question}
+
+	passages, method := index.Search(ai, retrievalQuery(question, history), retrieval, tutorPassages)
+	answer.Retrieval = method
+	if len(passages) == 0 {
+		answer.Answer = "I couldn't find anything about that in this document. Try rephrasing the question with terms the document uses."
+		answer.Citations = []models.TutorCitation{}
+		answer.Method = "extractive"
+		return answer
+	}
+
+	reply, err := ai.completeText(
+		"You are a patient tutor helping a student understand their study material. Answer only from the document excerpts you are given and cite the page of every fact as [p. N]. If the excerpts do not answer the question, say so instead of guessing.",
+		tutorPrompt(question, passages, history),
+		600,
+	)
+	if err != nil {
+		ai.logger.Warnf("LLM tutor answer failed: %v, answering from the passages", err)
+		answer.Answer, answer.Citations = extractiveAnswer(index, question, passages)
+		answer.Method = "extractive"
+		return answer
+	}
+
+	answer.Answer = strings.TrimSpace(reply)
+	answer.Citations = citedPassages(answer.Answer, passages)
+	answer.Method = "llm"
+	return answer
+}
+
+// retrievalQuery adds the previous question to short follow-ups like "why?" so the search
+// still finds the passages the conversation is about
+func retrievalQuery(question string, history []models.TutorMessage) string {
+	if len(strings.Fields(question)) >= tutorFollowUpWords {
+		return question
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Role == "user" {
+			return history[i].Content + " " + question
+		}
+	}
+	return question
+}
+
+func tutorPrompt(question string, passages []models.TutorCitation, history []models.TutorMessage) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("Document excerpts:\n")
+	for _, passage := range passages {
+		fmt.Fprintf(&prompt, "[p. %d] %s\n\n", passage.Page, passage.Excerpt)
+	}
+
+	if len(history) > tutorHistoryTurns {
+		history = history[len(history)-tutorHistoryTurns:]
+	}
+	if len(history) > 0 {
+		prompt.WriteString("Conversation so far:\n")
+		for _, msg := range history {
+			speaker := "Student"
+			if msg.Role == "assistant" {
+				speaker = "Tutor"
+			}
+			fmt.Fprintf(&prompt, "%s: %s\n", speaker, msg.Content)
+		}
+		prompt.WriteString("\n")
+	}
+
+	fmt.Fprintf(&prompt, `Student question: %s
+
+Requirements:
+- Answer in the same language as the question
+- Keep the answer short and clear, as a tutor would explain it
+- Cite the page of every fact as [p. N]
+
+Answer:`, question)
+	return prompt.String()
+}
+
+// citedPassages returns the passages on the pages an answer cites. When the answer cites
+// no page every retrieved passage is returned, since the answer was based on them.
+func citedPassages(answer string, passages []models.TutorCitation) []models.TutorCitation {
+	pages := make(map[int]bool)
+	for _, match := range pageCitationPattern.FindAllStringSubmatch(answer, -1) {
+		for _, page := range parsePageList(match[1]) {
+			pages[page] = true
+		}
+	}
+	if len(pages) == 0 {
+		return passages
+	}
+
+	cited := []models.TutorCitation{}
+	for _, passage := range passages {
+		if pages[passage.Page] {
+			cited = append(cited, passage)
+		}
+	}
+	if len(cited) == 0 {
+		return passages
+	}
+	return cited
+}
+
+// parsePageList reads page numbers and ranges like "3, 5-7"
+func parsePageList(list string) []int {
+	var pages []int
+	for _, part := range strings.Split(list, ",") {
+		bounds := strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '–' })
+		if len(bounds) == 0 {
+			continue
+		}
+		first, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			continue
+		}
+		last := first
+		if len(bounds) > 1 {
+			if n, err := strconv.Atoi(strings.TrimSpace(bounds[len(bounds)-1])); err == nil && n >= first && n-first < 50 {
+				last = n
+			}
+		}
+		for page := first; page <= last; page++ {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// extractiveAnswer quotes the sentences of the retrieved passages that share the most
+// terms with the question, each with its page
+func extractiveAnswer(index *DocumentIndex, question string, passages []models.TutorCitation) (string, []models.TutorCitation) {
+	queryTerms := make(map[string]bool)
+	for _, term := range index.terms(question) {
+		queryTerms[term] = true
+	}
+
+	type scoredSentence struct {
+		text    string
+		passage int
+		score   int
+	}
+	var sentences []scoredSentence
+	for i, passage := range passages {
+		for _, sentence := range strings.SplitAfter(passage.Excerpt, ". ") {
+			sentence = strings.TrimSpace(sentence)
+			score := 0
+			for _, term := range index.terms(sentence) {
+				if queryTerms[term] {
+					score++
+				}
+			}
+			if score > 0 {
+				sentences = append(sentences, scoredSentence{text: sentence, passage: i, score: score})
+			}
+		}
+	}
+	if len(sentences) == 0 {
+		return "These passages of the document look relevant to your question.", passages
+	}
+
+	sort.SliceStable(sentences, func(i, j int) bool { return sentences[i].score > sentences[j].score })
+	if len(sentences) > 3 {
+		sentences = sentences[:3]
+	}
+
+	var parts []string
+	used := make(map[int]bool)
+	for _, s := range sentences {
+		parts = append(parts, fmt.Sprintf("%s [%s]", s.text, passages[s.passage].Ref()))
+		used[s.passage] = true
+	}
+
+	cited := []models.TutorCitation{}
+	for i, passage := range passages {
+		if used[i] {
+			cited = append(cited, passage)
+		}
+	}
+	return "From the document: " + strings.Join(parts, " "), cited
+}
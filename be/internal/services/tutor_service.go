@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/repository"
+	"sync"
+)
+
+// tutorIndexCacheSize is the number of document indexes kept in memory
+const tutorIndexCacheSize = 32
+
+type TutorService struct {
+	repo *repository.TutorRepository
+
+	// Search indexes per document, dropped when the text of the document changes
+	indexMu sync.Mutex
+	indexes map[string]*DocumentIndex
+	order   []string
+}
+
+func NewTutorService() *TutorService {
+	return &TutorService{
+		repo:    repository.NewTutorRepository(),
+		indexes: make(map[string]*DocumentIndex),
+	}
+}
+
+// GetIndex returns the search index of a document, building it on first use
+func (ts *TutorService) GetIndex(doc *models.Document) *DocumentIndex {
+	ts.indexMu.Lock()
+	defer ts.indexMu.Unlock()
+
+	if index, ok := ts.indexes[doc.ID]; ok {
+		return index
+	}
+
+	index := NewDocumentIndex(doc.Pages)
+	if len(ts.order) >= tutorIndexCacheSize {
+		delete(ts.indexes, ts.order[0])
+		ts.order = ts.order[1:]
+	}
+	ts.indexes[doc.ID] = index
+	ts.order = append(ts.order, doc.ID)
+	return index
+}
+
+// Invalidate drops the search index of a document, so the next question builds it from
+// the current text
+func (ts *TutorService) Invalidate(docID string) {
+	ts.indexMu.Lock()
+	defer ts.indexMu.Unlock()
+
+	if _, ok := ts.indexes[docID]; !ok {
+		return
+	}
+	delete(ts.indexes, docID)
+	for i, id := range ts.order {
+		if id == docID {
+			ts.order = append(ts.order[:i], ts.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// GetHistory returns the latest messages of a user's conversation about a document
+func (ts *TutorService) GetHistory(userID, documentID string, limit int) ([]models.TutorMessage, error) {
+	ctx := context.Background()
+	return ts.repo.ListMessages(ctx, userID, documentID, limit)
+}
+
+// RecordExchange stores a question and the answer given to it
+func (ts *TutorService) RecordExchange(userID string, answer *models.TutorAnswer) error {
+	ctx := context.Background()
+
+	question := &models.TutorMessage{
+		DocumentID: answer.DocumentID,
+		Role:       "user",
+		Content:    answer.Question,
+	}
+	if err := ts.repo.AddMessage(ctx, userID, question); err != nil {
+		return fmt.Errorf("failed to store question: %w", err)
+	}
+
+	reply := &models.TutorMessage{
+		DocumentID: answer.DocumentID,
+		Role:       "assistant",
+		Content:    answer.Answer,
+		Citations:  answer.Citations,
+	}
+	if err := ts.repo.AddMessage(ctx, userID, reply); err != nil {
+		return fmt.Errorf("failed to store answer: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *TutorService) ClearHistory(userID, documentID string) error {
+	ctx := context.Background()
+	return ts.repo.DeleteMessages(ctx, userID, documentID)
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"pbkk-quizlit-backend/internal/models"
+	"testing"
+)
+
+func TestTutorIndexInvalidate(t *testing.T) {
+	ts := NewTutorService()
+	doc := &models.Document{ID: "d1", Pages: []models.DocumentPage{{Number: 1, Text: "Cells divide by mitosis."}}}
+
+	first := ts.GetIndex(doc)
+	if ts.GetIndex(doc) != first {
+		t.Fatal("index was rebuilt without a change")
+	}
+
+	doc.Pages = []models.DocumentPage{{Number: 1, Text: "Cells divide by meiosis."}}
+	ts.Invalidate(doc.ID)
+	if ts.GetIndex(doc) == first {
+		t.Error("index was kept after Invalidate")
+	}
+	if len(ts.order) != 1 {
+		t.Errorf("%d documents in the eviction order, want 1", len(ts.order))
+	}
+
+	// Unknown documents are ignored
+	ts.Invalidate("d2")
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Errors of uploads refused by their content or by a limit
+var (
+	ErrFileTooLarge      = errors.New("file is too large")
+	ErrUnsupportedFile   = errors.New("unsupported file type")
+	ErrContentMismatch   = errors.New("file content does not match its extension")
+	ErrEncryptedPDF      = errors.New("PDF is encrypted or password-protected")
+	ErrTooManyPages      = errors.New("PDF has too many pages")
+	ErrStreamTooLarge    = errors.New("PDF page content is too large")
+	ErrExtractionTimeout = errors.New("extraction took too long")
+	ErrArchiveTooLarge   = errors.New("archive is too large")
+)
+
+// UploadLimits bound the resources an uploaded file may take. Zero means no limit.
+type UploadLimits struct {
+	MaxFileSize       int64         // size of the uploaded file in bytes
+	MaxPages          int           // pages of a PDF
+	MaxStreamSize     int64         // decompressed content of a PDF page in bytes
+	MaxArchiveEntries int           // files in a DOCX, PPTX or EPUB archive
+	MaxArchiveSize    int64         // decompressed content read from such an archive in bytes
+	MaxExtractTime    time.Duration // time to extract a whole document
+}
+
+// DefaultUploadLimits are the limits used unless configured otherwise
+func DefaultUploadLimits() UploadLimits {
+	return UploadLimits{
+		MaxFileSize:       50 << 20,
+		MaxPages:          2000,
+		MaxStreamSize:     64 << 20,
+		MaxArchiveEntries: 10000,
+		MaxArchiveSize:    256 << 20,
+		MaxExtractTime:    2 * time.Minute,
+	}
+}
+
+// SpooledUpload is an uploaded file copied to a temporary file, so it is read in place
+// rather than held in memory. Close removes the file.
+type SpooledUpload struct {
+	File   *os.File
+	Size   int64
+	SHA256 string
+}
+
+// SpoolUpload copies an upload to a temporary file, hashing it on the way. Uploads larger
+// than maxSize fail with ErrFileTooLarge.
+func SpoolUpload(r io.Reader, maxSize int64) (*SpooledUpload, error) {
+	file, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	upload := &SpooledUpload{File: file}
+
+	if maxSize > 0 {
+		r = io.LimitReader(r, maxSize+1)
+	}
+	hash := sha256.New()
+	upload.Size, err = io.Copy(io.MultiWriter(file, hash), r)
+	if err != nil {
+		upload.Close()
+		return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	if maxSize > 0 && upload.Size > maxSize {
+		upload.Close()
+		return nil, fmt.Errorf("%w: the limit is %s", ErrFileTooLarge, formatBytes(maxSize))
+	}
+	upload.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return upload, nil
+}
+
+// Reader returns a reader of the whole upload from its start
+func (u *SpooledUpload) Reader() io.Reader {
+	return io.NewSectionReader(u.File, 0, u.Size)
+}
+
+// Head returns up to the first n bytes of the upload
+func (u *SpooledUpload) Head(n int) []byte {
+	return readHead(u.File, n)
+}
+
+// Close closes and removes the temporary file
+func (u *SpooledUpload) Close() error {
+	err := u.File.Close()
+	os.Remove(u.File.Name())
+	return err
+}
+
+// readHead reads up to the first n bytes of a file
+func readHead(r io.ReaderAt, n int) []byte {
+	head := make([]byte, n)
+	read, _ := r.ReadAt(head, 0)
+	return head[:read]
+}
+
+// zipMagic starts every zip archive, which DOCX, PPTX and EPUB files are
+var zipMagic = []byte("PK\x03\x04")
+
+// supportedExtensions are the extensions of the formats text can be extracted from
+var supportedExtensions = map[string]bool{
+	".pdf": true, ".txt": true, ".docx": true, ".pptx": true, ".epub": true,
+	".md": true, ".markdown": true, ".html": true, ".htm": true, ".xhtml": true,
+	".srt": true, ".vtt": true,
+}
+
+// SupportedFileType reports whether text can be extracted from files with the extension
+// of filename
+func SupportedFileType(filename string) bool {
+	return supportedExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// CheckContent checks the first bytes of a file against what its extension promises: a
+// PDF header, a zip archive for Office files and EPUBs, or text without binary data
+func CheckContent(filename string, head []byte) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch ext {
+	case ".pdf":
+		// Some writers put a few bytes of junk before the header, which readers accept
+		if bytes.Contains(head[:min(len(head), 1024)], []byte("%PDF-")) {
+			return nil
+		}
+	case ".docx", ".pptx", ".epub":
+		if bytes.HasPrefix(head, zipMagic) {
+			return nil
+		}
+	case ".txt", ".md", ".markdown", ".html", ".htm", ".xhtml", ".srt", ".vtt":
+		if looksLikeText(head) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return fmt.Errorf("%w: this is not a %s file", ErrContentMismatch, strings.TrimPrefix(ext, "."))
+}
+
+// looksLikeText reports whether the start of a file is text: it has no NUL bytes, unless
+// it is UTF-16, and is not a format known to be binary
+func looksLikeText(head []byte) bool {
+	if bytes.HasPrefix(head, []byte{0xFF, 0xFE}) || bytes.HasPrefix(head, []byte{0xFE, 0xFF}) || utf16Order(head) != "" {
+		return true
+	}
+	for _, magic := range [][]byte{[]byte("%PDF-"), zipMagic, []byte("\x89PNG"), []byte("\xFF\xD8\xFF"), []byte("GIF8")} {
+		if bytes.HasPrefix(head, magic) {
+			return false
+		}
+	}
+	return bytes.IndexByte(head[:min(len(head), 8192)], 0) < 0
+}
+
+// formatBytes writes a size in bytes for messages, e.g. "50 MB"
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30 && n%(1<<30) == 0:
+		return fmt.Sprintf("%d GB", n>>30)
+	case n >= 1<<20:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d bytes", n)
+	}
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Errors of resumable uploads
+var (
+	ErrUploadNotFound   = errors.New("upload not found")
+	ErrUploadExpired    = errors.New("upload has expired")
+	ErrUploadOffset     = errors.New("upload offset does not match")
+	ErrUploadBusy       = errors.New("upload is being written by another request")
+	ErrUploadIncomplete = errors.New("upload is not complete")
+	ErrChecksumMismatch = errors.New("checksum does not match")
+	ErrTooManyUploads   = errors.New("too many uploads in progress")
+	ErrUploadSpace      = errors.New("not enough space for the upload")
+)
+
+// UploadSessionLimits bound the uploads kept at once: how many a user may have in
+// progress, and the total size of all of them. Zero means no limit.
+type UploadSessionLimits struct {
+	MaxPerUser   int
+	MaxTotalSize int64
+}
+
+// UploadSessions keeps uploads sent in chunks until they are complete. Each session is a
+// data file and a JSON file under a directory, so uploads can be resumed after a restart
+// too. Sessions expire when no chunk arrived for their time to live.
+type UploadSessions struct {
+	dir    string
+	ttl    time.Duration
+	limits UploadSessionLimits
+
+	mu       sync.Mutex
+	sessions map[string]*models.UploadSession
+	busy     map[string]bool // sessions a request is writing to
+}
+
+// NewUploadSessions opens the upload sessions kept under dir, dropping those that expired
+func NewUploadSessions(dir string, ttl time.Duration, limits UploadSessionLimits) (*UploadSessions, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	us := &UploadSessions{
+		dir:      dir,
+		ttl:      ttl,
+		limits:   limits,
+		sessions: make(map[string]*models.UploadSession),
+		busy:     make(map[string]bool),
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uploads: %w", err)
+	}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var session models.UploadSession
+		if err := json.Unmarshal(content, &session); err != nil || session.ID == "" {
+			continue
+		}
+		// The data file is the truth about how much arrived, as a chunk may have been
+		// cut off after the session was last saved
+		info, err := os.Stat(us.dataPath(session.ID))
+		if err != nil {
+			us.remove(session.ID)
+			continue
+		}
+		session.Offset = info.Size()
+		if session.Offset > session.Length {
+			session.Offset = session.Length
+		}
+		us.sessions[session.ID] = &session
+	}
+	us.Expire(time.Now())
+	return us, nil
+}
+
+// Create starts an upload of a file of the given length. hash is the expected hex SHA-256
+// of the whole file, or empty when it is not known. The length is reserved when the upload
+// starts, so uploads in progress never need more space than the limit.
+func (us *UploadSessions) Create(userID, filename, contentType string, length int64, hash string, metadata map[string]string) (*models.UploadSession, error) {
+	now := time.Now()
+	session := &models.UploadSession{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: contentType,
+		Length:      length,
+		SHA256:      strings.ToLower(hash),
+		Metadata:    metadata,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(us.ttl),
+	}
+
+	us.mu.Lock()
+	if err := us.checkLimits(userID, length); err != nil {
+		us.mu.Unlock()
+		return nil, err
+	}
+	us.sessions[session.ID] = session
+	us.mu.Unlock()
+
+	file, err := os.Create(us.dataPath(session.ID))
+	if err == nil {
+		file.Close()
+		err = us.save(session)
+	} else {
+		err = fmt.Errorf("failed to create upload file: %w", err)
+	}
+	if err != nil {
+		us.mu.Lock()
+		delete(us.sessions, session.ID)
+		us.mu.Unlock()
+		us.remove(session.ID)
+		return nil, err
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+// checkLimits checks a user may start another upload of the given length. The caller
+// holds mu.
+func (us *UploadSessions) checkLimits(userID string, length int64) error {
+	count, total := 0, int64(0)
+	for _, session := range us.sessions {
+		if session.UserID == userID {
+			count++
+		}
+		total += session.Length
+	}
+
+	if us.limits.MaxPerUser > 0 && count >= us.limits.MaxPerUser {
+		return fmt.Errorf("%w: finish or delete one of your %d uploads first", ErrTooManyUploads, count)
+	}
+	if us.limits.MaxTotalSize > 0 && total+length > us.limits.MaxTotalSize {
+		return fmt.Errorf("%w: try again later", ErrUploadSpace)
+	}
+	return nil
+}
+
+// Get returns a user's upload session
+func (us *UploadSessions) Get(userID, id string) (*models.UploadSession, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	session, err := us.lookup(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	copied := *session
+	return &copied, nil
+}
+
+// Append writes a chunk read from r at offset, which must be where the upload stopped.
+// The chunk may end early, as when the connection drops; what arrived is kept and the
+// upload resumes from there. A chunk with a checksum is written whole or not at all.
+func (us *UploadSessions) Append(userID, id string, offset int64, r io.Reader, checksum []byte) (*models.UploadSession, error) {
+	session, err := us.acquire(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer us.release(id)
+
+	if offset != session.Offset {
+		return nil, fmt.Errorf("%w: the upload is at %d, not %d", ErrUploadOffset, session.Offset, offset)
+	}
+
+	file, err := os.OpenFile(us.dataPath(id), os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %w", err)
+	}
+
+	remaining := session.Length - offset
+	hash := sha256.New()
+	written, copyErr := io.Copy(io.MultiWriter(file, hash), io.LimitReader(r, remaining+1))
+
+	switch {
+	case written > remaining:
+		copyErr = fmt.Errorf("%w: the chunk goes past the length of the upload", ErrFileTooLarge)
+		written = 0
+	case checksum != nil && copyErr == nil && !bytes.Equal(hash.Sum(nil), checksum):
+		copyErr = fmt.Errorf("%w: the chunk was not received as sent", ErrChecksumMismatch)
+		written = 0
+	case checksum != nil && copyErr != nil:
+		written = 0
+	}
+	if err := file.Truncate(offset + written); err != nil && copyErr == nil {
+		copyErr = fmt.Errorf("failed to write upload file: %w", err)
+	}
+
+	us.mu.Lock()
+	session.Offset = offset + written
+	session.ExpiresAt = time.Now().Add(us.ttl)
+	copied := *session
+	us.mu.Unlock()
+	if err := us.save(&copied); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return &copied, copyErr
+	}
+	return &copied, nil
+}
+
+// Finish hands the file of a complete upload to ingest, checking it against the hash given
+// when the upload was created. A file that does not match is dropped, so the upload starts
+// again from the beginning. The upload is removed once ingest succeeds; when ingest fails
+// it is kept complete, so finishing can be tried again.
+func (us *UploadSessions) Finish(userID, id string, ingest func(*SpooledUpload, *models.UploadSession) error) error {
+	session, err := us.acquire(userID, id)
+	if err != nil {
+		return err
+	}
+	defer us.release(id)
+
+	if session.Offset < session.Length {
+		return fmt.Errorf("%w: %d of %d bytes received", ErrUploadIncomplete, session.Offset, session.Length)
+	}
+
+	file, err := os.Open(us.dataPath(id))
+	if err != nil {
+		return fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to read upload file: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	if session.SHA256 != "" && sum != session.SHA256 {
+		os.Truncate(us.dataPath(id), 0)
+		us.mu.Lock()
+		session.Offset = 0
+		copied := *session
+		us.mu.Unlock()
+		us.save(&copied)
+		return fmt.Errorf("%w: the file has SHA-256 %s, not %s", ErrChecksumMismatch, sum, session.SHA256)
+	}
+
+	us.mu.Lock()
+	copied := *session
+	us.mu.Unlock()
+	if err := ingest(&SpooledUpload{File: file, Size: session.Length, SHA256: sum}, &copied); err != nil {
+		// Give the client the whole time to live to try again
+		us.mu.Lock()
+		session.ExpiresAt = time.Now().Add(us.ttl)
+		copied = *session
+		us.mu.Unlock()
+		us.save(&copied)
+		return err
+	}
+
+	us.mu.Lock()
+	delete(us.sessions, id)
+	us.mu.Unlock()
+	us.remove(id)
+	return nil
+}
+
+// Delete ends an upload and removes what was received of it
+func (us *UploadSessions) Delete(userID, id string) error {
+	if _, err := us.acquire(userID, id); err != nil {
+		return err
+	}
+
+	us.mu.Lock()
+	delete(us.sessions, id)
+	delete(us.busy, id)
+	us.mu.Unlock()
+	us.remove(id)
+	return nil
+}
+
+// Expire removes the sessions that expired by now and returns how many there were.
+// Sessions a request is writing to are left alone.
+func (us *UploadSessions) Expire(now time.Time) int {
+	us.mu.Lock()
+	var expired []string
+	for id, session := range us.sessions {
+		if now.After(session.ExpiresAt) && !us.busy[id] {
+			delete(us.sessions, id)
+			expired = append(expired, id)
+		}
+	}
+	us.mu.Unlock()
+
+	for _, id := range expired {
+		us.remove(id)
+	}
+	return len(expired)
+}
+
+// RunExpiry removes expired sessions every interval until ctx is done
+func (us *UploadSessions) RunExpiry(ctx context.Context, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n := us.Expire(now); n > 0 {
+				logger.Infof("Removed %d expired uploads", n)
+			}
+		}
+	}
+}
+
+// lookup returns a session of the user that has not expired. The caller holds mu.
+func (us *UploadSessions) lookup(userID, id string) (*models.UploadSession, error) {
+	session, ok := us.sessions[id]
+	if !ok || session.UserID != userID {
+		return nil, ErrUploadNotFound
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	return session, nil
+}
+
+// acquire marks a session as being written to, so one request writes to it at a time
+func (us *UploadSessions) acquire(userID, id string) (*models.UploadSession, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	session, err := us.lookup(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if us.busy[id] {
+		return nil, ErrUploadBusy
+	}
+	us.busy[id] = true
+	return session, nil
+}
+
+func (us *UploadSessions) release(id string) {
+	us.mu.Lock()
+	delete(us.busy, id)
+	us.mu.Unlock()
+}
+
+// save writes the session next to its data, replacing the file so it is never partly
+// written
+func (us *UploadSessions) save(session *models.UploadSession) error {
+	content, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to save upload: %w", err)
+	}
+	tmp := us.infoPath(session.ID) + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return fmt.Errorf("failed to save upload: %w", err)
+	}
+	if err := os.Rename(tmp, us.infoPath(session.ID)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to save upload: %w", err)
+	}
+	return nil
+}
+
+func (us *UploadSessions) remove(id string) {
+	os.Remove(us.dataPath(id))
+	os.Remove(us.infoPath(id))
+}
+
+func (us *UploadSessions) dataPath(id string) string {
+	return filepath.Join(us.dir, id+".part")
+}
+
+func (us *UploadSessions) infoPath(id string) string {
+	return filepath.Join(us.dir, id+".json")
+}
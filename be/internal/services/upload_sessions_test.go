@@ -0,0 +1,364 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func newTestSessions(t *testing.T, ttl time.Duration, limits UploadSessionLimits) *UploadSessions {
+	t.Helper()
+	sessions, err := NewUploadSessions(t.TempDir(), ttl, limits)
+	if err != nil {
+		t.Fatalf("NewUploadSessions: %v", err)
+	}
+	return sessions
+}
+
+func createTestUpload(t *testing.T, us *UploadSessions, userID string, length int64, hash string) *models.UploadSession {
+	t.Helper()
+	session, err := us.Create(userID, "notes.txt", "text/plain", length, hash, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return session
+}
+
+func sha256Of(content string) []byte {
+	sum := sha256.Sum256([]byte(content))
+	return sum[:]
+}
+
+func TestUploadSessionsAppendOffset(t *testing.T) {
+	us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+	session := createTestUpload(t, us, "u1", 10, "")
+
+	tests := []struct {
+		name       string
+		offset     int64
+		chunk      string
+		wantErr    error
+		wantOffset int64
+	}{
+		{"first chunk", 0, "hello", nil, 5},
+		{"offset behind the upload", 3, "lo", ErrUploadOffset, 5},
+		{"offset ahead of the upload", 7, "ld", ErrUploadOffset, 5},
+		{"chunk past the length", 5, "world!", ErrFileTooLarge, 5},
+		{"last chunk", 5, "world", nil, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := us.Append("u1", session.ID, tt.offset, strings.NewReader(tt.chunk), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Append error = %v, want %v", err, tt.wantErr)
+			}
+			got, err := us.Get("u1", session.ID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got.Offset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d", got.Offset, tt.wantOffset)
+			}
+		})
+	}
+
+	content, err := os.ReadFile(us.dataPath(session.ID))
+	if err != nil {
+		t.Fatalf("reading upload file: %v", err)
+	}
+	if string(content) != "helloworld" {
+		t.Errorf("upload file = %q, want %q", content, "helloworld")
+	}
+}
+
+func TestUploadSessionsOtherUser(t *testing.T) {
+	us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+	session := createTestUpload(t, us, "u1", 10, "")
+
+	if _, err := us.Get("u2", session.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("Get by another user error = %v, want %v", err, ErrUploadNotFound)
+	}
+	if _, err := us.Append("u2", session.ID, 0, strings.NewReader("hello"), nil); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("Append by another user error = %v, want %v", err, ErrUploadNotFound)
+	}
+}
+
+func TestUploadSessionsInterruptedChunk(t *testing.T) {
+	dropped := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		checksum   []byte
+		wantOffset int64
+	}{
+		// What arrived is kept, so the upload resumes after it
+		{"without checksum", nil, 3},
+		// A chunk with a checksum cannot be checked unless it arrived whole
+		{"with checksum", sha256Of("hello"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+			session := createTestUpload(t, us, "u1", 10, "")
+
+			r := io.MultiReader(strings.NewReader("hel"), iotest.ErrReader(dropped))
+			got, err := us.Append("u1", session.ID, 0, r, tt.checksum)
+			if !errors.Is(err, dropped) {
+				t.Fatalf("Append error = %v, want %v", err, dropped)
+			}
+			if got.Offset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d", got.Offset, tt.wantOffset)
+			}
+			info, err := os.Stat(us.dataPath(session.ID))
+			if err != nil {
+				t.Fatalf("upload file: %v", err)
+			}
+			if info.Size() != tt.wantOffset {
+				t.Errorf("upload file size = %d, want %d", info.Size(), tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestUploadSessionsChunkChecksum(t *testing.T) {
+	tests := []struct {
+		name       string
+		checksum   []byte
+		wantErr    error
+		wantOffset int64
+	}{
+		{"matching checksum", sha256Of("hello"), nil, 5},
+		{"corrupted chunk", sha256Of("hellO"), ErrChecksumMismatch, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+			session := createTestUpload(t, us, "u1", 10, "")
+
+			got, err := us.Append("u1", session.ID, 0, strings.NewReader("hello"), tt.checksum)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Append error = %v, want %v", err, tt.wantErr)
+			}
+			if got.Offset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d", got.Offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestUploadSessionsFinish(t *testing.T) {
+	const content = "helloworld"
+	sum := hex.EncodeToString(sha256Of(content))
+
+	t.Run("incomplete upload", func(t *testing.T) {
+		us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+		session := createTestUpload(t, us, "u1", 10, sum)
+		us.Append("u1", session.ID, 0, strings.NewReader("hello"), nil)
+
+		err := us.Finish("u1", session.ID, func(*SpooledUpload, *models.UploadSession) error {
+			t.Error("ingest called for an incomplete upload")
+			return nil
+		})
+		if !errors.Is(err, ErrUploadIncomplete) {
+			t.Errorf("Finish error = %v, want %v", err, ErrUploadIncomplete)
+		}
+	})
+
+	t.Run("file does not match its hash", func(t *testing.T) {
+		us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+		session := createTestUpload(t, us, "u1", 10, sum)
+		us.Append("u1", session.ID, 0, strings.NewReader("HELLOWORLD"), nil)
+
+		err := us.Finish("u1", session.ID, func(*SpooledUpload, *models.UploadSession) error {
+			t.Error("ingest called for a file that does not match its hash")
+			return nil
+		})
+		if !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Finish error = %v, want %v", err, ErrChecksumMismatch)
+		}
+		// The upload starts again from the beginning
+		got, err := us.Get("u1", session.ID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got.Offset != 0 {
+			t.Errorf("offset = %d, want 0", got.Offset)
+		}
+	})
+
+	t.Run("ingest fails and is tried again", func(t *testing.T) {
+		us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+		session := createTestUpload(t, us, "u1", 10, sum)
+		us.Append("u1", session.ID, 0, strings.NewReader(content), nil)
+
+		failed := errors.New("failed to save document")
+		err := us.Finish("u1", session.ID, func(*SpooledUpload, *models.UploadSession) error {
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("Finish error = %v, want %v", err, failed)
+		}
+		got, err := us.Get("u1", session.ID)
+		if err != nil {
+			t.Fatalf("upload was not kept after ingest failed: %v", err)
+		}
+		if got.Offset != got.Length {
+			t.Errorf("offset = %d, want %d", got.Offset, got.Length)
+		}
+
+		// The client sends the last PATCH again, with an empty body
+		if _, err := us.Append("u1", session.ID, got.Offset, strings.NewReader(""), nil); err != nil {
+			t.Fatalf("Append of an empty chunk: %v", err)
+		}
+		var received string
+		err = us.Finish("u1", session.ID, func(upload *SpooledUpload, session *models.UploadSession) error {
+			data, err := io.ReadAll(upload.Reader())
+			received = string(data)
+			if upload.SHA256 != sum {
+				t.Errorf("upload SHA-256 = %s, want %s", upload.SHA256, sum)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Finish: %v", err)
+		}
+		if received != content {
+			t.Errorf("ingested %q, want %q", received, content)
+		}
+
+		if _, err := us.Get("u1", session.ID); !errors.Is(err, ErrUploadNotFound) {
+			t.Errorf("Get after Finish error = %v, want %v", err, ErrUploadNotFound)
+		}
+		for _, path := range []string{us.dataPath(session.ID), us.infoPath(session.ID)} {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%s was not removed", filepath.Base(path))
+			}
+		}
+	})
+}
+
+func TestUploadSessionsExpiry(t *testing.T) {
+	us := newTestSessions(t, time.Hour, UploadSessionLimits{})
+	session := createTestUpload(t, us, "u1", 10, "")
+
+	if n := us.Expire(time.Now().Add(30 * time.Minute)); n != 0 {
+		t.Errorf("Expire before the time to live removed %d uploads", n)
+	}
+
+	// A chunk keeps the upload alive for another time to live
+	us.sessions[session.ID].ExpiresAt = time.Now().Add(time.Minute)
+	if _, err := us.Append("u1", session.ID, 0, strings.NewReader("hello"), nil); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if n := us.Expire(time.Now().Add(30 * time.Minute)); n != 0 {
+		t.Errorf("Expire removed an upload that just received a chunk")
+	}
+
+	if n := us.Expire(time.Now().Add(2 * time.Hour)); n != 1 {
+		t.Errorf("Expire removed %d uploads, want 1", n)
+	}
+	if _, err := us.Get("u1", session.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("Get after expiry error = %v, want %v", err, ErrUploadNotFound)
+	}
+	for _, path := range []string{us.dataPath(session.ID), us.infoPath(session.ID)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", filepath.Base(path))
+		}
+	}
+}
+
+func TestUploadSessionsExpiredBeforeRemoval(t *testing.T) {
+	us := newTestSessions(t, -time.Second, UploadSessionLimits{})
+	session := createTestUpload(t, us, "u1", 10, "")
+
+	if _, err := us.Get("u1", session.ID); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("Get error = %v, want %v", err, ErrUploadExpired)
+	}
+	if _, err := us.Append("u1", session.ID, 0, strings.NewReader("hello"), nil); !errors.Is(err, ErrUploadExpired) {
+		t.Errorf("Append error = %v, want %v", err, ErrUploadExpired)
+	}
+}
+
+func TestUploadSessionsRestart(t *testing.T) {
+	dir := t.TempDir()
+	us, err := NewUploadSessions(dir, time.Hour, UploadSessionLimits{})
+	if err != nil {
+		t.Fatalf("NewUploadSessions: %v", err)
+	}
+
+	resumed := createTestUpload(t, us, "u1", 10, "")
+	if _, err := us.Append("u1", resumed.ID, 0, strings.NewReader("hello"), nil); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	// A chunk cut off by the restart after it was written but before the session was saved
+	file, err := os.OpenFile(us.dataPath(resumed.ID), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("opening upload file: %v", err)
+	}
+	file.WriteString("wo")
+	file.Close()
+
+	lost := createTestUpload(t, us, "u1", 10, "")
+	os.Remove(us.dataPath(lost.ID))
+
+	expired := createTestUpload(t, us, "u1", 10, "")
+	us.sessions[expired.ID].ExpiresAt = time.Now().Add(-time.Minute)
+	us.save(us.sessions[expired.ID])
+
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644)
+
+	restarted, err := NewUploadSessions(dir, time.Hour, UploadSessionLimits{})
+	if err != nil {
+		t.Fatalf("NewUploadSessions after restart: %v", err)
+	}
+
+	got, err := restarted.Get("u1", resumed.ID)
+	if err != nil {
+		t.Fatalf("Get after restart: %v", err)
+	}
+	if got.Offset != 7 {
+		t.Errorf("offset after restart = %d, want 7", got.Offset)
+	}
+	if _, err := restarted.Append("u1", resumed.ID, 7, strings.NewReader("rld"), nil); err != nil {
+		t.Errorf("Append after restart: %v", err)
+	}
+
+	if _, err := restarted.Get("u1", lost.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("upload without its file: Get error = %v, want %v", err, ErrUploadNotFound)
+	}
+	if _, err := restarted.Get("u1", expired.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("expired upload: Get error = %v, want %v", err, ErrUploadNotFound)
+	}
+	if _, err := os.Stat(restarted.dataPath(expired.ID)); !os.IsNotExist(err) {
+		t.Errorf("file of the expired upload was not removed")
+	}
+}
+
+func TestUploadSessionsLimits(t *testing.T) {
+	us := newTestSessions(t, time.Hour, UploadSessionLimits{MaxPerUser: 2, MaxTotalSize: 100})
+
+	first := createTestUpload(t, us, "u1", 40, "")
+	createTestUpload(t, us, "u1", 40, "")
+
+	if _, err := us.Create("u1", "more.txt", "", 10, "", nil); !errors.Is(err, ErrTooManyUploads) {
+		t.Errorf("third upload of a user: error = %v, want %v", err, ErrTooManyUploads)
+	}
+	if _, err := us.Create("u2", "big.txt", "", 30, "", nil); !errors.Is(err, ErrUploadSpace) {
+		t.Errorf("upload past the total size: error = %v, want %v", err, ErrUploadSpace)
+	}
+	createTestUpload(t, us, "u2", 20, "")
+
+	// Finishing or deleting an upload frees its place
+	if err := us.Delete("u1", first.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	createTestUpload(t, us, "u1", 40, "")
+}
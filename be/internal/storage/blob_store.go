@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when a blob does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps uploaded files. Keys are slash-separated paths such as
+// "documents/<user>/<hash>.pdf".
+type BlobStore interface {
+	// Put stores everything read from r under key, replacing any existing blob, and
+	// returns the number of bytes written
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Get opens the blob stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key. Deleting a missing blob is not an error.
+	Delete(ctx context.Context, key string) error
+	// List returns the blobs whose keys start with prefix
+	List(ctx context.Context, prefix string) ([]BlobInfo, error)
+}
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Options selects and configures a blob store backend
+type Options struct {
+	Backend string // "local" or "s3"
+	Dir     string // root directory of the local backend
+	S3      S3Config
+}
+
+// Open creates the blob store selected by the options
+func Open(opts Options) (BlobStore, error) {
+	switch opts.Backend {
+	case "", "local":
+		return NewLocalStore(opts.Dir)
+	case "s3":
+		return NewS3Store(opts.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", opts.Backend)
+	}
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LifecycleRule expires the blobs under a key prefix once they are older than MaxAge
+type LifecycleRule struct {
+	Prefix string
+	MaxAge time.Duration
+}
+
+// LifecycleReport counts what one lifecycle run did
+type LifecycleReport struct {
+	Scanned int
+	Deleted int
+	Bytes   int64
+}
+
+// ParseLifecycleRules reads rules written as "prefix=age" separated by commas, e.g.
+// "tmp/=24h,images/=30d". Ages are Go durations, with "d" allowed for days.
+func ParseLifecycleRules(spec string) ([]LifecycleRule, error) {
+	var rules []LifecycleRule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		prefix, age, ok := strings.Cut(part, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("invalid lifecycle rule %q, expected prefix=age", part)
+		}
+
+		maxAge, err := parseAge(strings.TrimSpace(age))
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("invalid age in lifecycle rule %q", part)
+		}
+		rules = append(rules, LifecycleRule{Prefix: prefix, MaxAge: maxAge})
+	}
+	return rules, nil
+}
+
+func parseAge(age string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(age, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(age)
+}
+
+// ApplyLifecycle deletes the blobs that have outlived their rule. A failure to delete
+// one blob does not stop the others from being deleted; the first error is returned.
+func ApplyLifecycle(ctx context.Context, store BlobStore, rules []LifecycleRule, now time.Time) (LifecycleReport, error) {
+	var report LifecycleReport
+	var firstErr error
+
+	for _, rule := range rules {
+		blobs, err := store.List(ctx, rule.Prefix)
+		if err != nil {
+			return report, err
+		}
+
+		for _, blob := range blobs {
+			report.Scanned++
+			if now.Sub(blob.ModTime) < rule.MaxAge {
+				continue
+			}
+			if err := store.Delete(ctx, blob.Key); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			report.Deleted++
+			report.Bytes += blob.Size
+		}
+	}
+
+	return report, firstErr
+}
+
+// RunLifecycle applies the rules now and then every interval until ctx is done
+func RunLifecycle(ctx context.Context, store BlobStore, rules []LifecycleRule, interval time.Duration, logger *logrus.Logger) {
+	if len(rules) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := ApplyLifecycle(ctx, store, rules, time.Now())
+		if err != nil {
+			logger.Warnf("Storage lifecycle run failed: %v", err)
+		}
+		if report.Deleted > 0 {
+			logger.Infof("Storage lifecycle deleted %d of %d blobs (%d bytes)", report.Deleted, report.Scanned, report.Bytes)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreRejectsKeysOutsideRoot(t *testing.T) {
+	parent := t.TempDir()
+	store, err := NewLocalStore(filepath.Join(parent, "blobs"))
+	if err != nil {
+		t.Fatalf("NewLocalStore: %v", err)
+	}
+	ctx := context.Background()
+
+	// A file next to the root that escaping keys would reach
+	secret := filepath.Join(parent, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{
+		"",
+		"..",
+		"../secret.txt",
+		"documents/../../secret.txt",
+		"documents/u1/../../../secret.txt",
+		"/etc/passwd",
+		filepath.Join(parent, "secret.txt"),
+	} {
+		if _, err := store.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Errorf("Put(%q): no error", key)
+		}
+		if blob, err := store.Get(ctx, key); err == nil {
+			blob.Close()
+			t.Errorf("Get(%q): no error", key)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete(%q): no error", key)
+		}
+	}
+	if _, err := store.List(ctx, "../"); err == nil {
+		t.Errorf("List(\"../\"): no error")
+	}
+
+	if data, err := os.ReadFile(secret); err != nil || string(data) != "secret" {
+		t.Errorf("file outside the root changed: %q, %v", data, err)
+	}
+
+	// Dots that stay inside the root are fine, as are names that only start with dots
+	for key, stored := range map[string]string{
+		"documents/u1/../u2/a.txt": "documents/u2/a.txt",
+		"documents/..notes.txt":    "documents/..notes.txt",
+	} {
+		if _, err := store.Put(ctx, key, strings.NewReader("inside")); err != nil {
+			t.Errorf("Put(%q): %v", key, err)
+			continue
+		}
+		blob, err := store.Get(ctx, stored)
+		if err != nil {
+			t.Errorf("Get(%q): %v", stored, err)
+			continue
+		}
+		data, _ := io.ReadAll(blob)
+		blob.Close()
+		if string(data) != "inside" {
+			t.Errorf("Get(%q): %q", stored, data)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
+	"strings"
+)
+
+// runLintCLI lints a quiz stored as JSON, either a bare quiz or an API response wrapping
+// one, and exits with status 1 when any question has an error
+func runLintCLI(quizPath, sourcePath string) {
+	quiz, err := loadQuizFile(quizPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	source := ""
+	if sourcePath != "" {
+		source, err = loadLintSource(sourcePath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	report := services.LintQuiz(quiz, source)
+
+	fmt.Println("Quiz Lint Report")
+	fmt.Println("================")
+	fmt.Printf("Quiz:      %s\n", quiz.Title)
+	fmt.Printf("Questions: %d\n", report.Questions)
+	if report.SourceChecked {
+		fmt.Printf("Source:    %s\n", sourcePath)
+	}
+	fmt.Println()
+
+	for _, issue := range report.Issues {
+		location := "quiz"
+		if issue.QuestionIndex >= 0 {
+			location = fmt.Sprintf("Q%d", issue.QuestionIndex+1)
+		}
+		fmt.Printf("%-5s %-8s %-18s %s\n", location, strings.ToUpper(issue.Severity), issue.Rule, issue.Message)
+	}
+	if len(report.Issues) == 0 {
+		fmt.Println("No issues found")
+	}
+
+	fmt.Printf("\n%d errors, %d warnings\n", report.Errors, report.Warnings)
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
+
+func loadQuizFile(path string) (*models.Quiz, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quiz file: %w", err)
+	}
+
+	var wrapped struct {
+		Data *models.Quiz `json:"data"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Data != nil {
+		return wrapped.Data, nil
+	}
+
+	var quiz models.Quiz
+	if err := json.Unmarshal(data, &quiz); err != nil {
+		return nil, fmt.Errorf("failed to parse quiz file: %w", err)
+	}
+	if len(quiz.Questions) == 0 {
+		return nil, fmt.Errorf("quiz file has no questions")
+	}
+	return &quiz, nil
+}
+
+func loadLintSource(path string) (string, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".pdf" {
+		return NewPDFParser().ExtractTextFromFile(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read source file: %w", err)
+	}
+	return string(data), nil
+}
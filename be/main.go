@@ -7,6 +7,7 @@ import (
 	"os"
 	"pbkk-quizlit-backend/internal/api"
 	"pbkk-quizlit-backend/internal/config"
+	"pbkk-quizlit-backend/internal/services"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -23,6 +24,13 @@ func main() {
 		uploadDir  = flag.String("upload-dir", "./uploads", "Upload directory for PDF server mode")
 		filePath   = flag.String("file", "", "Path to the PDF file to parse (CLI mode)")
 		infoOnly   = flag.Bool("info", false, "Show only PDF information without extracting text (CLI mode)")
+		pageSpec   = flag.String("pages", "", "Page ranges to extract, e.g. 40-55 or 3,7-9 (CLI mode)")
+		sections   = flag.String("sections", "", "Comma-separated outline section IDs or titles to extract (CLI mode)")
+		lintPath   = flag.String("lint", "", "Path to a quiz JSON file to check for question-quality issues")
+		sourcePath = flag.String("source", "", "Source document (PDF or text) to check answer keys against (lint mode)")
+		checkStore = flag.Bool("storage-check", false, "Check the configured file storage backend and exit")
+		normCheck  = flag.String("normalize-check", "", "Directory of golden files to check the text normaliser against")
+		normUpdate = flag.Bool("update", false, "Rewrite the golden files instead of checking them (normalize-check mode)")
 		help       = flag.Bool("help", false, "Show help message")
 	)
 	flag.Parse()
@@ -33,9 +41,27 @@ func main() {
 		return
 	}
 
+	// CLI mode for checking file storage
+	if *checkStore {
+		runStorageCheck()
+		return
+	}
+
+	// CLI mode for the text normalisation regression corpus
+	if *normCheck != "" {
+		runNormalizeCheck(*normCheck, *normUpdate)
+		return
+	}
+
+	// CLI mode for quiz linting
+	if *lintPath != "" {
+		runLintCLI(*lintPath, *sourcePath)
+		return
+	}
+
 	// CLI mode for PDF parsing
 	if *filePath != "" {
-		runCLI(*filePath, *infoOnly)
+		runCLI(*filePath, *infoOnly, *pageSpec, *sections)
 		return
 	}
 
@@ -120,7 +146,7 @@ func runServer(port, uploadDir string) {
 	}
 }
 
-func runCLI(filePath string, infoOnly bool) {
+func runCLI(filePath string, infoOnly bool, pageSpec, sections string) {
 	// Validate required arguments
 	if filePath == "" {
 		fmt.Println("Error: PDF file path is required in CLI mode")
@@ -129,7 +155,7 @@ func runCLI(filePath string, infoOnly bool) {
 	}
 
 	// Create PDF parser instance
-	parser := NewPDFParser()
+	parser := NewConfiguredPDFParser()
 
 	// Show PDF info if requested
 	if infoOnly {
@@ -142,7 +168,7 @@ func runCLI(filePath string, infoOnly bool) {
 	}
 
 	// Extract and display text
-	err := extractAndDisplayText(parser, filePath)
+	err := extractAndDisplayText(parser, filePath, pageSpec, sections)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
@@ -168,12 +194,32 @@ func showHelp() {
 	fmt.Println("CLI Mode (PDF Parsing):")
 	fmt.Println("  go run *.go -file <path_to_pdf>        # Extract text from PDF")
 	fmt.Println("  go run *.go -file <path_to_pdf> -info  # Show PDF info only")
+	fmt.Println("  go run *.go -file <path_to_pdf> -pages 40-55      # Extract some pages only")
+	fmt.Println("  go run *.go -file <path_to_pdf> -sections 3,4.1   # Extract some outline sections only")
+	fmt.Println()
+	fmt.Println("CLI Mode (Quiz Linting):")
+	fmt.Println("  go run *.go -lint <quiz.json>                     # Check questions for quality issues")
+	fmt.Println("  go run *.go -lint <quiz.json> -source <file.pdf>  # Also check answer keys against the source")
+	fmt.Println()
+	fmt.Println("CLI Mode (Text Normalisation):")
+	fmt.Println("  go run *.go -normalize-check internal/services/testdata/normalize          # Check the normaliser against golden files")
+	fmt.Println("  go run *.go -normalize-check internal/services/testdata/normalize -update  # Rewrite the golden files")
+	fmt.Println()
+	fmt.Println("CLI Mode (File Storage):")
+	fmt.Println("  go run *.go -storage-check  # Put, read, list and delete a probe file in the configured storage")
 	fmt.Println()
 	fmt.Println("Options:")
 	fmt.Println("  -port string      Server port (default: from config or 8080)")
 	fmt.Println("  -upload-dir       Upload directory for PDF server mode (default: ./uploads)")
 	fmt.Println("  -file string      Path to the PDF file to parse (CLI mode)")
 	fmt.Println("  -info             Show only PDF information without extracting text (CLI mode)")
+	fmt.Println("  -pages string     Page ranges to extract, e.g. 40-55 or 3,7-9 (CLI mode)")
+	fmt.Println("  -sections string  Outline section IDs or titles to extract, separated by commas (CLI mode)")
+	fmt.Println("  -lint string      Path to a quiz JSON file to lint")
+	fmt.Println("  -source string    Source document to check answer keys against (lint mode)")
+	fmt.Println("  -storage-check    Check the configured file storage backend")
+	fmt.Println("  -normalize-check  Directory of golden files to check the text normaliser against")
+	fmt.Println("  -update           Rewrite the golden files instead of checking them (normalize-check mode)")
 	fmt.Println("  -legacy           Force legacy mode with separate services")
 	fmt.Println("  -auth             Run as authentication HTTP server (legacy)")
 	fmt.Println("  -quiz             Run as quiz HTTP server (legacy)")
@@ -205,7 +251,7 @@ func showHelp() {
 
 func showPDFInfo(parser *PDFParser, filePath string) error {
 	// Validate file first when called directly
-	validator := NewFileValidator()
+	validator := NewFileValidator(parser.fileService.Limits().MaxFileSize)
 	if err := validator.ValidateFile(filePath); err != nil {
 		errorHandler := NewErrorHandler()
 		errorHandler.HandleError(err, "file validation")
@@ -227,9 +273,9 @@ func showPDFInfo(parser *PDFParser, filePath string) error {
 	return nil
 }
 
-func extractAndDisplayText(parser *PDFParser, filePath string) error {
+func extractAndDisplayText(parser *PDFParser, filePath, pageSpec, sections string) error {
 	// Validate file first
-	validator := NewFileValidator()
+	validator := NewFileValidator(parser.fileService.Limits().MaxFileSize)
 	if err := validator.ValidateFile(filePath); err != nil {
 		errorHandler := NewErrorHandler()
 		errorHandler.HandleError(err, "file validation")
@@ -246,14 +292,70 @@ func extractAndDisplayText(parser *PDFParser, filePath string) error {
 	fmt.Println("\nExtracting text...")
 
 	// Extract text
-	text, err := parser.ExtractTextFromFile(filePath)
+	doc, err := parser.ExtractDocument(filePath)
+	if err != nil {
+		NewErrorHandler().HandleError(err, "text extraction")
+		return err
+	}
+
+	// Limit the text to the chosen pages and sections
+	var chosen []string
+	for _, section := range strings.Split(sections, ",") {
+		if section = strings.TrimSpace(section); section != "" {
+			chosen = append(chosen, section)
+		}
+	}
+	pages, err := services.SelectParts(doc.Pages, chosen, pageSpec)
 	if err != nil {
 		return err
 	}
+	text := formatPages(pages)
+
+	// Display the sections found from bookmarks or headings
+	if len(doc.Sections) > 0 {
+		fmt.Println("\nOutline")
+		fmt.Println("=======")
+		for _, section := range doc.Sections {
+			pages := fmt.Sprintf("p. %d", section.FirstPage)
+			if section.LastPage > section.FirstPage {
+				pages = fmt.Sprintf("pp. %d-%d", section.FirstPage, section.LastPage)
+			}
+			fmt.Printf("%s%-6s %s (%s)\n", strings.Repeat("  ", section.Level-1), section.ID, section.Title, pages)
+		}
+	}
+
+	// Display the pages that could not be read
+	for _, status := range doc.PageStatus {
+		if status.Status == services.PageFailed || status.Status == services.PageTimeout || status.Status == services.PageMissing {
+			fmt.Printf("Warning: page %d %s: %s\n", status.Page, status.Status, status.Error)
+		}
+	}
+
+	// Display how well the pages without a text layer were read
+	if doc.OCR != nil {
+		fmt.Println("\nOCR")
+		fmt.Println("===")
+		for _, page := range doc.Pages {
+			if page.OCR == nil {
+				continue
+			}
+			if page.OCR.Confidence > 0 {
+				fmt.Printf("Page %-4d %s, %.0f%% confidence\n", page.Number, page.OCR.Engine, page.OCR.Confidence*100)
+			} else {
+				fmt.Printf("Page %-4d %s\n", page.Number, page.OCR.Engine)
+			}
+		}
+		if len(doc.OCR.LowConfidencePages) > 0 {
+			fmt.Printf("Check pages %s against the original\n", services.FormatPageNumbers(doc.OCR.LowConfidencePages))
+		}
+	}
 
 	// Display extracted text
 	fmt.Println("\nExtracted Text")
 	fmt.Println("==============")
+	if len(chosen) > 0 || len(pages) < len(doc.Pages) {
+		fmt.Printf("Pages: %s\n\n", services.FormatPageRanges(pages))
+	}
 
 	// Clean up the text a bit for better display
 	cleanText := strings.TrimSpace(text)
@@ -269,6 +371,8 @@ func extractAndDisplayText(parser *PDFParser, filePath string) error {
 	fmt.Printf("Total characters: %d\n", len(text))
 	fmt.Printf("Total words:      %d\n", len(strings.Fields(text)))
 	fmt.Printf("Total lines:      %d\n", strings.Count(text, "\n")+1)
+	fmt.Printf("Sections:         %d\n", len(doc.Sections))
+	fmt.Printf("Tables:           %d\n", len(doc.Tables))
 
 	return nil
 }
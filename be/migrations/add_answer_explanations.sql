@@ -0,0 +1,14 @@
+-- Explanations of wrong answers, cached per question and chosen option
+
+CREATE TABLE IF NOT EXISTS answer_explanations (
+    question_id BIGINT NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
+    option_text TEXT NOT NULL,
+    explanation TEXT NOT NULL,
+    source_page INT,
+    source_excerpt TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (question_id, option_text)
+);
+
+COMMENT ON TABLE answer_explanations IS 'LLM explanations of why an option is wrong and the key is right, shared by every attempt that chose the option';
+COMMENT ON COLUMN answer_explanations.source_page IS 'Page of the source document passage the explanation is grounded in';
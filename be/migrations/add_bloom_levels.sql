@@ -0,0 +1,16 @@
+-- Tag questions with a Bloom's taxonomy level and learning objectives
+
+-- Cognitive level (remember, understand, apply, analyze, evaluate, create) and objectives
+ALTER TABLE questions 
+ADD COLUMN IF NOT EXISTS bloom_level VARCHAR(20),
+ADD COLUMN IF NOT EXISTS learning_objectives JSONB;
+
+-- Share of questions per level requested when the quiz was generated
+ALTER TABLE quizzes 
+ADD COLUMN IF NOT EXISTS bloom_mix JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_questions_bloom_level ON questions(bloom_level);
+
+COMMENT ON COLUMN questions.bloom_level IS 'Cognitive level of the question in the revised Bloom''s taxonomy';
+COMMENT ON COLUMN questions.learning_objectives IS 'JSON array of learning objectives the question assesses';
+COMMENT ON COLUMN quizzes.bloom_mix IS 'JSON object mapping cognitive levels to the requested share of questions';
@@ -0,0 +1,18 @@
+-- Document library: keep the original upload in blob storage with its metadata, so one
+-- document can be reused for any number of quizzes, decks and summaries
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS storage_key TEXT;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_type VARCHAR(127);
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS size_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS sha256 CHAR(64);
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS page_count INT NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+UPDATE documents SET page_count = jsonb_array_length(pages) WHERE page_count = 0;
+
+-- The same file uploaded twice by one user is stored once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_user_sha256 ON documents(user_id, sha256) WHERE sha256 IS NOT NULL;
+
+COMMENT ON COLUMN documents.storage_key IS 'Key of the original file in blob storage, NULL for documents stored before the library';
+COMMENT ON COLUMN documents.sha256 IS 'Hex SHA-256 of the original file';
+COMMENT ON COLUMN documents.metadata IS 'Document information from the file, e.g. PDF title and author';
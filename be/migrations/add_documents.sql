@@ -0,0 +1,16 @@
+-- Source documents keep the text extracted from an upload so it can be reused
+
+CREATE TABLE IF NOT EXISTS documents (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    pages JSONB NOT NULL DEFAULT '[]'::jsonb,
+    glossary JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_documents_user_id ON documents(user_id);
+
+COMMENT ON COLUMN documents.pages IS 'Extracted text per page as JSON array of {number, text}';
+COMMENT ON COLUMN documents.glossary IS 'Ranked key terms with definitions, pages and frequency';
@@ -0,0 +1,6 @@
+-- Source labels: cached explanations keep how their source passage is cited, e.g. "Slide 4" or "14:32"
+
+ALTER TABLE answer_explanations 
+ADD COLUMN IF NOT EXISTS source_label TEXT;
+
+COMMENT ON COLUMN answer_explanations.source_label IS 'Label of the source passage for documents without page numbers, such as a slide or a timestamp of a recording';
@@ -0,0 +1,25 @@
+-- Flashcard decks generated from uploaded documents or text
+
+CREATE TABLE IF NOT EXISTS flashcard_decks (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT DEFAULT '',
+    source_name VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS flashcards (
+    id BIGSERIAL PRIMARY KEY,
+    deck_id BIGINT NOT NULL REFERENCES flashcard_decks(id) ON DELETE CASCADE,
+    position INT NOT NULL DEFAULT 0,
+    front TEXT NOT NULL,
+    back TEXT NOT NULL,
+    source_ref TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_flashcard_decks_user_id ON flashcard_decks(user_id);
+CREATE INDEX IF NOT EXISTS idx_flashcards_deck_id ON flashcards(deck_id);
+
+COMMENT ON COLUMN flashcards.source_ref IS 'Where in the source material the card came from, e.g. a sentence or page reference';
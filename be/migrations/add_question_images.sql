@@ -0,0 +1,6 @@
+-- Image attachments: a question can show an image kept in blob storage
+
+ALTER TABLE questions 
+ADD COLUMN IF NOT EXISTS image_key TEXT;
+
+COMMENT ON COLUMN questions.image_key IS 'Key of the question image in blob storage';
@@ -0,0 +1,8 @@
+-- Link quizzes to the document they were generated from
+
+ALTER TABLE quizzes 
+ADD COLUMN IF NOT EXISTS document_id BIGINT REFERENCES documents(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_quizzes_document_id ON quizzes(document_id);
+
+COMMENT ON COLUMN quizzes.document_id IS 'Uploaded document the quiz was generated from, NULL for text and hand-written quizzes';
@@ -0,0 +1,8 @@
+-- Record the part of a document a quiz was generated from
+
+ALTER TABLE quizzes 
+ADD COLUMN IF NOT EXISTS source_pages TEXT,
+ADD COLUMN IF NOT EXISTS source_sections JSONB;
+
+COMMENT ON COLUMN quizzes.source_pages IS 'Pages of the document the quiz was generated from, e.g. ''40-55, 60'', NULL when generated from the whole document';
+COMMENT ON COLUMN quizzes.source_sections IS 'JSON array of the section IDs or titles chosen when generating the quiz';
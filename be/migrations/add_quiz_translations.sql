@@ -0,0 +1,16 @@
+-- Link translated quizzes to their original and keep per-question explanations
+
+-- Language of the quiz content and the quiz it was translated from
+ALTER TABLE quizzes 
+ADD COLUMN IF NOT EXISTS language VARCHAR(35),
+ADD COLUMN IF NOT EXISTS source_quiz_id BIGINT REFERENCES quizzes(id) ON DELETE SET NULL;
+
+-- Explanation text and the question a translated question was derived from
+ALTER TABLE questions 
+ADD COLUMN IF NOT EXISTS explanation TEXT,
+ADD COLUMN IF NOT EXISTS source_question_id BIGINT REFERENCES questions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_quizzes_source_quiz_id ON quizzes(source_quiz_id);
+
+COMMENT ON COLUMN quizzes.source_quiz_id IS 'Original quiz this quiz was translated from, NULL for originals';
+COMMENT ON COLUMN questions.source_question_id IS 'Question in the original quiz that this translated question mirrors';
@@ -0,0 +1,6 @@
+-- Store study notes (outline and key points) with the source document
+
+ALTER TABLE documents 
+ADD COLUMN IF NOT EXISTS notes JSONB;
+
+COMMENT ON COLUMN documents.notes IS 'Study notes generated from the document: hierarchical outline and key points';
@@ -0,0 +1,16 @@
+-- Tutor chat history, kept per user and per source document
+
+CREATE TABLE IF NOT EXISTS tutor_messages (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    document_id BIGINT NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL,
+    content TEXT NOT NULL,
+    citations JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tutor_messages_user_document ON tutor_messages(user_id, document_id, created_at);
+
+COMMENT ON COLUMN tutor_messages.role IS 'Who wrote the message: user or assistant';
+COMMENT ON COLUMN tutor_messages.citations IS 'Pages and excerpts of the document an answer cites, as JSON array of {page, excerpt, score}';
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"pbkk-quizlit-backend/internal/services"
+	"sort"
+	"strings"
+)
+
+// runNormalizeCheck runs the text normaliser over a corpus of golden files and exits with
+// status 1 when any output differs from its golden file. Each case is an input file
+// named <case>.<language>.txt holding paragraphs separated by blank lines, next to the
+// expected output in <case>.<language>.golden. An input may start with a "steps:" line
+// choosing the steps to run, as the normalize upload field does. With update the golden
+// files are rewritten instead.
+func runNormalizeCheck(dir string, update bool) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil || len(inputs) == 0 {
+		fmt.Printf("Error: no normalisation cases found in %s\n", dir)
+		os.Exit(1)
+	}
+	sort.Strings(inputs)
+
+	fmt.Println("Text Normalisation Check")
+	fmt.Println("========================")
+
+	failed := 0
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".txt")
+		golden := strings.TrimSuffix(input, ".txt") + ".golden"
+
+		output, err := services.NormalizeCase(input)
+		if err != nil {
+			fmt.Printf("ERROR %-28s %v\n", name, err)
+			failed++
+			continue
+		}
+
+		if update {
+			if err := os.WriteFile(golden, []byte(output), 0644); err != nil {
+				fmt.Printf("ERROR %-28s %v\n", name, err)
+				failed++
+				continue
+			}
+			fmt.Printf("WROTE %s\n", name)
+			continue
+		}
+
+		expected, err := os.ReadFile(golden)
+		if err != nil {
+			fmt.Printf("ERROR %-28s %v\n", name, err)
+			failed++
+			continue
+		}
+		if diff := diffLines(string(expected), output); diff != "" {
+			fmt.Printf("FAIL  %s\n%s", name, diff)
+			failed++
+			continue
+		}
+		fmt.Printf("ok    %s\n", name)
+	}
+
+	fmt.Printf("\n%d cases, %d failed\n", len(inputs), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// diffLines lists the lines that differ between the expected and the actual output
+func diffLines(expected, actual string) string {
+	want := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	got := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	var diff strings.Builder
+	for i := 0; i < len(want) || i < len(got); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			fmt.Fprintf(&diff, "      line %d\n      - %s\n      + %s\n", i+1, w, g)
+		}
+	}
+	return diff.String()
+}
@@ -5,71 +5,92 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"pbkk-quizlit-backend/internal/api"
+	"pbkk-quizlit-backend/internal/config"
+	"pbkk-quizlit-backend/internal/models"
+	"pbkk-quizlit-backend/internal/services"
 	"strings"
 
 	"github.com/ledongthuc/pdf"
 )
 
 // PDFParser handles PDF text extraction
-type PDFParser struct{}
+type PDFParser struct {
+	fileService *services.FileService
+}
 
 // NewPDFParser creates a new PDF parser instance
 func NewPDFParser() *PDFParser {
-	return &PDFParser{}
+	return &PDFParser{fileService: services.NewFileService()}
 }
 
-// ExtractTextFromFile extracts text from a PDF file
-func (p *PDFParser) ExtractTextFromFile(filePath string) (string, error) {
+// NewConfiguredPDFParser creates a PDF parser set up by the environment: pages are read
+// by PDF_WORKERS workers within PDF_PAGE_TIMEOUT each, and pages without text by the OCR
+// engine of OCR_BACKEND
+func NewConfiguredPDFParser() *PDFParser {
+	cfg := config.Load()
+	fileService := services.NewFileService().
+		WithPDFWorkers(cfg.PDFWorkers, cfg.PDFPageTimeout).
+		WithUploadLimits(api.UploadLimits(cfg))
+	engine, err := services.OpenOCR(api.OCROptions(cfg))
+	if err != nil {
+		fmt.Printf("Warning: OCR is off: %v\n", err)
+	} else if engine != nil {
+		fileService = fileService.WithOCR(engine)
+	}
+	return &PDFParser{fileService: fileService}
+}
+
+// ExtractDocument extracts the pages of a PDF file as paragraphs and headings in reading
+// order, with the outline of its sections
+func (p *PDFParser) ExtractDocument(filePath string) (*models.Document, error) {
 	// Validate file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return "", fmt.Errorf("file does not exist: %s", filePath)
+		return nil, fmt.Errorf("file does not exist: %s", filePath)
 	}
 
 	// Validate file extension
 	if !p.isPDFFile(filePath) {
-		return "", errors.New("file is not a PDF")
+		return nil, errors.New("file is not a PDF")
 	}
 
-	// Open the PDF file
-	file, reader, err := pdf.Open(filePath)
+	file, err := os.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open PDF file: %w", err)
+		return nil, fmt.Errorf("failed to open PDF file: %w", err)
 	}
 	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF file: %w", err)
+	}
 
-	// Extract text from all pages
-	var textBuilder strings.Builder
-	totalPages := reader.NumPage()
-
-	for pageNum := 1; pageNum <= totalPages; pageNum++ {
-		page := reader.Page(pageNum)
-		if page.V.IsNull() {
-			continue
-		}
+	doc, err := p.fileService.ExtractDocumentFrom(filepath.Base(filePath), file, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract PDF: %w", err)
+	}
+	return doc, nil
+}
 
-		// Extract text from the current page
-		pageText, err := page.GetPlainText(nil)
-		if err != nil {
-			fmt.Printf("Warning: failed to extract text from page %d: %v\n", pageNum, err)
-			continue
-		}
+// ExtractTextFromFile extracts text from a PDF file, page by page
+func (p *PDFParser) ExtractTextFromFile(filePath string) (string, error) {
+	doc, err := p.ExtractDocument(filePath)
+	if err != nil {
+		return "", err
+	}
+	return formatPages(doc.Pages), nil
+}
 
-		// Add page separator and text
-		if pageNum > 1 {
-			textBuilder.WriteString("\n--- Page ")
-			textBuilder.WriteString(fmt.Sprintf("%d", pageNum))
-			textBuilder.WriteString(" ---\n")
+// formatPages lays out extracted pages with a separator before every page after the first
+func formatPages(pages []models.DocumentPage) string {
+	var textBuilder strings.Builder
+	for i, page := range pages {
+		if i > 0 {
+			fmt.Fprintf(&textBuilder, "\n--- Page %d ---\n", page.Number)
 		}
-		textBuilder.WriteString(pageText)
+		textBuilder.WriteString(page.Text)
 		textBuilder.WriteString("\n")
 	}
-
-	extractedText := textBuilder.String()
-	if strings.TrimSpace(extractedText) == "" {
-		return "", errors.New("no text could be extracted from the PDF")
-	}
-
-	return extractedText, nil
+	return textBuilder.String()
 }
 
 // GetPDFInfo returns basic information about the PDF
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"pbkk-quizlit-backend/internal/api"
+	"pbkk-quizlit-backend/internal/config"
+	"pbkk-quizlit-backend/internal/storage"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// runStorageCheck round-trips a probe blob through the configured storage backend, so a
+// bucket or a local MinIO can be checked before the server is pointed at it
+func runStorageCheck() {
+	cfg := config.Load()
+	store, err := storage.Open(api.StorageOptions(cfg))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Storage Check")
+	fmt.Println("=============")
+	fmt.Printf("Backend: %s\n", cfg.StorageBackend)
+	if cfg.StorageBackend == "s3" {
+		fmt.Printf("Bucket:  %s at %s\n", cfg.S3Bucket, cfg.S3Endpoint)
+	} else {
+		fmt.Printf("Dir:     %s\n", cfg.StorageDir)
+	}
+	fmt.Println()
+
+	ctx := context.Background()
+	key := "tmp/storage-check-" + uuid.New().String() + ".txt"
+	content := []byte("quizlit storage check " + time.Now().Format(time.RFC3339))
+	failed := false
+	step := func(name string, err error) {
+		if err != nil {
+			failed = true
+			fmt.Printf("FAIL  %-8s %v\n", name, err)
+			return
+		}
+		fmt.Printf("ok    %s\n", name)
+	}
+
+	_, err = store.Put(ctx, key, bytes.NewReader(content))
+	step("put", err)
+
+	step("get", func() error {
+		blob, err := store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		defer blob.Close()
+		data, err := io.ReadAll(blob)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(data, content) {
+			return fmt.Errorf("read %d bytes that differ from the %d written", len(data), len(content))
+		}
+		return nil
+	}())
+
+	step("list", func() error {
+		blobs, err := store.List(ctx, "tmp/storage-check-")
+		if err != nil {
+			return err
+		}
+		for _, blob := range blobs {
+			if blob.Key == key {
+				return nil
+			}
+		}
+		return fmt.Errorf("probe blob missing from listing")
+	}())
+
+	step("token", func() error {
+		signer := storage.NewTokenSigner(cfg.StorageTokenSecret)
+		token := signer.Sign(storage.DownloadToken{Key: key, ExpiresAt: time.Now().Add(time.Minute)})
+		verified, err := signer.Verify(token, time.Now())
+		if err != nil {
+			return err
+		}
+		if verified.Key != key {
+			return fmt.Errorf("token grants %q instead of the probe blob", verified.Key)
+		}
+		if _, err := signer.Verify(token, time.Now().Add(2*time.Minute)); err != storage.ErrTokenExpired {
+			return fmt.Errorf("expired token was accepted")
+		}
+		return nil
+	}())
+
+	step("delete", store.Delete(ctx, key))
+
+	step("missing", func() error {
+		if _, err := store.Get(ctx, key); err != storage.ErrNotFound {
+			return fmt.Errorf("expected blob not found after delete, got %v", err)
+		}
+		return nil
+	}())
+
+	rules, err := storage.ParseLifecycleRules(cfg.StorageLifecycle)
+	step("lifecycle", err)
+	for _, rule := range rules {
+		fmt.Printf("      %s expires after %s\n", rule.Prefix, strings.TrimSuffix(rule.MaxAge.String(), "0m0s"))
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("\nStorage is working")
+}
@@ -1,18 +1,23 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"pbkk-quizlit-backend/internal/services"
 	"strings"
 )
 
 // FileValidator handles file validation operations
-type FileValidator struct{}
+type FileValidator struct {
+	maxFileSize int64 // zero means no limit
+}
 
-// NewFileValidator creates a new file validator instance
-func NewFileValidator() *FileValidator {
-	return &FileValidator{}
+// NewFileValidator creates a new file validator instance refusing files larger than
+// maxFileSize bytes, the MAX_UPLOAD_MB of the configuration
+func NewFileValidator(maxFileSize int64) *FileValidator {
+	return &FileValidator{maxFileSize: maxFileSize}
 }
 
 // ValidateFile performs comprehensive validation on a file
@@ -45,10 +50,9 @@ func (v *FileValidator) ValidateFile(filePath string) error {
 		return fmt.Errorf("invalid file extension '%s', expected .pdf", ext)
 	}
 
-	// Check file size (limit to 100MB)
-	const maxFileSize = 100 * 1024 * 1024 // 100MB
-	if fileInfo.Size() > maxFileSize {
-		return fmt.Errorf("file too large: %s (max allowed: 100MB)", formatFileSize(fileInfo.Size()))
+	// Check file size
+	if v.maxFileSize > 0 && fileInfo.Size() > v.maxFileSize {
+		return fmt.Errorf("%w: %s (max allowed: %s)", services.ErrFileTooLarge, formatFileSize(fileInfo.Size()), formatFileSize(v.maxFileSize))
 	}
 
 	// Check if file is empty
@@ -104,12 +108,22 @@ func (h *ErrorHandler) HandleError(err error, context string) {
 		fmt.Println("Suggestion: Check if you have permission to read the file.")
 	case strings.Contains(errorMsg, "invalid file extension"):
 		fmt.Println("Suggestion: Ensure the file has a .pdf extension.")
-	case strings.Contains(errorMsg, "file too large"):
-		fmt.Println("Suggestion: Try with a smaller PDF file (max 100MB).")
 	case strings.Contains(errorMsg, "directory"):
 		fmt.Println("Suggestion: Provide a path to a file, not a directory.")
-	case strings.Contains(errorMsg, "no text could be extracted"):
-		fmt.Println("Suggestion: The PDF might be image-based or encrypted. Try with a text-based PDF.")
+	case errors.Is(err, services.ErrFileTooLarge):
+		fmt.Println("Suggestion: Try with a smaller file, or raise MAX_UPLOAD_MB.")
+	case errors.Is(err, services.ErrContentMismatch):
+		fmt.Println("Suggestion: The file is not what its extension says. Check that it was saved in the right format.")
+	case errors.Is(err, services.ErrEncryptedPDF):
+		fmt.Println("Suggestion: Remove the password from the PDF and try again.")
+	case errors.Is(err, services.ErrTooManyPages), errors.Is(err, services.ErrExtractionTimeout):
+		fmt.Println("Suggestion: Split the PDF, or raise PDF_MAX_PAGES and MAX_EXTRACT_TIME.")
+	case errors.Is(err, services.ErrArchiveTooLarge):
+		fmt.Println("Suggestion: The file may be a zip bomb. If it is genuine, raise ARCHIVE_MAX_ENTRIES or ARCHIVE_MAX_MB.")
+	case errors.Is(err, services.ErrNoPDFText) && strings.Contains(errorMsg, "OCR failed"):
+		fmt.Println("Suggestion: Check that OCR_COMMAND works when run by hand on the PDF.")
+	case errors.Is(err, services.ErrNoPDFText):
+		fmt.Println("Suggestion: The PDF might be scanned or encrypted. Set OCR_BACKEND=command and OCR_COMMAND to read scanned pages with OCR.")
 	default:
 		fmt.Println("Suggestion: Check the file and try again.")
 	}